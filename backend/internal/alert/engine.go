@@ -0,0 +1,307 @@
+package alert
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+)
+
+// State คือสถานะของ alert
+type State string
+
+const (
+	// StatePending คือเงื่อนไขเป็นจริงแล้วแต่ยังไม่นานพอตาม For
+	StatePending State = "pending"
+
+	// StateFiring คือเงื่อนไขเป็นจริงต่อเนื่องนานพอแล้ว
+	StateFiring State = "firing"
+
+	// StateResolved คือค่ากลับมาปกติ (พ้น hysteresis) หลังจาก firing
+	StateResolved State = "resolved"
+
+	// StateCancelled คือค่ากลับมาปกติก่อนครบ For จึงไม่เคย firing และไม่ต้องแจ้งว่า resolved
+	StateCancelled State = "cancelled"
+)
+
+// Alert คือสถานะของกฎหนึ่งข้อกับเซนเซอร์หนึ่งตัว
+type Alert struct {
+	ID         string     `json:"id"`
+	RuleID     string     `json:"rule_id"`
+	SensorID   string     `json:"sensor_id"`
+	SensorType string     `json:"sensor_type"`
+	Tags       []string   `json:"tags,omitempty"`
+	Tenant     string     `json:"tenant,omitempty"`
+	Expr       string     `json:"expr"`
+	Metric     string     `json:"metric"`
+	Value      float64    `json:"value"`
+	Severity   string     `json:"severity"`
+	State      State      `json:"state"`
+	ActiveAt   time.Time  `json:"active_at"`
+	FiredAt    *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// DefaultCheckInterval คือระยะเวลาระหว่างการตรวจ alert ที่ pending เมื่อเซนเซอร์ไม่ส่งค่าใหม่
+const DefaultCheckInterval = 5 * time.Second
+
+// Listener คือ callback ที่รับ alert ทุกครั้งที่สถานะเปลี่ยน
+type Listener func(alert Alert)
+
+// Engine ประเมินกฎกับข้อมูลเซนเซอร์ทุกครั้งที่มีการเขียน และติดตามสถานะของ alert
+type Engine struct {
+	rules     []Rule
+	active    map[string]*Alert
+	mu        sync.Mutex
+	listeners []Listener
+	listenMu  sync.RWMutex
+	logger    *zap.Logger
+	done      chan struct{}
+	once      sync.Once
+}
+
+// NewEngine สร้าง engine จากกฎที่ compile แล้ว (จาก NewRule หรือ LoadRules)
+func NewEngine(rules []Rule, logger *zap.Logger) *Engine {
+	return &Engine{
+		rules:  rules,
+		active: make(map[string]*Alert),
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+}
+
+// AddListener ลงทะเบียน listener ที่จะถูกเรียกทุกครั้งที่สถานะของ alert เปลี่ยน
+func (e *Engine) AddListener(listener Listener) {
+	e.listenMu.Lock()
+	defer e.listenMu.Unlock()
+
+	e.listeners = append(e.listeners, listener)
+}
+
+// Evaluate ประเมินกฎทั้งหมดกับเซนเซอร์ที่เปลี่ยนแปลง ใช้เวลาของ reading ในการนับ For
+// มีรูปแบบเดียวกับ repository.ChangeListener จึงลงทะเบียนกับ repository ได้โดยตรง
+func (e *Engine) Evaluate(changed []*model.SensorModel) {
+	var transitions []Alert
+
+	e.mu.Lock()
+	for _, sensor := range changed {
+		for i := range e.rules {
+			rule := &e.rules[i]
+			if !rule.matches(sensor.ID, sensor.Type) {
+				continue
+			}
+
+			value, ok := metricValue(sensor, rule.Metric)
+			if !ok {
+				continue
+			}
+
+			if alert, changed := e.step(rule, sensor, value); changed {
+				transitions = append(transitions, alert)
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	for _, alert := range transitions {
+		e.logger.Info("Alert state changed",
+			zap.String("rule", alert.RuleID),
+			zap.String("sensor", alert.SensorID),
+			zap.String("state", string(alert.State)),
+			zap.Float64("value", alert.Value))
+		e.notify(alert)
+	}
+}
+
+// Remove ปิด alert ที่ active ของเซนเซอร์ที่ถูกลบ เพื่อไม่ให้ alert ค้างอยู่โดยไม่มีวันกลับมาปกติ
+// alert ที่ firing แล้วถือว่า resolved ส่วนที่ยัง pending ถือว่า cancelled
+// มีรูปแบบเดียวกับ repository.ChangeListener จึงลงทะเบียนกับ repository ได้โดยตรง
+func (e *Engine) Remove(removed []*model.SensorModel) {
+	now := time.Now()
+	var transitions []Alert
+
+	e.mu.Lock()
+	for _, sensor := range removed {
+		for id, alert := range e.active {
+			if alert.SensorID != sensor.ID {
+				continue
+			}
+
+			delete(e.active, id)
+			if alert.FiredAt == nil {
+				alert.State = StateCancelled
+			} else {
+				alert.State = StateResolved
+				alert.ResolvedAt = &now
+			}
+			alert.UpdatedAt = now
+			transitions = append(transitions, *alert)
+		}
+	}
+	e.mu.Unlock()
+
+	for _, alert := range transitions {
+		e.logger.Info("Alert closed because sensor was removed",
+			zap.String("rule", alert.RuleID),
+			zap.String("sensor", alert.SensorID),
+			zap.String("state", string(alert.State)))
+		e.notify(alert)
+	}
+}
+
+// Start เริ่ม goroutine ที่เรียก Check ทุก interval เพื่อให้ alert ที่ pending firing ได้แม้เซนเซอร์หยุดส่งค่า
+func (e *Engine) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-e.done:
+				return
+			case now := <-ticker.C:
+				e.Check(now)
+			}
+		}
+	}()
+}
+
+// Stop หยุดการตรวจ alert ที่ pending
+func (e *Engine) Stop() {
+	e.once.Do(func() {
+		close(e.done)
+	})
+}
+
+// Check เลื่อน alert ที่ pending ไป firing เมื่อค่าล่าสุดยังเกิน threshold และเวลาผ่านไปครบ For ณ เวลา now
+// Evaluate นับ For เฉพาะตอนมีค่าใหม่ จึงต้องมี Check สำหรับเซนเซอร์ที่เงียบไปหลังค่าเกิน threshold
+func (e *Engine) Check(now time.Time) {
+	var transitions []Alert
+
+	e.mu.Lock()
+	for _, alert := range e.active {
+		if alert.State != StatePending {
+			continue
+		}
+
+		rule := e.rule(alert.RuleID)
+		if rule == nil || !rule.breached(alert.Value) || now.Sub(alert.ActiveAt) < rule.For {
+			continue
+		}
+
+		alert.State = StateFiring
+		alert.FiredAt = &now
+		alert.UpdatedAt = now
+		transitions = append(transitions, *alert)
+	}
+	e.mu.Unlock()
+
+	for _, alert := range transitions {
+		e.logger.Info("Pending alert fired without new readings",
+			zap.String("rule", alert.RuleID),
+			zap.String("sensor", alert.SensorID),
+			zap.Float64("value", alert.Value))
+		e.notify(alert)
+	}
+}
+
+// rule คืนค่ากฎตาม ID (nil ถ้าไม่มี)
+func (e *Engine) rule(id string) *Rule {
+	for i := range e.rules {
+		if e.rules[i].ID == id {
+			return &e.rules[i]
+		}
+	}
+	return nil
+}
+
+// Active คืนค่า alert ที่ยัง pending หรือ firing อยู่ เรียงตาม ID
+func (e *Engine) Active() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alerts := make([]Alert, 0, len(e.active))
+	for _, alert := range e.active {
+		alerts = append(alerts, *alert)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].ID < alerts[j].ID
+	})
+
+	return alerts
+}
+
+// step เลื่อนสถานะของ alert หนึ่งตัวตามค่าล่าสุด คืนค่า alert และ true เมื่อสถานะเปลี่ยน (ผู้เรียกต้องถือ lock)
+func (e *Engine) step(rule *Rule, sensor *model.SensorModel, value float64) (Alert, bool) {
+	id := rule.ID + ":" + sensor.ID
+	now := sensor.Timestamp
+	alert, exists := e.active[id]
+
+	if !exists {
+		if !rule.breached(value) {
+			return Alert{}, false
+		}
+
+		alert = &Alert{
+			ID:         id,
+			RuleID:     rule.ID,
+			SensorID:   sensor.ID,
+			SensorType: sensor.Type,
+			Tags:       append([]string(nil), sensor.Tags...),
+			Tenant:     sensor.Tenant,
+			Expr:       rule.Expr,
+			Metric:     rule.Metric,
+			Severity:   rule.Severity,
+			State:      StatePending,
+			ActiveAt:   now,
+		}
+		e.active[id] = alert
+	} else if rule.recovered(value) {
+		delete(e.active, id)
+		if alert.FiredAt == nil {
+			alert.State = StateCancelled
+		} else {
+			alert.State = StateResolved
+			alert.ResolvedAt = &now
+		}
+		alert.Value = value
+		alert.UpdatedAt = now
+		return *alert, true
+	}
+
+	alert.Value = value
+	alert.UpdatedAt = now
+
+	if !exists && rule.For > 0 {
+		return *alert, true
+	}
+
+	// ค่าที่อยู่ในช่วง hysteresis ไม่ทำให้ pending เลื่อนไป firing แต่ก็ไม่ยกเลิก
+	if alert.State == StatePending && rule.breached(value) && now.Sub(alert.ActiveAt) >= rule.For {
+		alert.State = StateFiring
+		alert.FiredAt = &now
+		return *alert, true
+	}
+
+	return *alert, false
+}
+
+// notify เรียก listener ทั้งหมดด้วย alert ที่สถานะเปลี่ยน
+func (e *Engine) notify(alert Alert) {
+	e.listenMu.RLock()
+	defer e.listenMu.RUnlock()
+
+	for _, listener := range e.listeners {
+		listener(alert)
+	}
+}
+
+// metricValue คืนค่าของ metric ตามชื่อ เฉพาะเมื่อเซนเซอร์มีค่านั้นจริง
+func metricValue(sensor *model.SensorModel, metric string) (float64, bool) {
+	return sensor.Value(metric)
+}
@@ -0,0 +1,229 @@
+package alert_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/alert"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+)
+
+// recorder เก็บ alert ที่ engine แจ้งออกมา
+type recorder struct {
+	alerts []alert.Alert
+}
+
+func (r *recorder) states() []alert.State {
+	states := make([]alert.State, 0, len(r.alerts))
+	for _, a := range r.alerts {
+		states = append(states, a.State)
+	}
+	return states
+}
+
+func newEngine(t *testing.T, rules ...alert.Rule) (*alert.Engine, *recorder) {
+	engine := alert.NewEngine(rules, zaptest.NewLogger(t))
+	rec := &recorder{}
+	engine.AddListener(func(a alert.Alert) {
+		rec.alerts = append(rec.alerts, a)
+	})
+	return engine, rec
+}
+
+func sensor(id string, sensorType string, metric string, value float64, at time.Time) *model.SensorModel {
+	s := &model.SensorModel{ID: id, Type: sensorType, Timestamp: at}
+	s.SetValue(metric, value)
+	return s
+}
+
+func temperature(id string, value float64, at time.Time) []*model.SensorModel {
+	return []*model.SensorModel{sensor(id, "temperature", "temperature", value, at)}
+}
+
+func TestNewRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "with_for", expr: "temperature > 28 for 5m"},
+		{name: "without_for", expr: "humidity < 20"},
+		{name: "unknown_operator", expr: "humidity != 20", wantErr: true},
+		{name: "bad_threshold", expr: "humidity < high", wantErr: true},
+		{name: "bad_duration", expr: "humidity < 20 for soon", wantErr: true},
+		{name: "missing_for_keyword", expr: "humidity < 20 during 5m", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := alert.NewRule("r1", tc.expr)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "warning", rule.Severity)
+		})
+	}
+
+	rule, err := alert.NewRule("r1", "temperature >= 28.5 for 5m")
+	require.NoError(t, err)
+	assert.Equal(t, "temperature", rule.Metric)
+	assert.Equal(t, alert.OpGreaterEqual, rule.Op)
+	assert.Equal(t, 28.5, rule.Threshold)
+	assert.Equal(t, 5*time.Minute, rule.For)
+}
+
+func TestEngineForDuration(t *testing.T) {
+	rule, err := alert.NewRule("hot", "temperature > 28 for 5m")
+	require.NoError(t, err)
+	engine, rec := newEngine(t, rule)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine.Evaluate(temperature("temp-001", 29, start))
+	engine.Evaluate(temperature("temp-001", 30, start.Add(2*time.Minute)))
+	assert.Equal(t, []alert.State{alert.StatePending}, rec.states())
+
+	engine.Evaluate(temperature("temp-001", 30, start.Add(5*time.Minute)))
+	assert.Equal(t, []alert.State{alert.StatePending, alert.StateFiring}, rec.states())
+	require.Len(t, engine.Active(), 1)
+	assert.Equal(t, "hot:temp-001", engine.Active()[0].ID)
+
+	engine.Evaluate(temperature("temp-001", 20, start.Add(6*time.Minute)))
+	assert.Equal(t, []alert.State{alert.StatePending, alert.StateFiring, alert.StateResolved}, rec.states())
+	assert.Empty(t, engine.Active())
+	assert.NotNil(t, rec.alerts[2].FiredAt)
+	assert.NotNil(t, rec.alerts[2].ResolvedAt)
+}
+
+func TestEngineCheckFiresSilentSensor(t *testing.T) {
+	rule, err := alert.NewRule("hot", "temperature > 28 for 5m")
+	require.NoError(t, err)
+	engine, rec := newEngine(t, rule)
+
+	// เซนเซอร์ส่งค่าเกิน threshold ครั้งเดียวแล้วเงียบไป alert ต้อง firing เมื่อครบ For
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine.Evaluate(temperature("temp-001", 29, start))
+	engine.Check(start.Add(4 * time.Minute))
+	assert.Equal(t, []alert.State{alert.StatePending}, rec.states())
+
+	engine.Check(start.Add(5 * time.Minute))
+	assert.Equal(t, []alert.State{alert.StatePending, alert.StateFiring}, rec.states())
+	require.NotNil(t, rec.alerts[1].FiredAt)
+	assert.True(t, rec.alerts[1].FiredAt.Equal(start.Add(5*time.Minute)))
+
+	// alert ที่ firing แล้วต้องไม่ถูกแจ้งซ้ำ
+	engine.Check(start.Add(10 * time.Minute))
+	assert.Len(t, rec.alerts, 2)
+}
+
+func TestEngineCheckHysteresis(t *testing.T) {
+	rule, err := alert.NewRule("hot", "temperature > 28 for 5m")
+	require.NoError(t, err)
+	rule.Hysteresis = 1
+	engine, rec := newEngine(t, rule)
+
+	// ค่าล่าสุดอยู่ในช่วง hysteresis จึงยัง pending แต่ไม่ firing
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine.Evaluate(temperature("temp-001", 29, start))
+	engine.Evaluate(temperature("temp-001", 27.5, start.Add(time.Minute)))
+	engine.Check(start.Add(10 * time.Minute))
+	assert.Equal(t, []alert.State{alert.StatePending}, rec.states())
+}
+
+func TestEnginePendingRecovered(t *testing.T) {
+	rule, err := alert.NewRule("hot", "temperature > 28 for 5m")
+	require.NoError(t, err)
+	engine, rec := newEngine(t, rule)
+
+	// ค่ากลับมาปกติก่อนครบ For ต้องไม่ถือว่า resolved เพราะไม่เคย firing
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine.Evaluate(temperature("temp-001", 29, start))
+	engine.Evaluate(temperature("temp-001", 20, start.Add(2*time.Minute)))
+	assert.Equal(t, []alert.State{alert.StatePending, alert.StateCancelled}, rec.states())
+	assert.Empty(t, engine.Active())
+	assert.Nil(t, rec.alerts[1].FiredAt)
+	assert.Nil(t, rec.alerts[1].ResolvedAt)
+}
+
+func TestEngineHysteresis(t *testing.T) {
+	rule, err := alert.NewRule("hot", "temperature > 28")
+	require.NoError(t, err)
+	rule.Hysteresis = 1
+	engine, rec := newEngine(t, rule)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine.Evaluate(temperature("temp-001", 28.5, start))
+	assert.Equal(t, []alert.State{alert.StateFiring}, rec.states())
+
+	// ค่าที่แกว่งอยู่ระหว่าง threshold กับ hysteresis ต้องไม่ทำให้ alert หาย
+	engine.Evaluate(temperature("temp-001", 27.5, start.Add(time.Second)))
+	engine.Evaluate(temperature("temp-001", 28.2, start.Add(2*time.Second)))
+	assert.Equal(t, []alert.State{alert.StateFiring}, rec.states())
+
+	engine.Evaluate(temperature("temp-001", 27, start.Add(3*time.Second)))
+	assert.Equal(t, []alert.State{alert.StateFiring, alert.StateResolved}, rec.states())
+}
+
+func TestEngineScope(t *testing.T) {
+	perSensor, err := alert.NewRule("one", "temperature > 28")
+	require.NoError(t, err)
+	perSensor.SensorID = "temp-002"
+
+	perType, err := alert.NewRule("dry", "humidity < 20")
+	require.NoError(t, err)
+	perType.SensorType = "humidity"
+
+	engine, rec := newEngine(t, perSensor, perType)
+
+	now := time.Now()
+	engine.Evaluate([]*model.SensorModel{
+		sensor("temp-001", "temperature", "temperature", 35, now),
+		sensor("temp-002", "temperature", "temperature", 35, now),
+		// เซนเซอร์อุณหภูมิไม่มีค่าความชื้น จึงต้องไม่ถูกประเมินด้วยกฎความชื้น
+		sensor("temp-003", "temperature", "temperature", 20, now),
+		sensor("combined-001", "combined", "humidity", 10, now),
+		sensor("humid-001", "humidity", "humidity", 10, now),
+	})
+
+	ids := make([]string, 0, len(rec.alerts))
+	for _, a := range rec.alerts {
+		ids = append(ids, a.ID)
+	}
+	assert.ElementsMatch(t, []string{"one:temp-002", "dry:humid-001"}, ids)
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "rules.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`
+- id: server-room-hot
+  sensor_id: temp-001
+  expr: temperature > 28 for 5m
+  hysteresis: 0.5
+  severity: critical
+`), 0o644))
+
+	rules, err := alert.LoadRules(yamlPath)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "critical", rules[0].Severity)
+	assert.Equal(t, 5*time.Minute, rules[0].For)
+	assert.Equal(t, 0.5, rules[0].Hysteresis)
+
+	jsonPath := filepath.Join(dir, "rules.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`[
+		{"id": "a", "expr": "humidity < 20"},
+		{"id": "a", "expr": "humidity > 80"}
+	]`), 0o644))
+
+	_, err = alert.LoadRules(jsonPath)
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// Operator คือตัวดำเนินการเปรียบเทียบค่ากับ threshold
+type Operator string
+
+const (
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+)
+
+// Rule คือกฎการแจ้งเตือนหนึ่งข้อ ใช้กับเซนเซอร์ตาม SensorID หรือ SensorType (ไม่ระบุทั้งสองคือทุกตัว)
+// เงื่อนไขต้องเป็นจริงต่อเนื่องนาน For ก่อนแจ้งเตือน และต้องกลับมาเกิน Hysteresis จาก threshold จึงจะหายไป
+type Rule struct {
+	ID         string        `json:"id" yaml:"id" validate:"required"`
+	SensorID   string        `json:"sensor_id,omitempty" yaml:"sensor_id"`
+	SensorType string        `json:"sensor_type,omitempty" yaml:"sensor_type"`
+	Expr       string        `json:"expr" yaml:"expr" validate:"required"`
+	Hysteresis float64       `json:"hysteresis,omitempty" yaml:"hysteresis" validate:"gte=0"`
+	Severity   string        `json:"severity,omitempty" yaml:"severity" validate:"omitempty,oneof=info warning critical"`
+	Metric     string        `json:"-" yaml:"-"`
+	Op         Operator      `json:"-" yaml:"-"`
+	Threshold  float64       `json:"-" yaml:"-"`
+	For        time.Duration `json:"-" yaml:"-"`
+}
+
+// validate ใช้ตรวจสอบกฎที่โหลดจากไฟล์
+var validate = validator.New()
+
+// NewRule สร้างกฎจาก expression เช่น "temperature > 28 for 5m" หรือ "humidity < 20"
+func NewRule(id string, expr string) (Rule, error) {
+	rule := Rule{ID: id, Expr: expr}
+	if err := rule.compile(); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+// LoadRules โหลดกฎจากไฟล์ JSON หรือ YAML (ตามนามสกุลไฟล์) ที่เป็น list ของกฎ
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrConfigNotFound, fmt.Sprintf("alert rules %s: %v", path, err))
+	}
+
+	var rules []Rule
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &rules)
+	default:
+		err = json.Unmarshal(data, &rules)
+	}
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("alert rules %s: %v", path, err))
+	}
+
+	seen := make(map[string]bool, len(rules))
+	for i := range rules {
+		if err := validate.Struct(rules[i]); err != nil {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("alert rule #%d: %v", i+1, err))
+		}
+		if seen[rules[i].ID] {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("duplicate alert rule id %q", rules[i].ID))
+		}
+		seen[rules[i].ID] = true
+
+		if err := rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// compile แยก Expr ออกเป็น metric, operator, threshold และ for
+func (r *Rule) compile() error {
+	fields := strings.Fields(r.Expr)
+	if len(fields) != 3 && len(fields) != 5 {
+		return r.exprError("expected '<metric> <op> <threshold> [for <duration>]'")
+	}
+
+	r.Metric = fields[0]
+
+	r.Op = Operator(fields[1])
+	switch r.Op {
+	case OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+	default:
+		return r.exprError(fmt.Sprintf("unknown operator %q", fields[1]))
+	}
+
+	threshold, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return r.exprError(fmt.Sprintf("invalid threshold %q", fields[2]))
+	}
+	r.Threshold = threshold
+
+	r.For = 0
+	if len(fields) == 5 {
+		if fields[3] != "for" {
+			return r.exprError(fmt.Sprintf("expected 'for', got %q", fields[3]))
+		}
+		if r.For, err = time.ParseDuration(fields[4]); err != nil || r.For < 0 {
+			return r.exprError(fmt.Sprintf("invalid duration %q", fields[4]))
+		}
+	}
+
+	if r.Severity == "" {
+		r.Severity = "warning"
+	}
+
+	return nil
+}
+
+// exprError สร้าง error สำหรับ expression ที่ไม่ถูกต้อง
+func (r *Rule) exprError(reason string) error {
+	return apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("alert rule %q: invalid expr %q: %s", r.ID, r.Expr, reason))
+}
+
+// matches ตรวจสอบว่ากฎใช้กับเซนเซอร์นี้หรือไม่
+func (r *Rule) matches(sensorID string, sensorType string) bool {
+	if r.SensorID != "" && r.SensorID != sensorID {
+		return false
+	}
+	if r.SensorType != "" && r.SensorType != sensorType {
+		return false
+	}
+	return true
+}
+
+// breached ตรวจสอบว่าค่าละเมิดเงื่อนไขหรือไม่
+func (r *Rule) breached(value float64) bool {
+	switch r.Op {
+	case OpGreater:
+		return value > r.Threshold
+	case OpGreaterEqual:
+		return value >= r.Threshold
+	case OpLess:
+		return value < r.Threshold
+	default:
+		return value <= r.Threshold
+	}
+}
+
+// recovered ตรวจสอบว่าค่ากลับมาปกติพ้นช่วง hysteresis แล้ว เพื่อไม่ให้ alert กระพริบเมื่อค่าแกว่งรอบ threshold
+func (r *Rule) recovered(value float64) bool {
+	switch r.Op {
+	case OpGreater, OpGreaterEqual:
+		return value <= r.Threshold-r.Hysteresis && !r.breached(value)
+	default:
+		return value >= r.Threshold+r.Hysteresis && !r.breached(value)
+	}
+}
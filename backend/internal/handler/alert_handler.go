@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// GetAlerts คืนค่า alert ที่ยัง pending หรือ firing อยู่ผ่าน GET /api/alerts
+// การเปลี่ยนสถานะทุกครั้งจะถูกส่งบน stream เป็น event "alert" ด้วย
+func (h *SensorHandler) GetAlerts(c echo.Context) error {
+	alertsJSON, err := h.sensors(c).GetAlerts()
+	if err != nil {
+		h.logger.Error("Failed to get alerts", zap.Error(err))
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.JSONBlob(http.StatusOK, []byte(alertsJSON))
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
+)
+
+// ค่าอายุของ token ที่ local issuer ออกให้
+const (
+	defaultLocalTokenTTL = 15 * time.Minute
+	maxLocalTokenTTL     = 24 * time.Hour
+)
+
+// IAuthHandler คือ interface สำหรับ handler ที่ออก ticket และ token
+type IAuthHandler interface {
+	// IssueStreamTicket ออก ticket อายุสั้นสำหรับเปิด SSE stream
+	IssueStreamTicket(c echo.Context) error
+
+	// IssueLocalToken ออก JWT จาก local issuer (เฉพาะ dev)
+	IssueLocalToken(c echo.Context) error
+
+	// GetLocalJWKS คืนค่า JWKS ของ local issuer (เฉพาะ dev)
+	GetLocalJWKS(c echo.Context) error
+}
+
+// AuthHandler จัดการเกี่ยวกับ handler ของ auth API
+type AuthHandler struct {
+	tickets     *auth.TicketIssuer
+	issuer      *auth.LocalIssuer
+	rolesClaim  string
+	tenantClaim string
+	logger      *zap.Logger
+}
+
+// StreamTicketResponse คือ response ของการขอ stream ticket
+type StreamTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+	StreamURL string    `json:"stream_url"`
+}
+
+// LocalTokenRequest คือ request สำหรับขอ token จาก local issuer
+type LocalTokenRequest struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+	Tenant  string   `json:"tenant"`
+	TTL     string   `json:"ttl"`
+}
+
+// LocalTokenResponse คือ token ที่ local issuer ออกให้
+type LocalTokenResponse struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// NewAuthHandler สร้าง instance ใหม่ของ AuthHandler โดย issuer เป็น nil ได้เมื่อไม่ได้เปิด local issuer
+func NewAuthHandler(tickets *auth.TicketIssuer, issuer *auth.LocalIssuer, rolesClaim string, tenantClaim string, logger *zap.Logger) *AuthHandler {
+	return &AuthHandler{
+		tickets:     tickets,
+		issuer:      issuer,
+		rolesClaim:  rolesClaim,
+		tenantClaim: tenantClaim,
+		logger:      logger,
+	}
+}
+
+// IssueStreamTicket ออก ticket ให้ผู้เรียกที่ยืนยันตัวตนแล้วผ่าน POST /api/sensors/stream/ticket
+// ticket ใช้เปิด /api/sensors/stream?ticket=... และ stream จะปิดเมื่อ credential เดิมหมดอายุ
+func (h *AuthHandler) IssueStreamTicket(c echo.Context) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrUnauthorized, "missing credentials"))
+	}
+
+	ticket, expiresAt, err := h.tickets.Issue(principal)
+	if err != nil {
+		h.logger.Error("Failed to issue stream ticket", zap.String("subject", principal.Subject), zap.Error(err))
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, StreamTicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: expiresAt,
+		StreamURL: "/api/sensors/stream?" + auth.QueryStreamTicket + "=" + url.QueryEscape(ticket),
+	})
+}
+
+// IssueLocalToken ออก JWT ที่มี role และ tenant ตามที่ขอผ่าน POST /auth/local/token
+func (h *AuthHandler) IssueLocalToken(c echo.Context) error {
+	body, err := readBody(c)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	var req LocalTokenRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrInvalidRequest, err.Error()))
+	}
+	if req.Subject == "" {
+		return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrInvalidRequest, "subject is required"))
+	}
+	if req.Tenant == auth.AllTenants {
+		return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrInvalidRequest, "tenant \"*\" is only available to API keys"))
+	}
+
+	ttl := defaultLocalTokenTTL
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 || ttl > maxLocalTokenTTL {
+			return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrInvalidRequest, "ttl must be a duration between 0s and 24h"))
+		}
+	}
+
+	claims := map[string]interface{}{h.rolesClaim: req.Roles}
+	if req.Tenant != "" {
+		claims[h.tenantClaim] = req.Tenant
+	}
+
+	token, expiresAt, err := h.issuer.Issue(req.Subject, ttl, claims)
+	if err != nil {
+		h.logger.Error("Failed to issue local token", zap.String("subject", req.Subject), zap.Error(err))
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, LocalTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   expiresAt,
+	})
+}
+
+// GetLocalJWKS คืนค่า JWKS ของ local issuer ผ่าน GET /auth/local/jwks.json
+func (h *AuthHandler) GetLocalJWKS(c echo.Context) error {
+	jwks, err := h.issuer.JWKS()
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+	return c.JSONBlob(http.StatusOK, jwks)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// GetSensorHistory คืนค่าข้อมูลย้อนหลังผ่าน GET /api/sensors/:id/history?from=&to=&step=&agg=
+// ผลลัพธ์แยก series ตาม metric และแต่ละจุดคือค่าสรุปของหนึ่ง step
+func (h *SensorHandler) GetSensorHistory(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return apierror.HandleAPIError(c, apierror.ErrInvalidRequest)
+	}
+
+	query, err := model.ParseHistoryQuery(c.QueryParams(), time.Now())
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	historyJSON, err := h.sensors(c).GetSensorHistory(id, query)
+	if err != nil {
+		h.logger.Error("Failed to get sensor history",
+			zap.String("id", id),
+			zap.Time("from", query.From),
+			zap.Time("to", query.To),
+			zap.Error(err))
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.JSONBlob(http.StatusOK, []byte(historyJSON))
+}
@@ -0,0 +1,92 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// TestGetSensorHistory ทดสอบการแปลง query parameter และการส่งต่อไปยัง service
+func TestGetSensorHistory(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectCall     bool
+		serviceErr     error
+		expectedStatus int
+	}{
+		{
+			name:           "valid_query",
+			query:          "from=2025-01-01T00:00:00Z&to=2025-01-01T01:00:00Z&step=1m&agg=max",
+			expectCall:     true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown_sensor",
+			query:          "from=2025-01-01T00:00:00Z&to=2025-01-01T01:00:00Z&step=1m&agg=max",
+			expectCall:     true,
+			serviceErr:     apierror.Wrap(apierror.ErrDataNotFound, "sensor with ID temp-001 not found"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid_agg",
+			query:          "from=2025-01-01T00:00:00Z&to=2025-01-01T01:00:00Z&agg=median",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "to_before_from",
+			query:          "from=2025-01-01T01:00:00Z&to=2025-01-01T00:00:00Z",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too_many_points",
+			query:          "from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z&step=1s",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "range_too_long",
+			query:          "from=2020-01-01T00:00:00Z&to=2025-01-01T00:00:00Z&step=24h",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed_time",
+			query:          "from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/sensors/temp-001/history?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("temp-001")
+
+			h, mockService := NewMockSensorHandler(t)
+			if tc.expectCall {
+				mockService.On("GetSensorHistory", "temp-001", mock.MatchedBy(func(q model.HistoryQuery) bool {
+					return q.From.Equal(from) && q.To.Equal(to) && q.Step == time.Minute && q.Agg == model.AggMax
+				})).Return(`{"sensor_id":"temp-001","series":{}}`, tc.serviceErr)
+			}
+
+			err := h.GetSensorHistory(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
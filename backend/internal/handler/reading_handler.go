@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// IngestReadings รับค่าจากอุปกรณ์ผ่าน POST /api/sensors/:id/readings
+// body เป็น reading เดียว (JSON object) หรือหลาย reading (JSON array) ก็ได้
+func (h *SensorHandler) IngestReadings(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return apierror.HandleAPIError(c, apierror.ErrInvalidRequest)
+	}
+
+	body, err := readBody(c)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	readings, err := model.ParseReadings(body)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	sensorJSON, err := h.sensors(c).IngestReadings(id, readings)
+	if err != nil {
+		h.logger.Error("Failed to ingest readings",
+			zap.String("id", id),
+			zap.Int("count", len(readings)),
+			zap.Error(err))
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.JSONBlob(http.StatusOK, []byte(sensorJSON))
+}
@@ -0,0 +1,138 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/handler"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// TestIngestReadings ทดสอบการรับค่าจากอุปกรณ์ทั้งแบบเดี่ยวและแบบ batch
+func TestIngestReadings(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedCount  int
+		serviceErr     error
+		expectedStatus int
+	}{
+		{
+			name:           "single_reading",
+			body:           `{"temperature":25.5}`,
+			expectedCount:  1,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "batch_readings",
+			body:           `[{"temperature":25.5,"timestamp":"2025-01-01T00:00:00Z"},{"humidity":40}]`,
+			expectedCount:  2,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown_sensor",
+			body:           `{"humidity":40}`,
+			expectedCount:  1,
+			serviceErr:     apierror.Wrap(apierror.ErrDataNotFound, "sensor with ID temp-001 not found"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "missing_values",
+			body:           `{"timestamp":"2025-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "out_of_range",
+			body:           `{"humidity":140}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "future_timestamp",
+			body:           `[{"temperature":25.5},{"temperature":26,"timestamp":"2999-01-01T00:00:00Z"}]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "empty_batch",
+			body:           `[]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed_json",
+			body:           `{"temperature":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/sensors/temp-001/readings", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("temp-001")
+
+			h, mockService := NewMockSensorHandler(t)
+			if tc.expectedCount > 0 {
+				mockService.On("IngestReadings", "temp-001", mock.MatchedBy(func(readings []model.ReadingModel) bool {
+					return len(readings) == tc.expectedCount
+				})).Return(`{"id":"temp-001"}`, tc.serviceErr).Once()
+			}
+
+			err := h.IngestReadings(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code, rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestIngestReadingsBatchLimit ทดสอบการปฏิเสธ batch ที่ใหญ่เกินกำหนด
+func TestIngestReadingsBatchLimit(t *testing.T) {
+	readings := make([]string, model.MaxReadingsPerBatch+1)
+	for i := range readings {
+		readings[i] = fmt.Sprintf(`{"temperature":%d}`, i%50)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/sensors/temp-001/readings",
+		strings.NewReader("["+strings.Join(readings, ",")+"]"))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("temp-001")
+
+	h, mockService := NewMockSensorHandler(t)
+
+	assert.NoError(t, h.IngestReadings(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "IngestReadings", mock.Anything, mock.Anything)
+}
+
+// TestIngestReadingsBodyLimit ทดสอบการปฏิเสธ body ที่ใหญ่เกิน MaxBodyBytes โดยไม่อ่านทั้งหมดเข้าหน่วยความจำ
+func TestIngestReadingsBodyLimit(t *testing.T) {
+	body := `{"temperature":25,"pad":"` + strings.Repeat("x", handler.MaxBodyBytes) + `"}`
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/sensors/temp-001/readings", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("temp-001")
+
+	h, mockService := NewMockSensorHandler(t)
+
+	assert.NoError(t, h.IngestReadings(c))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	mockService.AssertNotCalled(t, "IngestReadings", mock.Anything, mock.Anything)
+}
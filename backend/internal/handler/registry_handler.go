@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// CreateSensor ลงทะเบียนเซนเซอร์ใหม่ผ่าน POST /api/sensors
+// คืนค่า 201 พร้อม Location ของเซนเซอร์ หรือ 409 ถ้า ID ซ้ำ
+func (h *SensorHandler) CreateSensor(c echo.Context) error {
+	body, err := readBody(c)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	registration, err := model.ParseSensorRegistration(body, "")
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	sensorJSON, err := h.sensors(c).CreateSensor(registration)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, c.Path()+"/"+registration.ID)
+	return c.JSONBlob(http.StatusCreated, []byte(sensorJSON))
+}
+
+// UpdateSensor แทนที่ metadata ทั้งหมดของเซนเซอร์ผ่าน PUT /api/sensors/:id
+func (h *SensorHandler) UpdateSensor(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return apierror.HandleAPIError(c, apierror.ErrInvalidRequest)
+	}
+
+	body, err := readBody(c)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	registration, err := model.ParseSensorRegistration(body, id)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	sensorJSON, err := h.sensors(c).UpdateSensor(id, registration)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.JSONBlob(http.StatusOK, []byte(sensorJSON))
+}
+
+// PatchSensor แก้ไข metadata บางส่วนของเซนเซอร์ผ่าน PATCH /api/sensors/:id
+func (h *SensorHandler) PatchSensor(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return apierror.HandleAPIError(c, apierror.ErrInvalidRequest)
+	}
+
+	body, err := readBody(c)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	patch, err := model.ParseSensorPatch(body)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	sensorJSON, err := h.sensors(c).PatchSensor(id, patch)
+	if err != nil {
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.JSONBlob(http.StatusOK, []byte(sensorJSON))
+}
+
+// DeleteSensor ลบเซนเซอร์ออกจาก registry ผ่าน DELETE /api/sensors/:id
+func (h *SensorHandler) DeleteSensor(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return apierror.HandleAPIError(c, apierror.ErrInvalidRequest)
+	}
+
+	if err := h.sensors(c).DeleteSensor(id); err != nil {
+		h.logger.Error("Failed to delete sensor", zap.String("id", id), zap.Error(err))
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
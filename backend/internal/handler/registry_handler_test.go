@@ -0,0 +1,209 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// TestCreateSensor ทดสอบการลงทะเบียนเซนเซอร์และการตรวจสอบข้อมูล
+func TestCreateSensor(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		callService    bool
+		serviceErr     error
+		expectedStatus int
+	}{
+		{
+			name:           "created",
+			body:           `{"id":"co2-101","name":"Meeting Room CO2","type":"co2","location":{"site":"HQ","building":"A","floor":"3","room":"301","lat":13.75,"lon":100.5},"tags":["zone:north"],"owner":"facilities"}`,
+			callService:    true,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "duplicate_id",
+			body:           `{"id":"temp-001","name":"Duplicate","type":"temperature"}`,
+			callService:    true,
+			serviceErr:     apierror.Wrap(apierror.ErrDataConflict, "sensor ID temp-001 is not available"),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "missing_name",
+			body:           `{"id":"co2-101","type":"co2"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown_type",
+			body:           `{"id":"x-1","name":"X","type":"radiation"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid_id",
+			body:           `{"id":"bad/id","name":"X","type":"co2"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// ต้องระบุพิกัดทั้ง lat และ lon
+			name:           "lat_without_lon",
+			body:           `{"id":"co2-101","name":"X","type":"co2","location":{"lat":13.75}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed_json",
+			body:           `{"id":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/sensors", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/sensors")
+
+			h, mockService := NewMockSensorHandler(t)
+			if tc.callService {
+				mockService.On("CreateSensor", mock.AnythingOfType("model.SensorRegistrationModel")).
+					Return(`{"id":"co2-101"}`, tc.serviceErr).Once()
+			}
+
+			err := h.CreateSensor(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code, rec.Body.String())
+			if tc.expectedStatus == http.StatusCreated {
+				assert.Equal(t, "/api/sensors/co2-101", rec.Header().Get(echo.HeaderLocation))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestUpdateSensor ทดสอบการแทนที่และแก้ไข metadata บางส่วน
+func TestUpdateSensor(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		serviceMethod  string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{
+			name:           "put_uses_path_id",
+			method:         http.MethodPut,
+			body:           `{"name":"Renamed","type":"temperature"}`,
+			serviceMethod:  "UpdateSensor",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "put_id_mismatch",
+			method:         http.MethodPut,
+			body:           `{"id":"temp-002","name":"Renamed","type":"temperature"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "patch_owner",
+			method:         http.MethodPatch,
+			body:           `{"owner":"ops"}`,
+			serviceMethod:  "PatchSensor",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "patch_unknown_sensor",
+			method:         http.MethodPatch,
+			body:           `{"owner":"ops"}`,
+			serviceMethod:  "PatchSensor",
+			serviceErr:     apierror.Wrap(apierror.ErrDataNotFound, "sensor with ID temp-001 not found"),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "patch_unknown_type",
+			method:         http.MethodPatch,
+			body:           `{"type":"radiation"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tc.method, "/api/sensors/temp-001", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("temp-001")
+
+			h, mockService := NewMockSensorHandler(t)
+			switch tc.serviceMethod {
+			case "UpdateSensor":
+				mockService.On("UpdateSensor", "temp-001", mock.MatchedBy(func(r model.SensorRegistrationModel) bool {
+					return r.ID == "temp-001"
+				})).Return(`{"id":"temp-001"}`, tc.serviceErr).Once()
+			case "PatchSensor":
+				mockService.On("PatchSensor", "temp-001", mock.AnythingOfType("model.SensorPatchModel")).
+					Return(`{"id":"temp-001"}`, tc.serviceErr).Once()
+			}
+
+			var err error
+			if tc.method == http.MethodPut {
+				err = h.UpdateSensor(c)
+			} else {
+				err = h.PatchSensor(c)
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code, rec.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// TestDeleteSensor ทดสอบการลบเซนเซอร์
+func TestDeleteSensor(t *testing.T) {
+	tests := []struct {
+		name           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{name: "deleted", expectedStatus: http.StatusNoContent},
+		{
+			name:           "not_found",
+			serviceErr:     apierror.Wrap(apierror.ErrDataNotFound, "sensor with ID temp-001 not found"),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/api/sensors/temp-001", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("temp-001")
+
+			h, mockService := NewMockSensorHandler(t)
+			mockService.On("DeleteSensor", "temp-001").Return(tc.serviceErr).Once()
+
+			err := h.DeleteSensor(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
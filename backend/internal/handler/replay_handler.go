@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/recording"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// IReplayHandler คือ interface สำหรับ handler ที่ควบคุมการเล่นไฟล์ recording
+type IReplayHandler interface {
+	// GetReplayStatus คืนค่าสถานะการเล่น
+	GetReplayStatus(c echo.Context) error
+
+	// StepReplay เล่น frame ถัดไป
+	StepReplay(c echo.Context) error
+}
+
+// ReplayHandler จัดการเกี่ยวกับ handler ของ replay API
+type ReplayHandler struct {
+	replayer recording.IReplayer
+	logger   *zap.Logger
+}
+
+// NewReplayHandler สร้าง instance ใหม่ของ ReplayHandler
+func NewReplayHandler(replayer recording.IReplayer, logger *zap.Logger) *ReplayHandler {
+	return &ReplayHandler{
+		replayer: replayer,
+		logger:   logger,
+	}
+}
+
+// GetReplayStatus คืนค่าสถานะการเล่นผ่าน GET /api/replay
+func (h *ReplayHandler) GetReplayStatus(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.replayer.Status())
+}
+
+// StepReplay เล่น frame ถัดไปผ่าน POST /api/replay/step (เฉพาะเมื่อเปิด APP_REPLAY_STEP)
+func (h *ReplayHandler) StepReplay(c echo.Context) error {
+	status, err := h.replayer.Step()
+	if err != nil {
+		h.logger.Warn("Failed to step replay", zap.Error(err))
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// MaxBodyBytes คือขนาดสูงสุดของ request body ที่ handler อ่าน (batch ของ reading เต็มจำนวนยังเล็กกว่านี้มาก)
+const MaxBodyBytes = 1 << 20
+
+// readBody อ่าน request body ไม่เกิน MaxBodyBytes เพื่อไม่ให้ body ขนาดใหญ่ใช้หน่วยความจำจนหมด
+// body ที่ใหญ่เกินคืนค่า ErrRequestTooLarge
+func readBody(c echo.Context) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, apierror.Wrap(apierror.ErrRequestTooLarge, fmt.Sprintf("request body exceeds %d bytes", MaxBodyBytes))
+		}
+		return nil, apierror.Wrap(apierror.ErrInvalidRequest, err.Error())
+	}
+	return body, nil
+}
@@ -3,15 +3,17 @@ package handler
 import (
 	"fmt"
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/service"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/tenant"
 )
 
 // ISensorHandler คือ interface สำหรับ handler ที่จัดการเกี่ยวกับ sensor
@@ -24,6 +26,27 @@ type ISensorHandler interface {
 
 	// GetSensorByID คืนค่าข้อมูล sensor ตาม ID
 	GetSensorByID(c echo.Context) error
+
+	// IngestReadings รับค่าที่อุปกรณ์ส่งเข้ามา
+	IngestReadings(c echo.Context) error
+
+	// GetSensorHistory คืนค่าข้อมูลย้อนหลังของ sensor
+	GetSensorHistory(c echo.Context) error
+
+	// GetAlerts คืนค่า alert ที่ยัง active อยู่
+	GetAlerts(c echo.Context) error
+
+	// CreateSensor ลงทะเบียนเซนเซอร์ใหม่
+	CreateSensor(c echo.Context) error
+
+	// UpdateSensor แทนที่ metadata ของเซนเซอร์
+	UpdateSensor(c echo.Context) error
+
+	// PatchSensor แก้ไข metadata บางส่วนของเซนเซอร์
+	PatchSensor(c echo.Context) error
+
+	// DeleteSensor ลบเซนเซอร์
+	DeleteSensor(c echo.Context) error
 }
 
 // SensorHandler จัดการเกี่ยวกับ handler ของ sensor API
@@ -34,127 +57,160 @@ type SensorHandler struct {
 }
 
 // NewSensorHandler สร้าง instance ใหม่ของ SensorHandler
-func NewSensorHandler(logger *zap.Logger) *SensorHandler {
+func NewSensorHandler(sensorService service.ISensorService, logger *zap.Logger) *SensorHandler {
 	return &SensorHandler{
-		sensorService: service.GetSensorService(logger),
+		sensorService: sensorService,
 		logger:        logger,
 	}
 }
 
+// sensors คืนค่า service ของ tenant ที่ระบุไว้ใน request (ไม่มีคือ tenant เริ่มต้น)
+func (h *SensorHandler) sensors(c echo.Context) service.ISensorService {
+	name, _ := tenant.From(c)
+	return h.sensorService.ForTenant(name)
+}
+
 // HandleSSE จัดการกับ Server-Sent Events
+// แต่ละ connection เป็นเพียง subscriber ของ broker กลาง ไม่มี ticker สำหรับดึงข้อมูลเอง
 func (h *SensorHandler) HandleSSE(c echo.Context) error {
-	// ตั้งค่า header สำหรับ SSE
-	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
-	c.Response().Header().Set("Cache-Control", "no-cache")
-	c.Response().Header().Set("Connection", "keep-alive")
-	c.Response().WriteHeader(http.StatusOK)
-
-	// สร้าง channel เพื่อรับสัญญาณการปิดการเชื่อมต่อ
-	done := make(chan bool)
-
-	// ติดตามการตัดการเชื่อมต่อของ client
-	go func() {
-		<-c.Request().Context().Done()
-		close(done)
-	}()
-
 	// ตรวจสอบ Last-Event-ID จาก request header (ถ้ามี)
 	lastEventID := c.Request().Header.Get("Last-Event-ID")
-	var lastTimestamp int64
+
+	// เงื่อนไขการกรองเซนเซอร์จาก query parameter (ids, type, tag)
+	filter := stream.ParseFilter(c.QueryParams())
+
+	// ลงทะเบียนกับ broker ก่อนดึงข้อมูลเริ่มต้น เพื่อไม่ให้พลาดการอัปเดตที่เกิดขึ้นระหว่างนั้น
+	// client เห็นเฉพาะเซนเซอร์ของ tenant ตัวเองทั้งใน snapshot, replay และ event ที่มีหลาย tenant ปนกัน
+	sensors := h.sensors(c)
+	sub, replay := sensors.Subscribe(lastEventID)
+	defer sensors.Unsubscribe(sub)
+	filter = filter.WithTenant(sub.Tenant())
 
 	if lastEventID != "" {
-		// แปลง string เป็น int64
+		h.logger.Info("Reconnection with Last-Event-ID",
+			zap.String("last_event_id", lastEventID),
+			zap.Int("missed_events", len(replay.Events)),
+			zap.Bool("reset", replay.Reset),
+			zap.String("client_ip", c.RealIP()))
+	}
+
+	// ดึง snapshot เฉพาะเมื่อเป็น client ใหม่ หรือ replay ไม่ได้
+	var snapshot stream.Event
+	if lastEventID == "" || replay.Reset {
 		var err error
-		lastTimestamp, err = strconv.ParseInt(lastEventID, 10, 64)
+		snapshot, err = sensors.GetSnapshot()
 		if err != nil {
-			h.logger.Warn("Invalid Last-Event-ID received",
-				zap.String("last_event_id", lastEventID),
+			h.logger.Error("Failed to get initial sensor data",
 				zap.Error(err))
-		} else {
-			h.logger.Info("Reconnection with Last-Event-ID",
-				zap.Int64("last_event_id", lastTimestamp),
-				zap.String("client_ip", c.RealIP()))
+			return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrDataNotFound, "failed to get sensor data"))
 		}
 	}
 
+	// ตั้งค่า header สำหรับ SSE
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	c.Response().Header().Set("Connection", "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+
 	// บันทึก log การเชื่อมต่อ
 	h.logger.Info("Client connected to SSE",
-		zap.String("client_ip", c.RealIP()))
-
-	// Flush buffer เพื่อให้ส่งข้อมูลเริ่มต้นได้ทันที
-	c.Response().Flush()
-
-	// อ่าน hostname ของ container
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = "unknown"
-		h.logger.Warn("Unable to get hostname", zap.Error(err))
-	}
-
-	// ส่งข้อมูลเซ็นเซอร์เริ่มต้น
-	initialData, err := h.sensorService.GetAllSensors()
-	if err != nil {
-		h.logger.Error("Failed to get initial sensor data",
-			zap.Error(err))
-		return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrDataNotFound, "failed to get sensor data"))
+		zap.String("client_ip", c.RealIP()),
+		zap.Bool("filtered", !filter.IsEmpty()))
+
+	metrics.SSEConnectsTotal.Inc()
+	metrics.SSEConnectionsActive.Inc()
+	defer metrics.SSEConnectionsActive.Dec()
+
+	serverID := h.sensorService.ServerID()
+
+	switch {
+	case lastEventID == "":
+		// client ใหม่ ส่ง snapshot เริ่มต้นพร้อม ID ล่าสุดของ stream หลังจากนั้นจะได้เฉพาะ delta
+		data, _ := snapshot.Payload(filter)
+		writeEvent(c.Response(), replay.LastID, stream.EventSnapshot, data)
+	case replay.Reset:
+		// ID ของ client เก่าเกินกว่าประวัติที่เก็บไว้ ส่ง snapshot ทั้งหมดให้เริ่มใหม่
+		data, _ := snapshot.Payload(filter)
+		writeEvent(c.Response(), replay.LastID, stream.EventReset, data)
+	default:
+		// ส่ง event ที่ client พลาดไปตามลำดับเดิม
+		for _, evt := range replay.Events {
+			writeFilteredEvent(c.Response(), evt, filter)
+		}
 	}
 
-	// ใช้ timestamp ปัจจุบันเป็น ID
-	currentTimestamp := time.Now().Unix()
-
-	// เพิ่ม hostname เข้าไปใน response
-	initialDataWithHostname := fmt.Sprintf(`{"server_id":"%s","data":%s}`, hostname, initialData)
-
-	// ส่งข้อมูลเริ่มต้นไปยัง client พร้อม ID
-	fmt.Fprintf(c.Response(), "id: %d\nevent: message\ndata: %s\n\n", currentTimestamp, initialDataWithHostname)
-	c.Response().Flush()
-
-	// ตั้ง ticker สำหรับส่งข้อมูลทุก 2 วินาที
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
 	// ส่ง ping ทุก 30 วินาที เพื่อรักษาการเชื่อมต่อ
 	pingTicker := time.NewTicker(30 * time.Second)
 	defer pingTicker.Stop()
 
+	// ปิด stream เมื่อ credential ที่ใช้เปิด stream หมดอายุ (API key ไม่มีวันหมดอายุ)
+	var expired <-chan time.Time
+	principal, hasPrincipal := auth.PrincipalFrom(c)
+	if hasPrincipal && !principal.ExpiresAt.IsZero() {
+		expiryTimer := time.NewTimer(time.Until(principal.ExpiresAt))
+		defer expiryTimer.Stop()
+		expired = expiryTimer.C
+	}
+
 	// รับและส่งข้อมูลเมื่อมีการอัพเดท
 	for {
 		select {
-		case <-done:
+		case <-c.Request().Context().Done():
 			h.logger.Info("Client disconnected from SSE",
 				zap.String("client_ip", c.RealIP()))
+			metrics.SSEDisconnectsTotal.WithLabelValues("client").Inc()
 			return nil
-		case <-ticker.C:
-			// ดึงข้อมูลเซ็นเซอร์ล่าสุด
-			data, err := h.sensorService.GetAllSensors()
-			if err != nil {
-				h.logger.Error("Failed to get sensor data for SSE update",
-					zap.Error(err))
-				continue
+		case evt, ok := <-sub.Events():
+			if !ok {
+				// broker ถอด subscriber ออกเพราะอ่านไม่ทัน client จะเชื่อมต่อใหม่และได้ replay
+				h.logger.Warn("SSE subscriber dropped by broker",
+					zap.String("client_ip", c.RealIP()))
+				metrics.SSEDisconnectsTotal.WithLabelValues("dropped").Inc()
+				return nil
 			}
-			// ใช้ timestamp ปัจจุบันเป็น ID
-			currentTimestamp = time.Now().Unix()
-
-			// เพิ่ม hostname เข้าไปใน response
-			dataWithHostname := fmt.Sprintf(`{"server_id":"%s","data":%s}`, hostname, data)
-
-			// ส่งข้อมูลอัพเดทไปยัง client พร้อม ID
-			fmt.Fprintf(c.Response(), "id: %d\nevent: message\ndata: %s\n\n", currentTimestamp, dataWithHostname)
-			c.Response().Flush()
+			writeFilteredEvent(c.Response(), evt, filter)
 		case <-pingTicker.C:
-			// ใช้ timestamp ปัจจุบันเป็น ID
-			currentTimestamp = time.Now().Unix()
-			// ส่ง ping เพื่อให้การเชื่อมต่อยังคงอยู่ พร้อม ID และ hostname
-			pingData := fmt.Sprintf(`{"ping": true, "server_id": "%s"}`, hostname)
-			fmt.Fprintf(c.Response(), "id: %d\nevent: ping\ndata: %s\n\n", currentTimestamp, pingData)
-			c.Response().Flush()
+			// ส่ง ping โดยไม่มี ID เพื่อไม่ให้ Last-Event-ID ของ client เปลี่ยน
+			writeEvent(c.Response(), "", stream.EventPing, stream.PingPayload(serverID))
+		case <-expired:
+			h.logger.Info("Closing SSE stream, credentials expired",
+				zap.String("client_ip", c.RealIP()),
+				zap.String("subject", principal.Subject))
+			writeEvent(c.Response(), "", stream.EventTokenExpired, stream.TokenExpiredPayload(serverID, principal.ExpiresAt))
+			metrics.SSEDisconnectsTotal.WithLabelValues("token_expired").Inc()
+			return nil
 		}
 	}
 }
 
+// writeEvent เขียน event หนึ่งรายการในรูปแบบ SSE แล้ว flush ทันที
+// ถ้า id เป็นค่าว่างจะไม่เขียนบรรทัด "id:"
+func writeEvent(res *echo.Response, id string, event string, data []byte) {
+	var written int
+	if id != "" {
+		n, _ := fmt.Fprintf(res, "id: %s\n", id)
+		written += n
+	}
+	n, _ := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, data)
+	written += n
+	res.Flush()
+
+	metrics.SSEEventsSentTotal.WithLabelValues(event).Inc()
+	metrics.SSEBytesWrittenTotal.Add(float64(written))
+}
+
+// writeFilteredEvent เขียน event ตาม Filter ของ client และข้าม event ที่ไม่มีเซนเซอร์ตรงเงื่อนไข
+func writeFilteredEvent(res *echo.Response, evt stream.Event, filter stream.Filter) {
+	data, ok := evt.Payload(filter)
+	if !ok {
+		return
+	}
+	writeEvent(res, evt.ID, evt.Type, data)
+}
+
 // GetSensorData คืนค่าข้อมูล sensor ทั้งหมด
 func (h *SensorHandler) GetSensorData(c echo.Context) error {
-	data, err := h.sensorService.GetAllSensors()
+	data, err := h.sensors(c).GetAllSensors()
 	if err != nil {
 		h.logger.Error("Failed to get sensor data",
 			zap.Error(err))
@@ -171,7 +227,7 @@ func (h *SensorHandler) GetSensorByID(c echo.Context) error {
 		return apierror.HandleAPIError(c, apierror.ErrInvalidRequest)
 	}
 
-	sensorJSON, err := h.sensorService.GetSensorByID(id)
+	sensorJSON, err := h.sensors(c).GetSensorByID(id)
 	if err != nil {
 		h.logger.Error("Failed to get sensor by ID",
 			zap.String("id", id),
@@ -2,72 +2,127 @@ package handler_test
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/handler"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/repository"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/service"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/tenant"
 )
 
-// MockSensorProviderImpl จำลอง SensorProvider สำหรับการทดสอบ
-type MockSensorProviderImpl struct {
+// MockSensorService จำลอง ISensorService สำหรับการทดสอบ
+type MockSensorService struct {
 	mock.Mock
+	broker *stream.Broker
 }
 
-func (m *MockSensorProviderImpl) GetSensorData() (string, error) {
+func (m *MockSensorService) GetAllSensors() (string, error) {
 	args := m.Called()
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockSensorProviderImpl) GetSensorByID(id string) (string, error) {
+func (m *MockSensorService) GetSensorByID(id string) (string, error) {
 	args := m.Called(id)
 	return args.String(0), args.Error(1)
 }
 
-// ในที่นี้เราไม่ได้ใช้ GetLatestData และ Update ดังนั้นทำให้มันเป็น stub
-func (m *MockSensorProviderImpl) GetLatestData() (float64, float64, time.Time) {
-	return 0, 0, time.Now()
+func (m *MockSensorService) IngestReadings(id string, readings []model.ReadingModel) (string, error) {
+	args := m.Called(id, readings)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSensorService) GetSensorHistory(id string, query model.HistoryQuery) (string, error) {
+	args := m.Called(id, query)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSensorService) CreateSensor(registration model.SensorRegistrationModel) (string, error) {
+	args := m.Called(registration)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSensorService) UpdateSensor(id string, registration model.SensorRegistrationModel) (string, error) {
+	args := m.Called(id, registration)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSensorService) PatchSensor(id string, patch model.SensorPatchModel) (string, error) {
+	args := m.Called(id, patch)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSensorService) DeleteSensor(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockSensorService) GetAlerts() (string, error) {
+	args := m.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSensorService) GetSnapshot() (stream.Event, error) {
+	args := m.Called()
+	return args.Get(0).(stream.Event), args.Error(1)
+}
+
+func (m *MockSensorService) Subscribe(lastEventID string) (*stream.Subscriber, stream.Replay) {
+	return m.broker.Subscribe(lastEventID)
+}
+
+func (m *MockSensorService) Unsubscribe(sub *stream.Subscriber) {
+	m.broker.Unsubscribe(sub)
+}
+
+func (m *MockSensorService) ServerID() string {
+	return "test-server"
+}
+
+func (m *MockSensorService) ForTenant(tenant string) service.ISensorService {
+	return m
 }
 
-func (m *MockSensorProviderImpl) Update(temp, humidity float64) {
-	// ไม่ทำอะไร
+func (m *MockSensorService) Close() error {
+	return nil
 }
 
 // NewMockSensorHandler สร้าง handler พร้อม mock dependencies
-func NewMockSensorHandler(t *testing.T) (*handler.SensorHandler, *MockSensorProviderImpl) {
-	// สร้าง mock provider
-	mockProvider := new(MockSensorProviderImpl)
+func NewMockSensorHandler(t *testing.T) (*handler.SensorHandler, *MockSensorService) {
+	// สร้าง mock service ที่ใช้ broker จริง
+	mockService := &MockSensorService{
+		broker: stream.NewBroker(stream.DefaultSubscriberBuffer, stream.DefaultHistorySize, zaptest.NewLogger(t)),
+	}
 
 	// สร้าง handler ด้วย logger จำลอง
-	h := handler.NewSensorHandler(zaptest.NewLogger(t))
-
-	// อาจต้องใช้วิธีอื่นในการฉีด dependency มากกว่านี้ถ้าจำเป็น
-	// เช่น monkey patching ผ่าน struct field หรือ ฉีด mock provider โดยตรงเข้า handler
+	h := handler.NewSensorHandler(mockService, zaptest.NewLogger(t))
 
-	return h, mockProvider
+	return h, mockService
 }
 
 // TestNewSensorHandler ทดสอบการสร้าง SensorHandler
 func TestNewSensorHandler(t *testing.T) {
-	logger := zaptest.NewLogger(t)
-
 	// เรียกใช้ฟังก์ชันที่ต้องการทดสอบ
-	h := handler.NewSensorHandler(logger)
+	h, _ := NewMockSensorHandler(t)
 
 	// ตรวจสอบว่า handler ไม่เป็น nil
 	assert.NotNil(t, h)
 }
 
 func TestHandleSSE(t *testing.T) {
-	// ยกเลิกการทดสอบนี้ชั่วคราว
-	t.Skip("ต้องปรับปรุงการออกแบบของ handler เพื่อรองรับการทดสอบที่ดีขึ้น")
-
 	// สร้าง echo context จำลอง
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/sensors/stream", nil)
@@ -79,42 +134,292 @@ func TestHandleSSE(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	// สร้าง mock provider
-	mockProvider := new(MockSensorProviderImpl)
-
-	// เนื่องจากเรามีปัญหากับการกำหนดค่า sensor.GetInstance
-	// เราจะใช้วิธีนี้แทน: การสร้าง handler และจัดการตรวจสอบข้อมูลหลังจากที่ handler ทำงาน
-	h := handler.NewSensorHandler(zaptest.NewLogger(t))
+	h, mockService := NewMockSensorHandler(t)
 
-	// จำลองการส่งข้อมูล sensor (ไม่ได้ใช้งานจริงเนื่องจากเรา skip)
-	mockProvider.On("GetSensorData").Return(`[{"id":"1","value":25.5}]`, nil).Once()
+	// จำลองการส่งข้อมูล sensor เริ่มต้น
+	mockService.On("GetSnapshot").Return(sensorEvent(`{"id":"1","temperature":25.5}`), nil).Once()
 
 	// รัน handler ใน goroutine แยก
+	done := make(chan error)
 	go func() {
-		_ = h.HandleSSE(c)
-		// ไม่ต้องตรวจสอบค่า error เนื่องจากเรา skip test
+		done <- h.HandleSSE(c)
 	}()
 
-	// รอสักครู่
-	time.Sleep(100 * time.Millisecond)
+	// รอจนกว่า handler จะลงทะเบียนกับ broker แล้วจึง publish การอัปเดต
+	assert.Eventually(t, func() bool {
+		return mockService.broker.SubscriberCount() == 1
+	}, time.Second, 10*time.Millisecond)
+	mockService.broker.Publish(stream.Event{Type: stream.EventSensorUpdated, Data: []byte(`{"update":true}`)})
 
-	// ยกเลิก context เพื่อหยุด handler
+	// รอสักครู่แล้วยกเลิก context เพื่อหยุด handler
+	time.Sleep(50 * time.Millisecond)
 	cancel()
 
-	// รอให้ handler หยุดทำงาน
-	time.Sleep(100 * time.Millisecond)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("handler did not stop after context cancellation")
+	}
+
+	body := rec.Body.String()
+	assert.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, body, `data: {"server_id":"test-server","data":[{"id":"1","temperature":25.5}]}`)
+	assert.Contains(t, body, `data: {"update":true}`)
+	assert.Equal(t, 1, strings.Count(body, "event: snapshot"))
+	assert.Equal(t, 1, strings.Count(body, "event: sensor.updated"))
+	assert.Equal(t, 0, mockService.broker.SubscriberCount())
+	mockService.AssertExpectations(t)
+}
+
+// TestHandleSSEReplay ทดสอบการส่ง event ที่พลาดไปเมื่อ client เชื่อมต่อใหม่พร้อม Last-Event-ID
+func TestHandleSSEReplay(t *testing.T) {
+	h, mockService := NewMockSensorHandler(t)
+
+	// เปิด stream ครั้งแรกเพื่อเอา ID ของ event แรก
+	first, replay := mockService.broker.Subscribe("")
+	mockService.broker.Unsubscribe(first)
+	mockService.broker.Publish(stream.Event{Type: stream.EventSensorUpdated, Data: []byte(`{"n":1}`)})
+	mockService.broker.Publish(stream.Event{Type: stream.EventSensorUpdated, Data: []byte(`{"n":2}`)})
+
+	runStream := func(lastEventID string) string {
+		return runSSE(t, h, "/api/sensors/stream", lastEventID, nil)
+	}
+
+	// client ที่มี ID ล่าสุดก่อน publish ต้องได้ทั้งสอง event โดยไม่มี snapshot
+	body := runStream(replay.LastID)
+	assert.Contains(t, body, `data: {"n":1}`)
+	assert.Contains(t, body, `data: {"n":2}`)
+	assert.NotContains(t, body, "event: reset")
+
+	// ID ที่ไม่รู้จักต้องได้ event reset พร้อม snapshot ทั้งหมด
+	mockService.On("GetSnapshot").Return(sensorEvent(`{"id":"1"}`), nil).Once()
+	body = runStream("unknown-42")
+	assert.Contains(t, body, "event: reset")
+	assert.Contains(t, body, `data: {"server_id":"test-server","data":[{"id":"1"}]}`)
+	assert.NotContains(t, body, `data: {"n":1}`)
+	mockService.AssertExpectations(t)
+}
+
+// TestHandleSSEFilter ทดสอบการกรองเซนเซอร์ตาม query parameter ก่อนส่งให้ client
+func TestHandleSSEFilter(t *testing.T) {
+	h, mockService := NewMockSensorHandler(t)
+
+	mockService.On("GetSnapshot").Return(stream.NewSensorEvent(stream.EventSnapshot, "test-server", []stream.Item{
+		{SensorID: "temp-001", SensorType: "temperature", JSON: []byte(`{"id":"temp-001"}`)},
+		{SensorID: "humid-001", SensorType: "humidity", JSON: []byte(`{"id":"humid-001"}`)},
+	}), nil).Once()
+
+	body := runSSE(t, h, "/api/sensors/stream?type=humidity", "", func() {
+		// event ที่ไม่มีเซนเซอร์ตรงเงื่อนไขต้องไม่ถูกส่ง
+		mockService.broker.Publish(stream.NewSensorEvent(stream.EventSensorUpdated, "test-server", []stream.Item{
+			{SensorID: "temp-001", SensorType: "temperature", JSON: []byte(`{"id":"temp-001","n":2}`)},
+		}))
+	})
+
+	assert.Contains(t, body, `data: {"server_id":"test-server","data":[{"id":"humid-001"}]}`)
+	assert.NotContains(t, body, "temp-001")
+	assert.Equal(t, 1, strings.Count(body, "event: snapshot"))
+	assert.NotContains(t, body, "event: sensor.updated")
+	mockService.AssertExpectations(t)
+}
+
+// TestHandleSSETokenExpiry ทดสอบการปิด stream ที่เปิดด้วย ticket เมื่อ token เดิมหมดอายุ
+func TestHandleSSETokenExpiry(t *testing.T) {
+	h, mockService := NewMockSensorHandler(t)
+	mockService.On("GetSnapshot").Return(sensorEvent(`{"id":"1"}`), nil).Once()
+
+	tickets, err := auth.NewTicketIssuer(nil, time.Minute)
+	require.NoError(t, err)
+	ticket, _, err := tickets.Issue(&auth.Principal{
+		Subject:   "alice",
+		Scopes:    []auth.Scope{auth.ScopeReadSensors},
+		ExpiresAt: time.Now().Add(1500 * time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.GET("/api/sensors/stream", h.HandleSSE, auth.NewGuard().With(tickets).Require(auth.ScopeReadSensors))
+
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sensors/stream?ticket="+ticket, nil))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not closed after the token expired")
+	}
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "event: snapshot")
+	assert.Contains(t, body, "event: token.expired")
+	assert.Equal(t, 0, mockService.broker.SubscriberCount())
+	mockService.AssertExpectations(t)
+}
+
+// TestHandleSSETenantIsolation ทดสอบว่า client ของแต่ละ tenant (ระบุจาก host) เห็นเฉพาะเซนเซอร์ของ tenant ตัวเอง
+func TestHandleSSETenantIsolation(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	sensors := service.NewSensorService(repository.NewSensorRepository(),
+		stream.NewBroker(stream.DefaultSubscriberBuffer, stream.DefaultHistorySize, logger), logger)
+	_, err := sensors.ForTenant("acme").CreateSensor(model.SensorRegistrationModel{ID: "acme-1", Name: "Acme", Type: "temperature"})
+	require.NoError(t, err)
+	_, err = sensors.ForTenant("globex").CreateSensor(model.SensorRegistrationModel{ID: "globex-1", Name: "Globex", Type: "temperature"})
+	require.NoError(t, err)
+
+	resolver, err := tenant.NewResolver(map[string]string{"acme.example.com": "acme", "globex.example.com": "globex"}, "")
+	require.NoError(t, err)
+	h := handler.NewSensorHandler(sensors, logger)
+	e := echo.New()
+	e.GET("/api/sensors/stream", h.HandleSSE, resolver.Middleware())
+	e.GET("/api/sensors/:id", h.GetSensorByID, resolver.Middleware())
+
+	openStream := func(host string) (*httptest.ResponseRecorder, context.CancelFunc, chan struct{}) {
+		req := httptest.NewRequest(http.MethodGet, "/api/sensors/stream", nil)
+		req.Host = host
+		ctx, cancel := context.WithCancel(req.Context())
+		rec := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			e.ServeHTTP(rec, req.WithContext(ctx))
+			close(done)
+		}()
+		return rec, cancel, done
+	}
+
+	acmeRec, acmeCancel, acmeDone := openStream("acme.example.com")
+	globexRec, globexCancel, globexDone := openStream("globex.example.com:8080")
+	time.Sleep(50 * time.Millisecond)
+
+	value := 21.5
+	_, err = sensors.IngestReadings("acme-1", []model.ReadingModel{{Temperature: &value, Timestamp: time.Now()}})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	acmeCancel()
+	globexCancel()
+	<-acmeDone
+	<-globexDone
+
+	acmeBody := acmeRec.Body.String()
+	assert.Contains(t, acmeBody, `"id":"acme-1"`)
+	assert.Contains(t, acmeBody, "event: sensor.updated")
+	assert.NotContains(t, acmeBody, "globex-1")
+	assert.NotContains(t, acmeBody, "temp-001")
+
+	globexBody := globexRec.Body.String()
+	assert.Contains(t, globexBody, `"id":"globex-1"`)
+	assert.NotContains(t, globexBody, "acme-1")
+	assert.NotContains(t, globexBody, "event: sensor.updated")
+
+	// REST API ของ tenant อื่นตอบเหมือนไม่มีเซนเซอร์นี้ และ host ที่ไม่รู้จักถูกปฏิเสธเมื่อไม่มี tenant เริ่มต้น
+	for host, status := range map[string]int{
+		"acme.example.com":   http.StatusOK,
+		"globex.example.com": http.StatusNotFound,
+		"other.example.com":  http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sensors/acme-1", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, status, rec.Code, host)
+	}
+}
+
+// runSSE เปิด stream จำลอง รอให้ subscribe เสร็จ เรียก publish (ถ้ามี) แล้วปิด connection และคืนค่า body
+func runSSE(t *testing.T, h *handler.SensorHandler, target string, lastEventID string, publish func()) string {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	done := make(chan error)
+	go func() {
+		done <- h.HandleSSE(c)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if publish != nil {
+		publish()
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	assert.NoError(t, <-done)
+
+	return rec.Body.String()
+}
+
+// sensorEvent สร้าง event snapshot ที่มีเซนเซอร์ตัวเดียว
+func sensorEvent(sensorJSON string) stream.Event {
+	return stream.NewSensorEvent(stream.EventSnapshot, "test-server", []stream.Item{
+		{SensorID: "1", SensorType: "temperature", JSON: []byte(sensorJSON)},
+	})
 }
 
 // TestGetSensorData ทดสอบการดึงข้อมูล sensor ทั้งหมด
 func TestGetSensorData(t *testing.T) {
-	// เนื่องจากเรามีปัญหากับการกำหนดค่า sensor.GetInstance
-	// เราจะ skip test นี้ด้วย
-	t.Skip("ต้องปรับปรุงการออกแบบของ handler เพื่อรองรับการทดสอบที่ดีขึ้น")
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/sensors", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h, mockService := NewMockSensorHandler(t)
+	mockService.On("GetAllSensors").Return(`[{"id":"temp-001"}]`, nil).Once()
+
+	err := h.GetSensorData(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":"temp-001"}]`, rec.Body.String())
 }
 
 // TestGetSensorByID ทดสอบการดึงข้อมูล sensor ตาม ID
 func TestGetSensorByID(t *testing.T) {
-	// เนื่องจากเรามีปัญหากับการกำหนดค่า sensor.GetInstance
-	// เราจะ skip test นี้ด้วย
-	t.Skip("ต้องปรับปรุงการออกแบบของ handler เพื่อรองรับการทดสอบที่ดีขึ้น")
+	tests := []struct {
+		name           string
+		id             string
+		result         string
+		err            error
+		expectedStatus int
+	}{
+		{
+			name:           "found",
+			id:             "temp-001",
+			result:         `{"id":"temp-001"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not_found",
+			id:             "missing",
+			err:            errors.New("sensor with ID missing not found"),
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/sensors/"+tc.id, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tc.id)
+
+			h, mockService := NewMockSensorHandler(t)
+			mockService.On("GetSensorByID", tc.id).Return(tc.result, tc.err).Once()
+
+			err := h.GetSensorByID(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
 }
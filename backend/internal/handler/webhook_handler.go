@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/notify"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/tenant"
+)
+
+// IWebhookHandler คือ interface สำหรับ handler ที่ใช้ตรวจสอบการส่ง webhook
+type IWebhookHandler interface {
+	// GetDeliveries คืนค่าบันทึกการส่ง webhook ล่าสุด
+	GetDeliveries(c echo.Context) error
+
+	// GetDeadLetters คืนค่า notification ที่ส่งไม่สำเร็จ
+	GetDeadLetters(c echo.Context) error
+
+	// RedeliverDeadLetter นำ notification ที่ส่งไม่สำเร็จกลับมาส่งใหม่
+	RedeliverDeadLetter(c echo.Context) error
+}
+
+// WebhookHandler จัดการเกี่ยวกับ handler ของ webhook API
+type WebhookHandler struct {
+	notifier notify.INotifier
+	logger   *zap.Logger
+}
+
+// NewWebhookHandler สร้าง instance ใหม่ของ WebhookHandler
+func NewWebhookHandler(notifier notify.INotifier, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// tenant คืนค่า tenant ที่ระบุไว้ใน request (ไม่มีคือ tenant เริ่มต้น)
+func (h *WebhookHandler) tenant(c echo.Context) string {
+	if name, ok := tenant.From(c); ok && name != "" {
+		return name
+	}
+	return model.DefaultTenant
+}
+
+// GetDeliveries คืนค่าบันทึกการส่ง webhook ล่าสุดของ tenant (ใหม่สุดก่อน) ผ่าน GET /api/webhooks/deliveries
+func (h *WebhookHandler) GetDeliveries(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.notifier.Deliveries(h.tenant(c)))
+}
+
+// GetDeadLetters คืนค่า notification ของ tenant ที่ส่งไม่สำเร็จผ่าน GET /api/webhooks/dead-letters
+func (h *WebhookHandler) GetDeadLetters(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.notifier.DeadLetters(h.tenant(c)))
+}
+
+// RedeliverDeadLetter นำ notification กลับมาส่งใหม่ผ่าน POST /api/webhooks/dead-letters/:id/redeliver
+func (h *WebhookHandler) RedeliverDeadLetter(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return apierror.HandleAPIError(c, apierror.ErrInvalidRequest)
+	}
+
+	if err := h.notifier.Redeliver(h.tenant(c), id); err != nil {
+		h.logger.Warn("Failed to redeliver dead letter", zap.String("id", id), zap.Error(err))
+		return apierror.HandleAPIError(c, err)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
@@ -0,0 +1,240 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
+)
+
+const (
+	// DefaultConnectTimeout คือเวลาที่รอการเชื่อมต่อครั้งแรกก่อนปล่อยให้ retry อยู่เบื้องหลัง
+	DefaultConnectTimeout = 10 * time.Second
+
+	// DefaultMaxReconnectInterval คือระยะเวลารอสูงสุดระหว่างการเชื่อมต่อใหม่ (backoff เพิ่มเป็นเท่าตัวจนถึงค่านี้)
+	DefaultMaxReconnectInterval = time.Minute
+)
+
+// ReadingWriter คือปลายทางที่ bridge เขียน reading เข้าไป (SensorService)
+type ReadingWriter interface {
+	IngestReadings(id string, readings []model.ReadingModel) (string, error)
+}
+
+// MQTTConfig คือการตั้งค่าของ MQTT bridge
+type MQTTConfig struct {
+	BrokerURL            string
+	ClientID             string
+	Username             string
+	Password             string
+	Topics               []string
+	QoS                  byte
+	ConnectTimeout       time.Duration
+	MaxReconnectInterval time.Duration
+}
+
+// TopicStats คือสถิติการรับข้อมูลของแต่ละ topic filter ที่ subscribe (ไม่แยกตาม topic จริงของแต่ละเซนเซอร์)
+// ค่าเดียวกันถูกเปิดเผยที่ /metrics ด้วย metrics.MQTTMessagesTotal
+type TopicStats struct {
+	Received    uint64    `json:"received"`
+	Errors      uint64    `json:"errors"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitempty"`
+}
+
+// MQTTBridge subscribe topic จาก MQTT broker แล้วแปลง payload เป็น reading ของเซนเซอร์
+// ID ของเซนเซอร์มาจากตำแหน่ง wildcard "+" ตัวแรกใน topic pattern เช่น sensors/+/telemetry
+type MQTTBridge struct {
+	cfg    MQTTConfig
+	writer ReadingWriter
+	client mqtt.Client
+	stats  map[string]*TopicStats
+	mu     sync.Mutex
+	logger *zap.Logger
+}
+
+// NewMQTTBridge สร้าง bridge ใหม่และตรวจสอบ topic pattern
+func NewMQTTBridge(cfg MQTTConfig, writer ReadingWriter, logger *zap.Logger) (*MQTTBridge, error) {
+	if len(cfg.Topics) == 0 {
+		return nil, fmt.Errorf("at least one MQTT topic pattern is required")
+	}
+	for _, pattern := range cfg.Topics {
+		if !strings.Contains(pattern, "+") {
+			return nil, fmt.Errorf("topic pattern %q must contain a '+' wildcard for the sensor ID", pattern)
+		}
+	}
+	if cfg.QoS > 2 {
+		return nil, fmt.Errorf("invalid MQTT QoS %d", cfg.QoS)
+	}
+	if cfg.ConnectTimeout <= 0 {
+		cfg.ConnectTimeout = DefaultConnectTimeout
+	}
+	if cfg.MaxReconnectInterval <= 0 {
+		cfg.MaxReconnectInterval = DefaultMaxReconnectInterval
+	}
+
+	return &MQTTBridge{
+		cfg:    cfg,
+		writer: writer,
+		stats:  make(map[string]*TopicStats),
+		logger: logger,
+	}, nil
+}
+
+// Start เชื่อมต่อ broker และ subscribe ทุก topic pattern
+// ถ้าเชื่อมต่อครั้งแรกไม่สำเร็จภายใน ConnectTimeout จะ retry ต่อเบื้องหลังโดยไม่คืนค่า error
+func (b *MQTTBridge) Start() error {
+	opts := mqtt.NewClientOptions().
+		AddBroker(b.cfg.BrokerURL).
+		SetClientID(b.cfg.ClientID).
+		SetUsername(b.cfg.Username).
+		SetPassword(b.cfg.Password).
+		SetCleanSession(true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(time.Second).
+		SetMaxReconnectInterval(b.cfg.MaxReconnectInterval).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			b.logger.Warn("MQTT connection lost", zap.Error(err))
+		}).
+		SetReconnectingHandler(func(_ mqtt.Client, _ *mqtt.ClientOptions) {
+			b.logger.Info("Reconnecting to MQTT broker", zap.String("broker", b.cfg.BrokerURL))
+		})
+
+	b.client = mqtt.NewClient(opts)
+
+	token := b.client.Connect()
+	if !token.WaitTimeout(b.cfg.ConnectTimeout) {
+		b.logger.Warn("MQTT broker not reachable yet, retrying in background",
+			zap.String("broker", b.cfg.BrokerURL))
+		return nil
+	}
+
+	return token.Error()
+}
+
+// Stop ยกเลิกการเชื่อมต่อจาก broker
+func (b *MQTTBridge) Stop() {
+	if b.client != nil {
+		b.client.Disconnect(250)
+	}
+}
+
+// Stats คืนค่าสำเนาของสถิติแยกตาม topic filter
+func (b *MQTTBridge) Stats() map[string]TopicStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make(map[string]TopicStats, len(b.stats))
+	for topic, stats := range b.stats {
+		result[topic] = *stats
+	}
+	return result
+}
+
+// onConnect subscribe ทุก pattern ใหม่ทุกครั้งที่เชื่อมต่อ (clean session ไม่จำ subscription เดิม)
+func (b *MQTTBridge) onConnect(client mqtt.Client) {
+	b.logger.Info("Connected to MQTT broker", zap.String("broker", b.cfg.BrokerURL))
+
+	for _, pattern := range b.cfg.Topics {
+		token := client.Subscribe(pattern, b.cfg.QoS, func(_ mqtt.Client, msg mqtt.Message) {
+			b.handleMessage(pattern, msg.Topic(), msg.Payload())
+		})
+		go func() {
+			if token.Wait() && token.Error() != nil {
+				b.logger.Error("Failed to subscribe MQTT topic",
+					zap.String("topic", pattern),
+					zap.Error(token.Error()))
+			}
+		}()
+	}
+}
+
+// handleMessage แปลง payload แล้วเขียนผ่าน ReadingWriter พร้อมนับสถิติของ topic filter
+func (b *MQTTBridge) handleMessage(pattern string, topic string, payload []byte) {
+	id, ok := SensorIDFromTopic(pattern, topic)
+	if !ok {
+		b.recordError(pattern, topic, fmt.Errorf("topic does not match pattern %s", pattern))
+		return
+	}
+
+	readings, err := model.ParseReadings(payload)
+	if err != nil {
+		b.recordError(pattern, topic, err)
+		return
+	}
+
+	if _, err := b.writer.IngestReadings(id, readings); err != nil {
+		b.recordError(pattern, topic, err)
+		return
+	}
+
+	metrics.MQTTMessagesTotal.WithLabelValues(pattern, "ingested").Inc()
+	b.mu.Lock()
+	b.topicStats(pattern).Received++
+	b.mu.Unlock()
+}
+
+// recordError นับ error ของ topic filter และบันทึก log พร้อม topic จริงของข้อความ
+func (b *MQTTBridge) recordError(pattern string, topic string, err error) {
+	b.logger.Warn("Failed to ingest MQTT message", zap.String("topic", topic), zap.Error(err))
+	metrics.MQTTMessagesTotal.WithLabelValues(pattern, "error").Inc()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.topicStats(pattern)
+	stats.Errors++
+	stats.LastError = fmt.Sprintf("%s: %v", topic, err)
+	stats.LastErrorAt = time.Now()
+}
+
+// topicStats คืนค่าสถิติของ topic filter และสร้างใหม่ถ้ายังไม่มี (ผู้เรียกต้องถือ lock อยู่แล้ว)
+// จำนวนรายการไม่เกินจำนวน topic filter ที่กำหนด ไม่เพิ่มตามจำนวนเซนเซอร์
+func (b *MQTTBridge) topicStats(pattern string) *TopicStats {
+	stats, ok := b.stats[pattern]
+	if !ok {
+		stats = &TopicStats{}
+		b.stats[pattern] = stats
+	}
+	return stats
+}
+
+// SensorIDFromTopic ดึง ID ของเซนเซอร์จาก topic ตามตำแหน่ง "+" ตัวแรกใน pattern
+func SensorIDFromTopic(pattern string, topic string) (string, bool) {
+	patternLevels := strings.Split(pattern, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	var id string
+	for i, level := range patternLevels {
+		if level == "#" {
+			break
+		}
+		if i >= len(topicLevels) {
+			return "", false
+		}
+
+		switch level {
+		case "+":
+			if id == "" {
+				id = topicLevels[i]
+			}
+		default:
+			if level != topicLevels[i] {
+				return "", false
+			}
+		}
+
+		if i == len(patternLevels)-1 && len(topicLevels) != len(patternLevels) {
+			return "", false
+		}
+	}
+
+	return id, id != ""
+}
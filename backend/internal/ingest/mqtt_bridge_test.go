@@ -0,0 +1,138 @@
+package ingest_test
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/ingest"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
+)
+
+// fakeWriter เก็บ reading ที่ bridge เขียนเข้ามา
+type fakeWriter struct {
+	mu       sync.Mutex
+	readings map[string][]model.ReadingModel
+}
+
+func (w *fakeWriter) IngestReadings(id string, readings []model.ReadingModel) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if id == "unknown" {
+		return "", apierror.Wrap(apierror.ErrDataNotFound, "sensor with ID unknown not found")
+	}
+	w.readings[id] = append(w.readings[id], readings...)
+	return "{}", nil
+}
+
+func (w *fakeWriter) count(id string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.readings[id])
+}
+
+// startBroker เริ่ม MQTT broker ภายใน process บน port ที่ว่างอยู่
+func startBroker(t *testing.T) (*mochi.Server, string) {
+	server := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	require.NoError(t, server.AddHook(new(auth.AllowHook), nil))
+
+	tcp := listeners.NewTCP(listeners.Config{ID: "test", Address: "127.0.0.1:0"})
+	require.NoError(t, server.AddListener(tcp))
+	require.NoError(t, server.Serve())
+	t.Cleanup(func() { _ = server.Close() })
+
+	return server, "tcp://" + tcp.Address()
+}
+
+func TestMQTTBridge(t *testing.T) {
+	server, brokerURL := startBroker(t)
+	writer := &fakeWriter{readings: make(map[string][]model.ReadingModel)}
+
+	bridge, err := ingest.NewMQTTBridge(ingest.MQTTConfig{
+		BrokerURL: brokerURL,
+		ClientID:  "bridge-test",
+		Topics:    []string{"sensors/+/telemetry"},
+		QoS:       1,
+	}, writer, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	require.NoError(t, bridge.Start())
+	defer bridge.Stop()
+
+	// รอจน bridge subscribe สำเร็จ โดยส่งข้อความซ้ำจนกว่าจะได้รับ
+	require.Eventually(t, func() bool {
+		_ = server.Publish("sensors/temp-001/telemetry", []byte(`{"temperature":26.5}`), false, 1)
+		return writer.count("temp-001") > 0
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// batch payload
+	before := writer.count("temp-001")
+	require.NoError(t, server.Publish("sensors/temp-001/telemetry", []byte(`[{"temperature":27},{"temperature":28}]`), false, 1))
+
+	// payload ผิดรูปแบบ และเซนเซอร์ที่ไม่รู้จัก ต้องถูกนับเป็น error ของ topic filter ที่รับข้อความ
+	errorsBefore := testutil.ToFloat64(metrics.MQTTMessagesTotal.WithLabelValues("sensors/+/telemetry", "error"))
+	require.NoError(t, server.Publish("sensors/humid-001/telemetry", []byte(`not json`), false, 1))
+	require.NoError(t, server.Publish("sensors/unknown/telemetry", []byte(`{"humidity":40}`), false, 1))
+
+	require.Eventually(t, func() bool {
+		stats := bridge.Stats()
+		return writer.count("temp-001") >= before+2 && stats["sensors/+/telemetry"].Errors == 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// สถิติแยกตาม topic filter เท่านั้น จำนวนรายการจึงไม่เพิ่มตามจำนวนเซนเซอร์
+	stats := bridge.Stats()
+	require.Len(t, stats, 1)
+	assert.GreaterOrEqual(t, stats["sensors/+/telemetry"].Received, uint64(2))
+	assert.Contains(t, stats["sensors/+/telemetry"].LastError, "sensors/unknown/telemetry")
+	assert.Equal(t, errorsBefore+2, testutil.ToFloat64(metrics.MQTTMessagesTotal.WithLabelValues("sensors/+/telemetry", "error")))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.MQTTMessagesTotal.WithLabelValues("sensors/+/telemetry", "ingested")), 2.0)
+}
+
+func TestNewMQTTBridgeValidation(t *testing.T) {
+	writer := &fakeWriter{readings: make(map[string][]model.ReadingModel)}
+
+	_, err := ingest.NewMQTTBridge(ingest.MQTTConfig{Topics: []string{"sensors/all"}}, writer, zaptest.NewLogger(t))
+	assert.Error(t, err)
+
+	_, err = ingest.NewMQTTBridge(ingest.MQTTConfig{}, writer, zaptest.NewLogger(t))
+	assert.Error(t, err)
+}
+
+func TestSensorIDFromTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		id      string
+		ok      bool
+	}{
+		{pattern: "sensors/+/telemetry", topic: "sensors/temp-001/telemetry", id: "temp-001", ok: true},
+		{pattern: "sensors/+/telemetry", topic: "sensors/temp-001/status", ok: false},
+		{pattern: "sensors/+/telemetry", topic: "sensors/temp-001/telemetry/extra", ok: false},
+		{pattern: "site/+/+/data", topic: "site/a/temp-002/data", id: "a", ok: true},
+		{pattern: "devices/+/#", topic: "devices/combined-001/env/raw", id: "combined-001", ok: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.topic, func(t *testing.T) {
+			id, ok := ingest.SensorIDFromTopic(tc.pattern, tc.topic)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.id, id)
+		})
+	}
+}
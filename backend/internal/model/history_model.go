@@ -0,0 +1,132 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+const (
+	// DefaultHistoryRange คือช่วงเวลาเริ่มต้นเมื่อไม่ได้ระบุ from
+	DefaultHistoryRange = time.Hour
+
+	// DefaultHistoryPoints คือจำนวนจุดโดยประมาณเมื่อไม่ได้ระบุ step
+	DefaultHistoryPoints = 300
+
+	// MaxHistoryPoints คือจำนวน bucket สูงสุดที่ query หนึ่งครั้งขอได้
+	MaxHistoryPoints = 10000
+
+	// MaxHistoryRange คือช่วงเวลายาวสุดที่ query หนึ่งครั้งขอได้ เท่ากับ retention เริ่มต้นของ tier รายชั่วโมง
+	// ซึ่งเก็บนานที่สุด ช่วงที่ยาวกว่านี้ต้องอ่าน segment รายวันจำนวนมากโดยไม่มีข้อมูลให้คืน
+	MaxHistoryRange = 2 * 365 * 24 * time.Hour
+)
+
+// HistoryAgg คือวิธีสรุปค่าในแต่ละ bucket
+type HistoryAgg string
+
+const (
+	AggAvg  HistoryAgg = "avg"
+	AggMin  HistoryAgg = "min"
+	AggMax  HistoryAgg = "max"
+	AggLast HistoryAgg = "last"
+)
+
+// HistoryQuery คือเงื่อนไขการดึงข้อมูลย้อนหลังในช่วง [From, To) แบ่งเป็นช่วงละ Step
+type HistoryQuery struct {
+	From time.Time     `validate:"required"`
+	To   time.Time     `validate:"required,gtfield=From"`
+	Step time.Duration `validate:"min=1s"`
+	Agg  HistoryAgg    `validate:"oneof=avg min max last"`
+}
+
+// HistoryPoint คือค่าของ metric หนึ่งใน bucket หนึ่ง
+type HistoryPoint struct {
+	Time  time.Time `json:"t"`
+	Value float64   `json:"v"`
+	Count int       `json:"count"`
+}
+
+// HistoryModel คือผลลัพธ์ของ history API แยก series ตามชื่อ metric
+type HistoryModel struct {
+	SensorID string                    `json:"sensor_id"`
+	From     time.Time                 `json:"from"`
+	To       time.Time                 `json:"to"`
+	Step     string                    `json:"step"`
+	Agg      HistoryAgg                `json:"agg"`
+	Series   map[string][]HistoryPoint `json:"series"`
+}
+
+// ParseHistoryQuery แปลง query parameter from, to, step, agg เป็น HistoryQuery
+// from/to รับได้ทั้ง RFC3339, unix seconds หรือ duration ย้อนหลังจาก now เช่น -24h
+func ParseHistoryQuery(values url.Values, now time.Time) (HistoryQuery, error) {
+	q := HistoryQuery{To: now, Agg: AggAvg}
+
+	var err error
+	if raw := values.Get("to"); raw != "" {
+		if q.To, err = parseHistoryTime(raw, now); err != nil {
+			return HistoryQuery{}, apierror.Wrap(apierror.ErrInvalidRequest, fmt.Sprintf("invalid to: %v", err))
+		}
+	}
+
+	q.From = q.To.Add(-DefaultHistoryRange)
+	if raw := values.Get("from"); raw != "" {
+		if q.From, err = parseHistoryTime(raw, now); err != nil {
+			return HistoryQuery{}, apierror.Wrap(apierror.ErrInvalidRequest, fmt.Sprintf("invalid from: %v", err))
+		}
+	}
+
+	if raw := values.Get("step"); raw != "" {
+		if q.Step, err = time.ParseDuration(raw); err != nil {
+			return HistoryQuery{}, apierror.Wrap(apierror.ErrInvalidRequest, fmt.Sprintf("invalid step: %v", err))
+		}
+	} else {
+		q.Step = defaultHistoryStep(q.To.Sub(q.From))
+	}
+
+	if raw := values.Get("agg"); raw != "" {
+		q.Agg = HistoryAgg(strings.ToLower(raw))
+	}
+
+	if err := validate.Struct(q); err != nil {
+		return HistoryQuery{}, apierror.Wrap(apierror.ErrDataInvalid, validationMessage(err))
+	}
+
+	if span := q.To.Sub(q.From); span > MaxHistoryRange {
+		return HistoryQuery{}, apierror.Wrap(apierror.ErrDataInvalid,
+			fmt.Sprintf("query range %s exceeds maximum %s", span, MaxHistoryRange))
+	}
+
+	if points := q.To.Sub(q.From) / q.Step; points > MaxHistoryPoints {
+		return HistoryQuery{}, apierror.Wrap(apierror.ErrDataInvalid,
+			fmt.Sprintf("query would return %d points, maximum is %d", points, MaxHistoryPoints))
+	}
+
+	return q, nil
+}
+
+// parseHistoryTime แปลงเวลาได้หลายรูปแบบ: RFC3339, unix seconds หรือ duration ย้อนหลัง
+func parseHistoryTime(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if sec, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d <= 0 {
+		return now.Add(d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not RFC3339, unix seconds or a negative duration", raw)
+}
+
+// defaultHistoryStep เลือก step ที่ให้จำนวนจุดประมาณ DefaultHistoryPoints ปัดเป็นวินาที
+func defaultHistoryStep(span time.Duration) time.Duration {
+	step := (span / DefaultHistoryPoints).Truncate(time.Second)
+	if step < time.Second {
+		return time.Second
+	}
+	return step
+}
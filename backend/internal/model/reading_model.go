@@ -0,0 +1,93 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// MaxReadingsPerBatch คือจำนวน reading สูงสุดที่รับได้ใน request เดียว
+const MaxReadingsPerBatch = 1000
+
+// MaxClockSkew คือเวลาที่ timestamp ของ reading ล้ำหน้าเวลาของ server ได้มากที่สุด (เผื่อนาฬิกาของอุปกรณ์ไม่ตรง)
+// reading ที่ล้ำหน้ากว่านี้จะถูกปฏิเสธ เพราะจะกลายเป็นค่าล่าสุดและบังค่าจริงที่ส่งมาหลังจากนั้น
+const MaxClockSkew = 5 * time.Minute
+
+// validate ใช้ตรวจสอบ reading ที่ส่งเข้ามาจากทุกช่องทาง (HTTP, MQTT)
+var validate = validator.New()
+
+// ReadingModel คือค่าที่อุปกรณ์ส่งเข้ามาหนึ่งครั้ง ต้องมีอย่างน้อยหนึ่งค่า
+// ค่าอื่นนอกจากอุณหภูมิและความชื้นส่งผ่าน Metrics ตามชื่อ metric เช่น {"metrics":{"co2":812}}
+// ช่วงค่าของแต่ละ metric ตรวจสอบตามชนิดของเซนเซอร์ตอนบันทึก
+type ReadingModel struct {
+	Temperature *float64           `json:"temperature,omitempty" validate:"omitempty,gte=-50,lte=150"`
+	Humidity    *float64           `json:"humidity,omitempty" validate:"omitempty,gte=0,lte=100"`
+	Metrics     map[string]float64 `json:"metrics,omitempty" validate:"omitempty,dive,keys,required,max=64,endkeys"`
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+// Values รวมค่าจาก field เดิม (temperature, humidity) และ Metrics เป็น map ตามชื่อ metric
+func (r ReadingModel) Values() map[string]float64 {
+	values := make(map[string]float64, len(r.Metrics)+2)
+	for name, value := range r.Metrics {
+		values[name] = value
+	}
+	if r.Temperature != nil {
+		values[MetricTemperature] = *r.Temperature
+	}
+	if r.Humidity != nil {
+		values[MetricHumidity] = *r.Humidity
+	}
+	return values
+}
+
+// ParseReadings แปลง JSON เป็น slice ของ reading และตรวจสอบความถูกต้อง
+// รองรับทั้ง reading เดียว (JSON object) และหลาย reading (JSON array)
+func ParseReadings(raw []byte) ([]ReadingModel, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, apierror.Wrap(apierror.ErrInvalidRequest, "request body is empty")
+	}
+
+	var readings []ReadingModel
+	if raw[0] == '[' {
+		if err := json.Unmarshal(raw, &readings); err != nil {
+			return nil, apierror.Wrap(apierror.ErrInvalidRequest, fmt.Sprintf("malformed readings: %v", err))
+		}
+	} else {
+		var reading ReadingModel
+		if err := json.Unmarshal(raw, &reading); err != nil {
+			return nil, apierror.Wrap(apierror.ErrInvalidRequest, fmt.Sprintf("malformed reading: %v", err))
+		}
+		readings = []ReadingModel{reading}
+	}
+
+	if err := validate.Var(readings, fmt.Sprintf("min=1,max=%d,dive", MaxReadingsPerBatch)); err != nil {
+		return nil, apierror.Wrap(apierror.ErrDataInvalid, validationMessage(err))
+	}
+	latest := time.Now().Add(MaxClockSkew)
+	for i, reading := range readings {
+		if len(reading.Values()) == 0 {
+			return nil, apierror.Wrap(apierror.ErrDataInvalid, fmt.Sprintf("reading #%d has no values", i+1))
+		}
+		if reading.Timestamp.After(latest) {
+			return nil, apierror.Wrap(apierror.ErrDataInvalid, fmt.Sprintf("reading #%d timestamp is in the future", i+1))
+		}
+	}
+
+	return readings, nil
+}
+
+// validationMessage แปลงผลการตรวจสอบเป็นข้อความที่อ่านง่าย
+func validationMessage(err error) string {
+	if validationErrors, ok := err.(validator.ValidationErrors); ok && len(validationErrors) > 0 {
+		e := validationErrors[0]
+		return fmt.Sprintf("validation failed for field %s on '%s' tag", e.Namespace(), e.Tag())
+	}
+	return err.Error()
+}
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// sensorIDPattern จำกัด ID ให้ใช้เป็น path parameter และชื่อ topic ของ MQTT ได้โดยไม่ต้อง escape
+var sensorIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+func init() {
+	_ = validate.RegisterValidation("sensorid", func(fl validator.FieldLevel) bool {
+		return sensorIDPattern.MatchString(fl.Field().String())
+	})
+}
+
+// LocationModel คือตำแหน่งที่ติดตั้งเซนเซอร์ ระบุเป็นลำดับชั้นและ/หรือพิกัดก็ได้
+type LocationModel struct {
+	Site     string   `json:"site,omitempty" validate:"max=128"`
+	Building string   `json:"building,omitempty" validate:"max=128"`
+	Floor    string   `json:"floor,omitempty" validate:"max=32"`
+	Room     string   `json:"room,omitempty" validate:"max=128"`
+	Lat      *float64 `json:"lat,omitempty" validate:"required_with=Lon,omitempty,gte=-90,lte=90"`
+	Lon      *float64 `json:"lon,omitempty" validate:"required_with=Lat,omitempty,gte=-180,lte=180"`
+}
+
+// SensorRegistrationModel คือข้อมูลสำหรับลงทะเบียนหรือแทนที่ metadata ของเซนเซอร์
+// ผ่าน POST /api/sensors และ PUT /api/sensors/:id
+type SensorRegistrationModel struct {
+	ID       string         `json:"id" validate:"required,max=64,sensorid"`
+	Name     string         `json:"name" validate:"required,max=128"`
+	Type     string         `json:"type" validate:"required,max=64"`
+	Location *LocationModel `json:"location,omitempty"`
+	Tags     []string       `json:"tags,omitempty" validate:"max=32,dive,required,max=64"`
+	Owner    string         `json:"owner,omitempty" validate:"max=128"`
+}
+
+// SensorPatchModel คือการแก้ไข metadata บางส่วนผ่าน PATCH /api/sensors/:id
+// field ที่ไม่ได้ส่งมา (nil) จะคงค่าเดิม
+type SensorPatchModel struct {
+	Name     *string        `json:"name" validate:"omitempty,min=1,max=128"`
+	Type     *string        `json:"type" validate:"omitempty,min=1,max=64"`
+	Location *LocationModel `json:"location"`
+	Tags     *[]string      `json:"tags" validate:"omitempty,max=32,dive,required,max=64"`
+	Owner    *string        `json:"owner" validate:"omitempty,max=128"`
+}
+
+// ParseSensorRegistration แปลง JSON เป็นข้อมูลการลงทะเบียนและตรวจสอบความถูกต้อง
+// id ที่ไม่ว่างจะถูกใช้แทน id ใน body (PUT) และต้องตรงกันถ้า body ระบุมาด้วย
+func ParseSensorRegistration(raw []byte, id string) (SensorRegistrationModel, error) {
+	var registration SensorRegistrationModel
+	if err := decodeJSON(raw, &registration); err != nil {
+		return SensorRegistrationModel{}, err
+	}
+
+	if id != "" {
+		if registration.ID != "" && registration.ID != id {
+			return SensorRegistrationModel{}, apierror.Wrap(apierror.ErrInvalidRequest, fmt.Sprintf("body id %s does not match path id %s", registration.ID, id))
+		}
+		registration.ID = id
+	}
+
+	if err := validate.Struct(registration); err != nil {
+		return SensorRegistrationModel{}, apierror.Wrap(apierror.ErrDataInvalid, validationMessage(err))
+	}
+	if err := validateTypeName(registration.Type); err != nil {
+		return SensorRegistrationModel{}, err
+	}
+
+	return registration, nil
+}
+
+// ParseSensorPatch แปลง JSON เป็นการแก้ไขบางส่วนและตรวจสอบความถูกต้อง
+func ParseSensorPatch(raw []byte) (SensorPatchModel, error) {
+	var patch SensorPatchModel
+	if err := decodeJSON(raw, &patch); err != nil {
+		return SensorPatchModel{}, err
+	}
+
+	if err := validate.Struct(patch); err != nil {
+		return SensorPatchModel{}, apierror.Wrap(apierror.ErrDataInvalid, validationMessage(err))
+	}
+	if patch.Type != nil {
+		if err := validateTypeName(*patch.Type); err != nil {
+			return SensorPatchModel{}, err
+		}
+	}
+
+	return patch, nil
+}
+
+// Apply เขียน metadata จากการลงทะเบียนทับเซนเซอร์ ค่าที่วัดได้และสถานะไม่เปลี่ยน
+func (r SensorRegistrationModel) Apply(sensor *SensorModel) {
+	sensor.ID = r.ID
+	sensor.Name = r.Name
+	sensor.Location = copyLocation(r.Location)
+	sensor.Tags = append([]string(nil), r.Tags...)
+	sensor.Owner = r.Owner
+	sensor.changeType(r.Type)
+}
+
+// Apply เขียนเฉพาะ field ที่ส่งมาทับเซนเซอร์
+func (p SensorPatchModel) Apply(sensor *SensorModel) {
+	if p.Name != nil {
+		sensor.Name = *p.Name
+	}
+	if p.Location != nil {
+		sensor.Location = copyLocation(p.Location)
+	}
+	if p.Tags != nil {
+		sensor.Tags = append([]string(nil), (*p.Tags)...)
+	}
+	if p.Owner != nil {
+		sensor.Owner = *p.Owner
+	}
+	if p.Type != nil {
+		sensor.changeType(*p.Type)
+	}
+}
+
+// changeType เปลี่ยนชนิดของเซนเซอร์และลบค่าของ metric ที่ชนิดใหม่ไม่ได้วัด
+func (s *SensorModel) changeType(name string) {
+	if s.Type == name {
+		return
+	}
+	s.Type = name
+
+	sensorType, ok := LookupSensorType(name)
+	if !ok {
+		return
+	}
+	values := s.Values()
+	s.Metrics = nil
+	for metric, value := range values {
+		if _, ok := sensorType.Metric(metric); ok {
+			s.SetValue(metric, value)
+		}
+	}
+}
+
+// validateTypeName ตรวจสอบว่าชนิดเซนเซอร์มีอยู่ใน registry
+func validateTypeName(name string) error {
+	if _, ok := LookupSensorType(name); !ok {
+		return apierror.Wrap(apierror.ErrDataInvalid, fmt.Sprintf("unknown sensor type %s", name))
+	}
+	return nil
+}
+
+// decodeJSON แปลง body ที่ต้องเป็น JSON object และไม่ว่าง
+func decodeJSON(raw []byte, v any) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return apierror.Wrap(apierror.ErrInvalidRequest, "request body is empty")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return apierror.Wrap(apierror.ErrInvalidRequest, fmt.Sprintf("malformed body: %v", err))
+	}
+	return nil
+}
+
+// copyLocation สร้างสำเนาของตำแหน่งรวมถึงพิกัด
+func copyLocation(location *LocationModel) *LocationModel {
+	if location == nil {
+		return nil
+	}
+	locationCopy := *location
+	if location.Lat != nil {
+		lat := *location.Lat
+		locationCopy.Lat = &lat
+	}
+	if location.Lon != nil {
+		lon := *location.Lon
+		locationCopy.Lon = &lon
+	}
+	return &locationCopy
+}
@@ -1,16 +1,135 @@
 package model
 
 import (
+	"encoding/json"
+	"maps"
 	"time"
 )
 
+// สถานะของเซนเซอร์ที่ watchdog กำหนดตามเวลาที่ได้รับข้อมูลล่าสุด
+const (
+	StatusActive  = "active"
+	StatusStale   = "stale"
+	StatusOffline = "offline"
+)
+
+// DefaultTenant คือ tenant ของเซนเซอร์ที่ไม่ได้ระบุ tenant (เช่นเซนเซอร์จำลองและข้อมูลที่บันทึกก่อนรองรับหลาย tenant)
+const DefaultTenant = "default"
+
+// Measurement คือค่าล่าสุดของ metric หนึ่งพร้อมหน่วย
+type Measurement struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
 // SensorModel คือข้อมูลของเซนเซอร์ที่จะส่งกลับไปให้ client
+// ค่าที่วัดได้เก็บใน Metrics ตามชื่อ metric ของชนิดเซนเซอร์ (ดู SensorType)
 type SensorModel struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Type        string    `json:"type"`
-	Temperature float64   `json:"temperature"`
-	Humidity    float64   `json:"humidity"`
-	Timestamp   time.Time `json:"timestamp"`
-	Status      string    `json:"status"`
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type"`
+	Metrics   map[string]Measurement `json:"metrics"`
+	Timestamp time.Time              `json:"timestamp"`
+	Status    string                 `json:"status"`
+	LastSeen  time.Time              `json:"last_seen"`
+	Tags      []string               `json:"tags,omitempty"`
+	Location  *LocationModel         `json:"location,omitempty"`
+	Owner     string                 `json:"owner,omitempty"`
+	Tenant    string                 `json:"tenant,omitempty"`
+}
+
+// Value คืนค่าล่าสุดของ metric และบอกว่าเซนเซอร์มีค่านี้หรือไม่
+func (s *SensorModel) Value(metric string) (float64, bool) {
+	measurement, ok := s.Metrics[metric]
+	return measurement.Value, ok
+}
+
+// SetValue เขียนค่าของ metric โดยใช้หน่วยจาก registry ของชนิดเซนเซอร์
+func (s *SensorModel) SetValue(metric string, value float64) {
+	if s.Metrics == nil {
+		s.Metrics = make(map[string]Measurement)
+	}
+
+	measurement := Measurement{Value: value}
+	if sensorType, ok := LookupSensorType(s.Type); ok {
+		if spec, ok := sensorType.Metric(metric); ok {
+			measurement.Unit = spec.Unit
+		}
+	}
+	s.Metrics[metric] = measurement
+}
+
+// Values คืนค่าล่าสุดของทุก metric โดยไม่มีหน่วย
+func (s *SensorModel) Values() map[string]float64 {
+	values := make(map[string]float64, len(s.Metrics))
+	for name, measurement := range s.Metrics {
+		values[name] = measurement.Value
+	}
+	return values
+}
+
+// Clone สร้างสำเนาของเซนเซอร์รวมถึง map และ slice ภายใน
+func (s *SensorModel) Clone() *SensorModel {
+	sensorCopy := *s
+	sensorCopy.Metrics = maps.Clone(s.Metrics)
+	sensorCopy.Tags = append([]string(nil), s.Tags...)
+	sensorCopy.Location = copyLocation(s.Location)
+	return &sensorCopy
+}
+
+// sensorAlias ใช้หลีกเลี่ยงการเรียก MarshalJSON/UnmarshalJSON ซ้ำ
+type sensorAlias SensorModel
+
+// sensorJSON คือรูปแบบ JSON ของเซนเซอร์ที่ยังมี field temperature และ humidity
+// สำหรับ client เดิม (frontend) ซึ่งมีค่าเป็น 0 เมื่อเซนเซอร์ไม่ได้วัดค่านั้น
+type sensorJSON struct {
+	*sensorAlias
+	Temperature *float64 `json:"temperature,omitempty"`
+	Humidity    *float64 `json:"humidity,omitempty"`
+}
+
+// MarshalJSON เพิ่ม field temperature และ humidity จาก Metrics เพื่อให้ JSON เข้ากันได้กับรูปแบบเดิม
+func (s SensorModel) MarshalJSON() ([]byte, error) {
+	temperature, _ := s.Value(MetricTemperature)
+	humidity, _ := s.Value(MetricHumidity)
+
+	alias := sensorAlias(s)
+	if alias.Metrics == nil {
+		alias.Metrics = map[string]Measurement{}
+	}
+	return json.Marshal(sensorJSON{
+		sensorAlias: &alias,
+		Temperature: &temperature,
+		Humidity:    &humidity,
+	})
+}
+
+// UnmarshalJSON รองรับทั้งรูปแบบใหม่ (metrics) และรูปแบบเดิม (temperature, humidity)
+// ค่าจากรูปแบบเดิมจะถูกใช้เฉพาะ metric ที่ชนิดเซนเซอร์วัดได้และยังไม่มีใน metrics
+func (s *SensorModel) UnmarshalJSON(data []byte) error {
+	decoded := sensorJSON{sensorAlias: (*sensorAlias)(s)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	legacy := map[string]*float64{
+		MetricTemperature: decoded.Temperature,
+		MetricHumidity:    decoded.Humidity,
+	}
+	sensorType, known := LookupSensorType(s.Type)
+	for metric, value := range legacy {
+		if value == nil {
+			continue
+		}
+		if _, ok := s.Metrics[metric]; ok {
+			continue
+		}
+		if known {
+			if _, ok := sensorType.Metric(metric); !ok {
+				continue
+			}
+		}
+		s.SetValue(metric, *value)
+	}
+	return nil
 }
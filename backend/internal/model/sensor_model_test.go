@@ -0,0 +1,92 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+)
+
+// TestSensorModelJSON ทดสอบว่า JSON ยังมี field temperature และ humidity สำหรับ client เดิม
+func TestSensorModelJSON(t *testing.T) {
+	sensor := &model.SensorModel{ID: "temp-001", Type: "temperature"}
+	sensor.SetValue(model.MetricTemperature, 25.5)
+
+	data, err := json.Marshal(sensor)
+	require.NoError(t, err)
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, 25.5, raw["temperature"])
+	assert.Equal(t, 0.0, raw["humidity"])
+	assert.Equal(t, map[string]any{"temperature": map[string]any{"value": 25.5, "unit": "°C"}}, raw["metrics"])
+
+	var decoded model.SensorModel
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, sensor.Metrics, decoded.Metrics)
+}
+
+// TestSensorModelLegacyJSON ทดสอบการอ่าน JSON รูปแบบเดิมที่ไม่มี metrics
+func TestSensorModelLegacyJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want map[string]model.Measurement
+	}{
+		{
+			name: "temperature_sensor",
+			json: `{"id":"temp-001","type":"temperature","temperature":22.5,"humidity":0}`,
+			want: map[string]model.Measurement{"temperature": {Value: 22.5, Unit: "°C"}},
+		},
+		{
+			name: "combined_sensor",
+			json: `{"id":"combined-001","type":"combined","temperature":24,"humidity":40}`,
+			want: map[string]model.Measurement{
+				"temperature": {Value: 24, Unit: "°C"},
+				"humidity":    {Value: 40, Unit: "%"},
+			},
+		},
+		{
+			// ค่าใน metrics มีความสำคัญกว่า field เดิม
+			name: "metrics_take_precedence",
+			json: `{"id":"humid-001","type":"humidity","humidity":10,"metrics":{"humidity":{"value":55,"unit":"%"}}}`,
+			want: map[string]model.Measurement{"humidity": {Value: 55, Unit: "%"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var sensor model.SensorModel
+			require.NoError(t, json.Unmarshal([]byte(tc.json), &sensor))
+			assert.Equal(t, tc.want, sensor.Metrics)
+		})
+	}
+}
+
+// TestRegisterSensorType ทดสอบการลงทะเบียนชนิดเซนเซอร์และการตรวจสอบช่วงค่า
+func TestRegisterSensorType(t *testing.T) {
+	err := model.RegisterSensorType(model.SensorType{
+		Name:    "soil",
+		Metrics: []model.MetricSpec{{Name: "moisture", Unit: "%", Min: 0, Max: 100}},
+	})
+	require.NoError(t, err)
+
+	soil, ok := model.LookupSensorType("soil")
+	require.True(t, ok)
+	assert.NoError(t, soil.ValidateValues(map[string]float64{"moisture": 35}))
+	assert.Error(t, soil.ValidateValues(map[string]float64{"moisture": 120}))
+	assert.Error(t, soil.ValidateValues(map[string]float64{"temperature": 20}))
+
+	// ช่วงค่าที่ Max ไม่มากกว่า Min และ metric ซ้ำต้องถูกปฏิเสธ
+	assert.Error(t, model.RegisterSensorType(model.SensorType{
+		Name:    "broken",
+		Metrics: []model.MetricSpec{{Name: "x", Min: 10, Max: 10}},
+	}))
+	assert.Error(t, model.RegisterSensorType(model.SensorType{
+		Name:    "duplicate",
+		Metrics: []model.MetricSpec{{Name: "x", Max: 1}, {Name: "x", Max: 2}},
+	}))
+}
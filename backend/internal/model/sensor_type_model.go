@@ -0,0 +1,186 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// ชื่อ metric ที่ใช้ร่วมกันระหว่างชนิดเซนเซอร์
+const (
+	MetricTemperature = "temperature"
+	MetricHumidity    = "humidity"
+	MetricCO2         = "co2"
+	MetricPressure    = "pressure"
+	MetricPM25        = "pm2_5"
+	MetricPM10        = "pm10"
+	MetricBattery     = "battery"
+)
+
+// MetricSpec กำหนด metric หนึ่งที่ชนิดเซนเซอร์วัดได้ พร้อมหน่วยและช่วงค่าที่ถูกต้อง
+type MetricSpec struct {
+	Name string  `json:"name" yaml:"name" validate:"required,max=64"`
+	Unit string  `json:"unit" yaml:"unit" validate:"max=16"`
+	Min  float64 `json:"min" yaml:"min"`
+	Max  float64 `json:"max" yaml:"max" validate:"gtfield=Min"`
+}
+
+// SensorType กำหนดชนิดของเซนเซอร์และ metric ที่เซนเซอร์ชนิดนี้ส่งออกมา
+type SensorType struct {
+	Name    string       `json:"name" yaml:"name" validate:"required,max=64"`
+	Metrics []MetricSpec `json:"metrics" yaml:"metrics" validate:"min=1,dive"`
+}
+
+// Metric คืนค่า spec ของ metric ตามชื่อ
+func (t SensorType) Metric(name string) (MetricSpec, bool) {
+	for _, spec := range t.Metrics {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return MetricSpec{}, false
+}
+
+// ValidateValues ตรวจสอบว่าทุกค่าเป็น metric ของชนิดนี้และอยู่ในช่วงที่กำหนด
+func (t SensorType) ValidateValues(values map[string]float64) error {
+	for name, value := range values {
+		spec, ok := t.Metric(name)
+		if !ok {
+			return apierror.Wrap(apierror.ErrDataInvalid, fmt.Sprintf("metric %s is not supported by sensor type %s", name, t.Name))
+		}
+		if value < spec.Min || value > spec.Max {
+			return apierror.Wrap(apierror.ErrDataInvalid, fmt.Sprintf("metric %s value %g is out of range [%g, %g]", name, value, spec.Min, spec.Max))
+		}
+	}
+	return nil
+}
+
+// builtinSensorTypes คือชนิดเซนเซอร์ที่ลงทะเบียนไว้ตั้งแต่เริ่มต้น
+var builtinSensorTypes = []SensorType{
+	{Name: "temperature", Metrics: []MetricSpec{
+		{Name: MetricTemperature, Unit: "°C", Min: -50, Max: 150},
+	}},
+	{Name: "humidity", Metrics: []MetricSpec{
+		{Name: MetricHumidity, Unit: "%", Min: 0, Max: 100},
+	}},
+	{Name: "combined", Metrics: []MetricSpec{
+		{Name: MetricTemperature, Unit: "°C", Min: -50, Max: 150},
+		{Name: MetricHumidity, Unit: "%", Min: 0, Max: 100},
+	}},
+	{Name: "co2", Metrics: []MetricSpec{
+		{Name: MetricCO2, Unit: "ppm", Min: 0, Max: 10000},
+		{Name: MetricBattery, Unit: "%", Min: 0, Max: 100},
+	}},
+	{Name: "pressure", Metrics: []MetricSpec{
+		{Name: MetricPressure, Unit: "hPa", Min: 300, Max: 1100},
+	}},
+	{Name: "air_quality", Metrics: []MetricSpec{
+		{Name: MetricPM25, Unit: "µg/m³", Min: 0, Max: 1000},
+		{Name: MetricPM10, Unit: "µg/m³", Min: 0, Max: 1000},
+		{Name: MetricBattery, Unit: "%", Min: 0, Max: 100},
+	}},
+}
+
+var (
+	sensorTypes   = make(map[string]SensorType)
+	sensorTypesMu sync.RWMutex
+)
+
+func init() {
+	for _, sensorType := range builtinSensorTypes {
+		sensorTypes[sensorType.Name] = sensorType
+	}
+}
+
+// RegisterSensorType เพิ่มหรือแทนที่ชนิดเซนเซอร์ใน registry
+func RegisterSensorType(sensorType SensorType) error {
+	if err := validateSensorType(sensorType); err != nil {
+		return err
+	}
+
+	sensorTypesMu.Lock()
+	defer sensorTypesMu.Unlock()
+
+	sensorTypes[sensorType.Name] = sensorType
+	return nil
+}
+
+// validateSensorType ตรวจสอบ spec ของชนิดเซนเซอร์และชื่อ metric ที่ซ้ำกัน
+func validateSensorType(sensorType SensorType) error {
+	if err := validate.Struct(sensorType); err != nil {
+		return apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("sensor type %q: %s", sensorType.Name, validationMessage(err)))
+	}
+
+	seen := make(map[string]bool, len(sensorType.Metrics))
+	for _, spec := range sensorType.Metrics {
+		if seen[spec.Name] {
+			return apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("sensor type %q: duplicate metric %s", sensorType.Name, spec.Name))
+		}
+		seen[spec.Name] = true
+	}
+	return nil
+}
+
+// LookupSensorType คืนค่าชนิดเซนเซอร์ตามชื่อ
+func LookupSensorType(name string) (SensorType, bool) {
+	sensorTypesMu.RLock()
+	defer sensorTypesMu.RUnlock()
+
+	sensorType, ok := sensorTypes[name]
+	return sensorType, ok
+}
+
+// SensorTypes คืนค่าชนิดเซนเซอร์ทั้งหมดเรียงตามชื่อ
+func SensorTypes() []SensorType {
+	sensorTypesMu.RLock()
+	defer sensorTypesMu.RUnlock()
+
+	types := make([]SensorType, 0, len(sensorTypes))
+	for _, sensorType := range sensorTypes {
+		types = append(types, sensorType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// LoadSensorTypes อ่านชนิดเซนเซอร์จากไฟล์ JSON หรือ YAML (เลือกตามนามสกุลไฟล์) แล้วลงทะเบียนทั้งหมด
+// ถ้ามีชนิดใดไม่ถูกต้องจะไม่ลงทะเบียนชนิดใดเลย
+func LoadSensorTypes(path string) ([]SensorType, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrConfigNotFound, fmt.Sprintf("sensor types %s: %v", path, err))
+	}
+
+	var types []SensorType
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &types)
+	default:
+		err = json.Unmarshal(data, &types)
+	}
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("sensor types %s: %v", path, err))
+	}
+
+	for _, sensorType := range types {
+		if err := validateSensorType(sensorType); err != nil {
+			return nil, err
+		}
+	}
+
+	sensorTypesMu.Lock()
+	defer sensorTypesMu.Unlock()
+
+	for _, sensorType := range types {
+		sensorTypes[sensorType.Name] = sensorType
+	}
+	return types, nil
+}
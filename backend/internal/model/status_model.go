@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// StatusChangeModel คือการเปลี่ยน Status ของเซนเซอร์หนึ่งครั้ง
+type StatusChangeModel struct {
+	SensorID       string    `json:"sensor_id"`
+	SensorType     string    `json:"sensor_type"`
+	Tags           []string  `json:"tags,omitempty"`
+	Tenant         string    `json:"tenant,omitempty"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	LastSeen       time.Time `json:"last_seen"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
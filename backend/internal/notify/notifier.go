@@ -0,0 +1,371 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+const (
+	// ชื่อ event ที่ส่งไปยัง webhook
+	EventAlertFiring   = "alert.firing"
+	EventAlertResolved = "alert.resolved"
+	EventSensorStatus  = "sensor.status"
+
+	// SignatureHeader คือ header ที่เก็บ HMAC-SHA256 ของ body ในรูปแบบ "sha256=<hex>"
+	SignatureHeader = "X-Webhook-Signature-256"
+
+	// EventHeader คือ header ที่เก็บชื่อ event
+	EventHeader = "X-Webhook-Event"
+
+	// DeliveryHeader คือ header ที่เก็บ ID ของ notification (ใช้ตรวจสอบการส่งซ้ำฝั่งผู้รับ)
+	DeliveryHeader = "X-Webhook-Delivery"
+
+	// DefaultMaxAttempts คือจำนวนครั้งสูงสุดในการส่งก่อนย้ายไป dead-letter queue
+	DefaultMaxAttempts = 5
+
+	// DefaultInitialBackoff คือเวลารอก่อนส่งซ้ำครั้งแรก (เพิ่มเป็นสองเท่าทุกครั้ง)
+	DefaultInitialBackoff = time.Second
+
+	// DefaultMaxBackoff คือเวลารอสูงสุดระหว่างการส่งซ้ำ
+	DefaultMaxBackoff = time.Minute
+
+	// DefaultTimeout คือ timeout ของการส่งแต่ละครั้ง
+	DefaultTimeout = 5 * time.Second
+
+	// maxDeliveryLog คือจำนวนบันทึกการส่งล่าสุดที่เก็บไว้
+	maxDeliveryLog = 500
+
+	// maxDeadLetters คือจำนวน notification ที่ส่งไม่สำเร็จที่เก็บไว้สูงสุด
+	maxDeadLetters = 1000
+)
+
+// Config คือการตั้งค่าของ Notifier
+type Config struct {
+	URLs           []string
+	Secret         string
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+}
+
+// Notification คือ payload ที่ส่งไปยัง webhook
+type Notification struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	Tenant    string    `json:"tenant,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Delivery คือบันทึกการส่งหนึ่งครั้งไปยัง URL หนึ่ง
+type Delivery struct {
+	NotificationID string        `json:"notification_id"`
+	Event          string        `json:"event"`
+	Tenant         string        `json:"tenant,omitempty"`
+	URL            string        `json:"url"`
+	Attempt        int           `json:"attempt"`
+	StatusCode     int           `json:"status_code,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Success        bool          `json:"success"`
+	Duration       time.Duration `json:"duration_ns"`
+	At             time.Time     `json:"at"`
+}
+
+// DeadLetter คือ notification ที่ส่งไปยัง URL หนึ่งไม่สำเร็จจนครบจำนวนครั้ง
+type DeadLetter struct {
+	ID           string       `json:"id"`
+	Notification Notification `json:"notification"`
+	URL          string       `json:"url"`
+	Attempts     int          `json:"attempts"`
+	LastError    string       `json:"last_error"`
+	FailedAt     time.Time    `json:"failed_at"`
+}
+
+// INotifier คือ interface สำหรับส่ง notification และตรวจสอบผลการส่ง
+// บันทึกการส่งและ dead letter แยกตาม tenant ของ event ผู้ดูแลเห็นเฉพาะของ tenant ตัวเอง
+type INotifier interface {
+	// Notify ส่ง event ของ tenant ไปยังทุก webhook แบบ asynchronous
+	Notify(event string, tenant string, data any)
+
+	// Deliveries คืนค่าบันทึกการส่งล่าสุดของ tenant (ใหม่สุดก่อน)
+	Deliveries(tenant string) []Delivery
+
+	// DeadLetters คืนค่า notification ของ tenant ที่ส่งไม่สำเร็จ
+	DeadLetters(tenant string) []DeadLetter
+
+	// Redeliver นำ dead letter ของ tenant กลับมาส่งใหม่
+	Redeliver(tenant string, id string) error
+}
+
+// Notifier ส่ง notification ที่ลงลายเซ็น HMAC ไปยัง webhook พร้อมส่งซ้ำแบบ exponential backoff
+type Notifier struct {
+	cfg    Config
+	client *http.Client
+	logger *zap.Logger
+
+	mu          sync.Mutex
+	deliveries  []Delivery
+	deadLetters []DeadLetter
+
+	wg   sync.WaitGroup
+	done chan struct{}
+	once sync.Once
+}
+
+// NewNotifier สร้าง Notifier ใหม่ ค่าที่ไม่ได้กำหนดจะใช้ค่าเริ่มต้น
+func NewNotifier(cfg Config, logger *zap.Logger) *Notifier {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	return &Notifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+}
+
+// Notify ส่ง event ของ tenant ไปยังทุก webhook แบบ asynchronous
+func (n *Notifier) Notify(event string, tenant string, data any) {
+	if len(n.cfg.URLs) == 0 {
+		return
+	}
+
+	notification := Notification{
+		ID:        newID(),
+		Event:     event,
+		Tenant:    tenant,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		n.logger.Error("Failed to serialize notification", zap.String("event", event), zap.Error(err))
+		return
+	}
+
+	for _, url := range n.cfg.URLs {
+		n.start(notification, url, body)
+	}
+}
+
+// Deliveries คืนค่าบันทึกการส่งล่าสุดของ tenant (ใหม่สุดก่อน)
+func (n *Notifier) Deliveries(tenant string) []Delivery {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	deliveries := make([]Delivery, 0)
+	for i := len(n.deliveries) - 1; i >= 0; i-- {
+		if n.deliveries[i].Tenant == tenant {
+			deliveries = append(deliveries, n.deliveries[i])
+		}
+	}
+	return deliveries
+}
+
+// DeadLetters คืนค่า notification ของ tenant ที่ส่งไม่สำเร็จ
+func (n *Notifier) DeadLetters(tenant string) []DeadLetter {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	letters := make([]DeadLetter, 0)
+	for _, letter := range n.deadLetters {
+		if letter.Notification.Tenant == tenant {
+			letters = append(letters, letter)
+		}
+	}
+	return letters
+}
+
+// Redeliver นำ dead letter ของ tenant กลับมาส่งใหม่ตั้งแต่ครั้งแรก
+// dead letter ของ tenant อื่นมีผลเหมือนไม่มีอยู่ (ErrDataNotFound)
+func (n *Notifier) Redeliver(tenant string, id string) error {
+	n.mu.Lock()
+	var letter *DeadLetter
+	for i := range n.deadLetters {
+		if n.deadLetters[i].ID == id && n.deadLetters[i].Notification.Tenant == tenant {
+			found := n.deadLetters[i]
+			letter = &found
+			n.deadLetters = append(n.deadLetters[:i], n.deadLetters[i+1:]...)
+			break
+		}
+	}
+	n.mu.Unlock()
+
+	if letter == nil {
+		return apierror.Wrap(apierror.ErrDataNotFound, fmt.Sprintf("dead letter %s not found", id))
+	}
+
+	body, err := json.Marshal(letter.Notification)
+	if err != nil {
+		return err
+	}
+
+	n.start(letter.Notification, letter.URL, body)
+	return nil
+}
+
+// Close หยุดการส่งซ้ำที่ค้างอยู่และรอให้การส่งที่กำลังทำงานจบ
+func (n *Notifier) Close() {
+	n.once.Do(func() {
+		close(n.done)
+	})
+	n.wg.Wait()
+}
+
+// start เริ่ม goroutine สำหรับส่ง notification ไปยัง URL หนึ่ง
+func (n *Notifier) start(notification Notification, url string, body []byte) {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.deliver(notification, url, body)
+	}()
+}
+
+// deliver ส่ง notification จนสำเร็จหรือครบจำนวนครั้ง แล้วย้ายไป dead-letter queue
+func (n *Notifier) deliver(notification Notification, url string, body []byte) {
+	backoff := n.cfg.InitialBackoff
+
+	var lastErr string
+	for attempt := 1; attempt <= n.cfg.MaxAttempts; attempt++ {
+		delivery := n.send(notification, url, body, attempt)
+		n.record(delivery)
+		if delivery.Success {
+			return
+		}
+		lastErr = delivery.Error
+
+		if attempt == n.cfg.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-n.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > n.cfg.MaxBackoff {
+			backoff = n.cfg.MaxBackoff
+		}
+	}
+
+	n.logger.Warn("Webhook delivery failed, moved to dead-letter queue",
+		zap.String("notification", notification.ID),
+		zap.String("url", url),
+		zap.String("error", lastErr))
+
+	n.mu.Lock()
+	n.deadLetters = append(n.deadLetters, DeadLetter{
+		ID:           newID(),
+		Notification: notification,
+		URL:          url,
+		Attempts:     n.cfg.MaxAttempts,
+		LastError:    lastErr,
+		FailedAt:     time.Now().UTC(),
+	})
+	if len(n.deadLetters) > maxDeadLetters {
+		n.deadLetters = n.deadLetters[len(n.deadLetters)-maxDeadLetters:]
+	}
+	n.mu.Unlock()
+}
+
+// send ส่ง request หนึ่งครั้ง สถานะ 2xx ถือว่าสำเร็จ
+func (n *Notifier) send(notification Notification, url string, body []byte, attempt int) Delivery {
+	delivery := Delivery{
+		NotificationID: notification.ID,
+		Event:          notification.Event,
+		Tenant:         notification.Tenant,
+		URL:            url,
+		Attempt:        attempt,
+		At:             time.Now().UTC(),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, notification.Event)
+	req.Header.Set(DeliveryHeader, notification.ID)
+	if n.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.cfg.Secret, body))
+	}
+
+	start := time.Now()
+	resp, err := n.client.Do(req)
+	delivery.Duration = time.Since(start)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	delivery.StatusCode = resp.StatusCode
+	delivery.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !delivery.Success {
+		delivery.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+
+	return delivery
+}
+
+// record เก็บบันทึกการส่งโดยจำกัดจำนวนไว้ที่ maxDeliveryLog รายการล่าสุด
+func (n *Notifier) record(delivery Delivery) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.deliveries = append(n.deliveries, delivery)
+	if len(n.deliveries) > maxDeliveryLog {
+		n.deliveries = n.deliveries[len(n.deliveries)-maxDeliveryLog:]
+	}
+}
+
+// Sign คืนค่าลายเซ็น HMAC-SHA256 ของ body ในรูปแบบ "sha256=<hex>"
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify ตรวจสอบลายเซ็นของ body สำหรับฝั่งผู้รับ webhook
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// newID สร้าง ID แบบสุ่มสำหรับ notification และ dead letter
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,155 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/notify"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// receiver คือ webhook ปลายทางจำลองที่ตอบด้วยสถานะตามลำดับที่กำหนด
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+	calls    atomic.Int32
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	call := int(r.calls.Add(1)) - 1
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+	status := http.StatusOK
+	if call < len(r.statuses) {
+		status = r.statuses[call]
+	}
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func newNotifier(t *testing.T, url string, maxAttempts int) *notify.Notifier {
+	n := notify.NewNotifier(notify.Config{
+		URLs:           []string{url},
+		Secret:         "s3cret",
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: 5 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	}, zaptest.NewLogger(t))
+	t.Cleanup(n.Close)
+	return n
+}
+
+func TestNotifierSignsPayload(t *testing.T) {
+	rec := &receiver{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	n := newNotifier(t, server.URL, 3)
+	n.Notify(notify.EventAlertFiring, "acme", map[string]string{"rule_id": "hot"})
+
+	require.Eventually(t, func() bool { return len(n.Deliveries("acme")) == 1 }, time.Second, 5*time.Millisecond)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	// ผู้รับต้องตรวจสอบลายเซ็นจาก body ได้
+	assert.True(t, notify.Verify("s3cret", rec.bodies[0], rec.headers[0].Get(notify.SignatureHeader)))
+	assert.False(t, notify.Verify("wrong", rec.bodies[0], rec.headers[0].Get(notify.SignatureHeader)))
+	assert.Equal(t, notify.EventAlertFiring, rec.headers[0].Get(notify.EventHeader))
+
+	var notification notify.Notification
+	require.NoError(t, json.Unmarshal(rec.bodies[0], &notification))
+	assert.Equal(t, notify.EventAlertFiring, notification.Event)
+	assert.Equal(t, notification.ID, rec.headers[0].Get(notify.DeliveryHeader))
+
+	delivery := n.Deliveries("acme")[0]
+	assert.True(t, delivery.Success)
+	assert.Equal(t, http.StatusOK, delivery.StatusCode)
+}
+
+func TestNotifierRetriesWithBackoff(t *testing.T) {
+	rec := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	n := newNotifier(t, server.URL, 5)
+	n.Notify(notify.EventSensorStatus, "acme", map[string]string{"status": "offline"})
+
+	require.Eventually(t, func() bool { return rec.calls.Load() == 3 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return len(n.Deliveries("acme")) == 3 }, time.Second, 5*time.Millisecond)
+
+	// บันทึกการส่งเรียงใหม่สุดก่อน
+	deliveries := n.Deliveries("acme")
+	assert.True(t, deliveries[0].Success)
+	assert.Equal(t, 3, deliveries[0].Attempt)
+	assert.False(t, deliveries[2].Success)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[2].StatusCode)
+
+	// ทุกครั้งต้องใช้ notification ID เดิมเพื่อให้ผู้รับตัดการส่งซ้ำได้
+	rec.mu.Lock()
+	assert.Equal(t, rec.headers[0].Get(notify.DeliveryHeader), rec.headers[2].Get(notify.DeliveryHeader))
+	rec.mu.Unlock()
+
+	assert.Empty(t, n.DeadLetters("acme"))
+}
+
+func TestNotifierDeadLetterAndRedeliver(t *testing.T) {
+	rec := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	n := newNotifier(t, server.URL, 2)
+	n.Notify(notify.EventAlertResolved, "acme", map[string]string{"rule_id": "hot"})
+
+	require.Eventually(t, func() bool { return len(n.DeadLetters("acme")) == 1 }, time.Second, 5*time.Millisecond)
+
+	letter := n.DeadLetters("acme")[0]
+	assert.Equal(t, 2, letter.Attempts)
+	assert.Equal(t, server.URL, letter.URL)
+	assert.Contains(t, letter.LastError, "503")
+
+	// ID ที่ไม่มีอยู่ต้องได้ error
+	assert.Error(t, n.Redeliver("acme", "missing"))
+
+	// ผู้รับกลับมาทำงานแล้ว ส่งใหม่ต้องสำเร็จ
+	require.NoError(t, n.Redeliver("acme", letter.ID))
+	require.Eventually(t, func() bool { return rec.calls.Load() == 3 }, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool { return n.Deliveries("acme")[0].Success }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, n.DeadLetters("acme"))
+}
+
+func TestNotifierTenantIsolation(t *testing.T) {
+	rec := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	n := newNotifier(t, server.URL, 1)
+	n.Notify(notify.EventAlertFiring, "acme", map[string]string{"rule_id": "hot"})
+
+	require.Eventually(t, func() bool { return len(n.DeadLetters("acme")) == 1 }, time.Second, 5*time.Millisecond)
+	letter := n.DeadLetters("acme")[0]
+	assert.Equal(t, "acme", letter.Notification.Tenant)
+	assert.Equal(t, "acme", n.Deliveries("acme")[0].Tenant)
+
+	// tenant อื่นต้องไม่เห็นและส่งใหม่ไม่ได้
+	assert.Empty(t, n.Deliveries("globex"))
+	assert.Empty(t, n.DeadLetters("globex"))
+	assert.ErrorIs(t, n.Redeliver("globex", letter.ID), apierror.ErrDataNotFound)
+	assert.Len(t, n.DeadLetters("acme"), 1)
+}
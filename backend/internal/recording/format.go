@@ -0,0 +1,137 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// FormatVersion คือเวอร์ชันของรูปแบบไฟล์ (ดู docs/recording.md)
+const FormatVersion = 1
+
+// ชนิดของบรรทัดในไฟล์ recording
+const (
+	KindHeader  = "header"
+	KindReading = "reading"
+	KindEvent   = "event"
+)
+
+// Mode คือสิ่งที่ถูกบันทึก: reading ที่เขียนเข้า repository หรือ event ที่ส่งออกทาง SSE
+type Mode string
+
+const (
+	ModeReadings Mode = "readings"
+	ModeEvents   Mode = "events"
+)
+
+// Entry คือหนึ่งบรรทัดของไฟล์ recording (JSON หนึ่ง object ต่อบรรทัด)
+// บรรทัดแรกเป็น header เสมอ บรรทัดถัดไปเป็น reading หรือ event ตาม Mode ของไฟล์
+type Entry struct {
+	Time time.Time `json:"t"`
+	Kind string    `json:"kind"`
+
+	// header
+	Version int  `json:"version,omitempty"`
+	Mode    Mode `json:"mode,omitempty"`
+
+	// reading
+	SensorID   string              `json:"sensor_id,omitempty"`
+	SensorType string              `json:"sensor_type,omitempty"`
+	Reading    *model.ReadingModel `json:"reading,omitempty"`
+
+	// event
+	EventID string          `json:"event_id,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Writer เขียน entry ลงไฟล์ทีละบรรทัด แต่ละบรรทัดถูกเขียนลงไฟล์ทันทีเพื่อไม่ให้ข้อมูลหายถ้า process หยุดกะทันหัน
+type Writer struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// Create สร้างไฟล์ recording ใหม่ (เขียนทับไฟล์เดิม) และเขียน header
+func Create(path string, mode Mode) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create recording %s: %w", path, err)
+	}
+
+	w := &Writer{file: file}
+	if err := w.Write(Entry{Time: time.Now().UTC(), Kind: KindHeader, Version: FormatVersion, Mode: mode}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write เขียน entry หนึ่งบรรทัด
+func (w *Writer) Write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err = w.file.Write(line)
+	return err
+}
+
+// Close ปิดไฟล์
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+// ReadFile อ่านทุก entry ต่อจาก header ของไฟล์ recording
+// บรรทัดสุดท้ายที่เขียนไม่ครบ (process หยุดระหว่างเขียน) จะถูกข้ามไป
+func ReadFile(path string) (Mode, []Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, apierror.Wrap(apierror.ErrConfigNotFound, fmt.Sprintf("recording %s: %v", path, err))
+	}
+
+	var (
+		header  Entry
+		entries []Entry
+	)
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// ส่วนท้ายที่ไม่มีขึ้นบรรทัดใหม่คือบรรทัดที่เขียนไม่ครบ
+			if i == len(lines)-1 {
+				break
+			}
+			return "", nil, apierror.Wrap(apierror.ErrDataInvalid, fmt.Sprintf("recording %s: line %d: %v", path, i+1, err))
+		}
+
+		if header.Kind == "" {
+			header = entry
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if header.Kind != KindHeader || header.Version != FormatVersion {
+		return "", nil, apierror.Wrap(apierror.ErrDataInvalid, fmt.Sprintf("recording %s: missing or unsupported header", path))
+	}
+	return header.Mode, entries, nil
+}
@@ -0,0 +1,135 @@
+package recording
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
+)
+
+// Recorder บันทึกข้อมูลลงไฟล์ recording ตาม Mode
+//   - ModeReadings: ลงทะเบียน RecordChanges เป็น listener ของ repository
+//   - ModeEvents: เรียก RecordEvents เพื่อ subscribe กับ broker
+type Recorder struct {
+	writer *Writer
+	mode   Mode
+	logger *zap.Logger
+
+	last   map[string]time.Time
+	lastMu sync.Mutex
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewRecorder สร้างไฟล์ recording ใหม่ที่ path
+func NewRecorder(path string, mode Mode, logger *zap.Logger) (*Recorder, error) {
+	writer, err := Create(path, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		writer: writer,
+		mode:   mode,
+		logger: logger,
+		last:   make(map[string]time.Time),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Mode คืนค่าสิ่งที่ recorder บันทึก
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// RecordChanges บันทึกค่าล่าสุดของเซนเซอร์ที่เปลี่ยนแปลงเป็น reading (ใช้เป็น repository.ChangeListener)
+// บันทึกเฉพาะเมื่อ Timestamp ของเซนเซอร์ใหม่กว่าครั้งก่อน เพื่อไม่ให้การเปลี่ยนสถานะหรือ metadata กลายเป็น reading ซ้ำ
+func (r *Recorder) RecordChanges(changed []*model.SensorModel) {
+	now := time.Now().UTC()
+	for _, sensor := range changed {
+		if len(sensor.Metrics) == 0 || !r.advance(sensor.ID, sensor.Timestamp) {
+			continue
+		}
+
+		err := r.writer.Write(Entry{
+			Time:       now,
+			Kind:       KindReading,
+			SensorID:   sensor.ID,
+			SensorType: sensor.Type,
+			Reading:    &model.ReadingModel{Metrics: sensor.Values(), Timestamp: sensor.Timestamp},
+		})
+		if err != nil {
+			r.logger.Error("Failed to record reading", zap.String("id", sensor.ID), zap.Error(err))
+		}
+	}
+}
+
+// RecordEvents บันทึกทุก event ที่ broker กระจาย (payload ที่ไม่ได้กรอง) จนกว่าจะ Close
+// ถ้า broker ถอด recorder ออกเพราะอ่านไม่ทัน จะ subscribe ใหม่และบันทึก gap ไว้ใน log
+func (r *Recorder) RecordEvents(broker *stream.Broker) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		for {
+			sub, _ := broker.Subscribe("")
+			if !r.drain(sub) {
+				broker.Unsubscribe(sub)
+				return
+			}
+			r.logger.Warn("Recorder fell behind the stream, resubscribing")
+		}
+	}()
+}
+
+// drain เขียน event จาก subscriber ลงไฟล์ คืนค่า false เมื่อ recorder ถูกปิด
+func (r *Recorder) drain(sub *stream.Subscriber) bool {
+	for {
+		select {
+		case <-r.done:
+			return false
+		case evt, ok := <-sub.Events():
+			if !ok {
+				return true
+			}
+			err := r.writer.Write(Entry{
+				Time:    time.Now().UTC(),
+				Kind:    KindEvent,
+				EventID: evt.ID,
+				Event:   evt.Type,
+				Data:    evt.Data,
+			})
+			if err != nil {
+				r.logger.Error("Failed to record event", zap.String("event", evt.Type), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Close หยุดบันทึกและปิดไฟล์
+func (r *Recorder) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+		err = r.writer.Close()
+	})
+	return err
+}
+
+// advance จำ Timestamp ล่าสุดของเซนเซอร์ คืนค่า true ถ้าใหม่กว่าครั้งก่อน
+func (r *Recorder) advance(id string, ts time.Time) bool {
+	r.lastMu.Lock()
+	defer r.lastMu.Unlock()
+
+	if last, ok := r.last[id]; ok && !ts.After(last) {
+		return false
+	}
+	r.last[id] = ts
+	return true
+}
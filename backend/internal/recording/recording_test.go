@@ -0,0 +1,177 @@
+package recording_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/recording"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/repository"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+func sensor(id string, sensorType string, metric string, value float64, at time.Time) *model.SensorModel {
+	s := &model.SensorModel{ID: id, Name: id, Type: sensorType, Timestamp: at}
+	s.SetValue(metric, value)
+	return s
+}
+
+// TestRecordChanges ทดสอบการบันทึก reading และอ่านกลับจากไฟล์
+func TestRecordChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.jsonl")
+	recorder, err := recording.NewRecorder(path, recording.ModeReadings, zaptest.NewLogger(t))
+	require.NoError(t, err)
+
+	now := time.Now()
+	recorder.RecordChanges([]*model.SensorModel{sensor("temp-001", "temperature", model.MetricTemperature, 24.5, now)})
+	// Timestamp เดิม (เช่นเปลี่ยนสถานะ) ต้องไม่ถูกบันทึกซ้ำ
+	recorder.RecordChanges([]*model.SensorModel{sensor("temp-001", "temperature", model.MetricTemperature, 24.5, now)})
+	recorder.RecordChanges([]*model.SensorModel{sensor("temp-001", "temperature", model.MetricTemperature, 25, now.Add(time.Second))})
+	require.NoError(t, recorder.Close())
+
+	mode, entries, err := recording.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, recording.ModeReadings, mode)
+	require.Len(t, entries, 2)
+	assert.Equal(t, recording.KindReading, entries[0].Kind)
+	assert.Equal(t, "temp-001", entries[0].SensorID)
+	assert.Equal(t, "temperature", entries[0].SensorType)
+	assert.Equal(t, 25.0, entries[1].Reading.Metrics[model.MetricTemperature])
+}
+
+// TestReadFile ทดสอบการอ่านไฟล์ที่เสียหายหรือเขียนไม่ครบ
+func TestReadFile(t *testing.T) {
+	header := `{"t":"2026-01-01T00:00:00Z","kind":"header","version":1,"mode":"readings"}` + "\n"
+	reading := `{"t":"2026-01-01T00:00:01Z","kind":"reading","sensor_id":"temp-001","reading":{"metrics":{"temperature":20}}}` + "\n"
+
+	testCases := []struct {
+		name    string
+		content string
+		entries int
+		wantErr error
+	}{
+		{
+			name:    "complete file",
+			content: header + reading + reading,
+			entries: 2,
+		},
+		{
+			name:    "truncated last line is skipped",
+			content: header + reading + `{"t":"2026-01-01T00:00:02Z","kind":"rea`,
+			entries: 1,
+		},
+		{
+			name:    "corrupt line in the middle",
+			content: header + "not json\n" + reading,
+			wantErr: apierror.ErrDataInvalid,
+		},
+		{
+			name:    "missing header",
+			content: reading,
+			wantErr: apierror.ErrDataInvalid,
+		},
+		{
+			name:    "unsupported version",
+			content: `{"t":"2026-01-01T00:00:00Z","kind":"header","version":99,"mode":"readings"}` + "\n",
+			wantErr: apierror.ErrDataInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "recording.jsonl")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o644))
+
+			_, entries, err := recording.ReadFile(path)
+			if tc.wantErr != nil {
+				assert.True(t, errors.Is(err, tc.wantErr), "expected %v, got %v", tc.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, entries, tc.entries)
+		})
+	}
+
+	_, _, err := recording.ReadFile(filepath.Join(t.TempDir(), "missing.jsonl"))
+	assert.True(t, errors.Is(err, apierror.ErrConfigNotFound))
+}
+
+// TestReplayerStep ทดสอบการเล่นทีละ frame และการลงทะเบียนเซนเซอร์ที่ไม่มีใน repository
+func TestReplayerStep(t *testing.T) {
+	repo := repository.NewSensorRepository()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []recording.Entry{
+		{Time: start, Kind: recording.KindReading, SensorID: "temp-001", SensorType: "temperature",
+			Reading: &model.ReadingModel{Metrics: map[string]float64{model.MetricTemperature: 30}}},
+		{Time: start, Kind: recording.KindReading, SensorID: "lab-co2", SensorType: "co2",
+			Reading: &model.ReadingModel{Metrics: map[string]float64{model.MetricCO2: 800}}},
+		{Time: start.Add(time.Minute), Kind: recording.KindReading, SensorID: "temp-001", SensorType: "temperature",
+			Reading: &model.ReadingModel{Metrics: map[string]float64{model.MetricTemperature: 31}}},
+	}
+
+	replayer := recording.NewReplayer(entries, repo, recording.ReplayOptions{Step: true}, zaptest.NewLogger(t))
+	replayer.Start()
+	defer replayer.Stop()
+
+	assert.Equal(t, 2, replayer.Status().Frames)
+
+	status, err := replayer.Step()
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Position)
+
+	temp, err := repo.GetSensorByID("temp-001")
+	require.NoError(t, err)
+	value, _ := temp.Value(model.MetricTemperature)
+	assert.Equal(t, 30.0, value)
+	assert.WithinDuration(t, time.Now(), temp.Timestamp, time.Minute)
+
+	co2, err := repo.GetSensorByID("lab-co2")
+	require.NoError(t, err, "unknown sensor should be registered from the recording")
+	assert.Equal(t, "co2", co2.Type)
+
+	_, err = replayer.Step()
+	require.NoError(t, err)
+	status, err = replayer.Step()
+	require.NoError(t, err)
+	assert.True(t, status.Finished)
+
+	temp, err = repo.GetSensorByID("temp-001")
+	require.NoError(t, err)
+	value, _ = temp.Value(model.MetricTemperature)
+	assert.Equal(t, 31.0, value)
+}
+
+// TestReplayerEvents ทดสอบการเล่นไฟล์ mode events ที่ใช้เฉพาะ event ที่มีค่าของเซนเซอร์
+func TestReplayerEvents(t *testing.T) {
+	repo := repository.NewSensorRepository()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []recording.Entry{
+		{Time: start, Kind: recording.KindEvent, EventID: "1", Event: "sensor.updated",
+			Data: []byte(`{"data":[{"id":"humid-001","type":"humidity","metrics":{"humidity":{"value":55,"unit":"%"}}}]}`)},
+		{Time: start.Add(time.Second), Kind: recording.KindEvent, EventID: "2", Event: "alert.fired",
+			Data: []byte(`{"rule":"x"}`)},
+	}
+
+	replayer := recording.NewReplayer(entries, repo, recording.ReplayOptions{Step: true}, zaptest.NewLogger(t))
+	assert.Equal(t, 1, replayer.Status().Frames)
+
+	_, err := replayer.Step()
+	require.NoError(t, err)
+
+	humid, err := repo.GetSensorByID("humid-001")
+	require.NoError(t, err)
+	value, _ := humid.Value(model.MetricHumidity)
+	assert.Equal(t, 55.0, value)
+
+	// ไม่ได้อยู่ในโหมด step
+	timed := recording.NewReplayer(entries, repo, recording.ReplayOptions{}, zaptest.NewLogger(t))
+	_, err = timed.Step()
+	assert.True(t, errors.Is(err, apierror.ErrInvalidRequest))
+}
@@ -0,0 +1,295 @@
+package recording
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// Target คือปลายทางที่ replayer เขียนข้อมูลกลับเข้าไป (repository)
+type Target interface {
+	SaveReadings(id string, readings []model.ReadingModel) (*model.SensorModel, error)
+	CreateSensor(sensor *model.SensorModel) (*model.SensorModel, error)
+}
+
+// IReplayer คือ interface สำหรับควบคุมการเล่นจาก API
+type IReplayer interface {
+	// Status คืนค่าสถานะปัจจุบัน
+	Status() ReplayStatus
+
+	// Step เล่น frame ถัดไปหนึ่ง frame (เฉพาะโหมด Step)
+	Step() (ReplayStatus, error)
+}
+
+// ReplayOptions กำหนดวิธีเล่นไฟล์ recording
+type ReplayOptions struct {
+	// Speed คือตัวคูณความเร็วเทียบกับเวลาจริงที่บันทึก (1 คือความเร็วเดิม, 10 คือเร็วขึ้น 10 เท่า)
+	Speed float64
+
+	// Step เล่นทีละ frame เมื่อเรียก Step แทนการเล่นตามเวลา
+	Step bool
+
+	// Loop เริ่มเล่นใหม่ตั้งแต่ต้นเมื่อจบไฟล์
+	Loop bool
+}
+
+// ReplayStatus คือสถานะปัจจุบันของ replayer
+type ReplayStatus struct {
+	Frames   int     `json:"frames"`
+	Position int     `json:"position"`
+	Speed    float64 `json:"speed"`
+	Step     bool    `json:"step"`
+	Loop     bool    `json:"loop"`
+	Finished bool    `json:"finished"`
+}
+
+// sample คือ reading หนึ่งค่าที่จะเขียนกลับเข้า repository
+type sample struct {
+	sensorID   string
+	sensorType string
+	name       string
+	values     map[string]float64
+}
+
+// frame คือกลุ่ม sample ที่ถูกบันทึกในเวลาเดียวกัน (เช่นการอัปเดตเซนเซอร์ทุกตัวในรอบเดียว)
+type frame struct {
+	at      time.Time
+	samples []sample
+}
+
+// Replayer เล่นไฟล์ recording ผ่าน repository ทำให้ listener ทุกตัว (SSE, alert, watchdog) ทำงานเหมือนตอนบันทึก
+// reading ทุกค่าถูกเขียนด้วยเวลาปัจจุบัน เพื่อให้ dashboard แสดงเป็นข้อมูลสด
+type Replayer struct {
+	frames  []frame
+	target  Target
+	opts    ReplayOptions
+	logger  *zap.Logger
+	created map[string]bool
+
+	pos      int
+	finished bool
+	mu       sync.Mutex
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewReplayer สร้าง replayer จาก entry ของไฟล์ recording
+// event ที่ใช้ได้คือ snapshot, reset และ sensor.updated ส่วน event อื่นจะถูกสร้างใหม่จากข้อมูลที่เล่น
+func NewReplayer(entries []Entry, target Target, opts ReplayOptions, logger *zap.Logger) *Replayer {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+
+	return &Replayer{
+		frames:  buildFrames(entries, logger),
+		target:  target,
+		opts:    opts,
+		logger:  logger,
+		created: make(map[string]bool),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start เริ่มเล่นตามเวลาที่บันทึก สำหรับโหมด Step จะไม่ทำอะไรจนกว่าจะเรียก Step
+func (p *Replayer) Start() {
+	p.logger.Info("Replay started",
+		zap.Int("frames", len(p.frames)),
+		zap.Float64("speed", p.opts.Speed),
+		zap.Bool("step", p.opts.Step),
+		zap.Bool("loop", p.opts.Loop))
+
+	if p.opts.Step {
+		return
+	}
+	go p.loop()
+}
+
+// Stop หยุดเล่น
+func (p *Replayer) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+// Step เล่น frame ถัดไปหนึ่ง frame ใช้ได้เฉพาะโหมด Step
+func (p *Replayer) Step() (ReplayStatus, error) {
+	if !p.opts.Step {
+		return p.Status(), apierror.Wrap(apierror.ErrInvalidRequest, "replay is not in step mode")
+	}
+
+	p.mu.Lock()
+	if next, ok := p.nextLocked(); ok {
+		p.apply(next)
+	}
+	p.mu.Unlock()
+
+	return p.Status(), nil
+}
+
+// Status คืนค่าสถานะปัจจุบัน
+func (p *Replayer) Status() ReplayStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return ReplayStatus{
+		Frames:   len(p.frames),
+		Position: p.pos,
+		Speed:    p.opts.Speed,
+		Step:     p.opts.Step,
+		Loop:     p.opts.Loop,
+		Finished: p.finished,
+	}
+}
+
+// loop เล่นทุก frame โดยรอตามระยะห่างของเวลาที่บันทึกหารด้วย Speed
+func (p *Replayer) loop() {
+	var previous time.Time
+	for {
+		p.mu.Lock()
+		next, ok := p.nextLocked()
+		p.mu.Unlock()
+		if !ok {
+			p.logger.Info("Replay finished", zap.Int("frames", len(p.frames)))
+			return
+		}
+
+		if !previous.IsZero() && next.at.After(previous) {
+			wait := time.Duration(float64(next.at.Sub(previous)) / p.opts.Speed)
+			select {
+			case <-p.done:
+				return
+			case <-time.After(wait):
+			}
+		}
+		previous = next.at
+
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		p.mu.Lock()
+		p.apply(next)
+		p.mu.Unlock()
+	}
+}
+
+// nextLocked คืนค่า frame ถัดไปและเลื่อนตำแหน่ง (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (p *Replayer) nextLocked() (frame, bool) {
+	if p.pos >= len(p.frames) {
+		if !p.opts.Loop || len(p.frames) == 0 {
+			p.finished = true
+			return frame{}, false
+		}
+		p.pos = 0
+	}
+
+	next := p.frames[p.pos]
+	p.pos++
+	return next, true
+}
+
+// apply เขียนทุก sample ของ frame เข้า repository โดยลงทะเบียนเซนเซอร์ที่ยังไม่มีก่อน
+func (p *Replayer) apply(f frame) {
+	now := time.Now()
+	for _, s := range f.samples {
+		reading := model.ReadingModel{Metrics: s.values, Timestamp: now}
+
+		_, err := p.target.SaveReadings(s.sensorID, []model.ReadingModel{reading})
+		if errors.Is(err, apierror.ErrDataNotFound) && p.register(s) {
+			_, err = p.target.SaveReadings(s.sensorID, []model.ReadingModel{reading})
+		}
+		if err != nil {
+			p.logger.Warn("Failed to replay reading", zap.String("id", s.sensorID), zap.Error(err))
+		}
+	}
+}
+
+// register ลงทะเบียนเซนเซอร์ที่มีในไฟล์แต่ไม่มีใน repository คืนค่า false ถ้าลงทะเบียนไม่ได้
+func (p *Replayer) register(s sample) bool {
+	if p.created[s.sensorID] || s.sensorType == "" {
+		return false
+	}
+	p.created[s.sensorID] = true
+
+	name := s.name
+	if name == "" {
+		name = s.sensorID
+	}
+	_, err := p.target.CreateSensor(&model.SensorModel{ID: s.sensorID, Name: name, Type: s.sensorType})
+	if err != nil && !errors.Is(err, apierror.ErrDataConflict) {
+		p.logger.Warn("Failed to register replayed sensor", zap.String("id", s.sensorID), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// buildFrames แปลง entry เป็น frame โดยรวม entry ที่บันทึกในเวลาเดียวกันติดกันเป็น frame เดียว
+func buildFrames(entries []Entry, logger *zap.Logger) []frame {
+	var frames []frame
+	for _, entry := range entries {
+		samples := entrySamples(entry, logger)
+		if len(samples) == 0 {
+			continue
+		}
+
+		if n := len(frames); n > 0 && frames[n-1].at.Equal(entry.Time) {
+			frames[n-1].samples = append(frames[n-1].samples, samples...)
+			continue
+		}
+		frames = append(frames, frame{at: entry.Time, samples: samples})
+	}
+	return frames
+}
+
+// entrySamples แปลง entry หนึ่งบรรทัดเป็น sample
+func entrySamples(entry Entry, logger *zap.Logger) []sample {
+	switch entry.Kind {
+	case KindReading:
+		if entry.Reading == nil {
+			return nil
+		}
+		return []sample{{sensorID: entry.SensorID, sensorType: entry.SensorType, values: entry.Reading.Values()}}
+
+	case KindEvent:
+		switch entry.Event {
+		case stream.EventSnapshot, stream.EventReset, stream.EventSensorUpdated:
+		default:
+			return nil
+		}
+
+		var payload struct {
+			Data []model.SensorModel `json:"data"`
+		}
+		if err := json.Unmarshal(entry.Data, &payload); err != nil {
+			logger.Warn("Skipping unreadable recorded event", zap.String("event_id", entry.EventID), zap.Error(err))
+			return nil
+		}
+
+		samples := make([]sample, 0, len(payload.Data))
+		for _, sensor := range payload.Data {
+			if len(sensor.Metrics) == 0 {
+				continue
+			}
+			samples = append(samples, sample{
+				sensorID:   sensor.ID,
+				sensorType: sensor.Type,
+				name:       sensor.Name,
+				values:     sensor.Values(),
+			})
+		}
+		return samples
+
+	default:
+		return nil
+	}
+}
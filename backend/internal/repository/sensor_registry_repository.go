@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// CreateSensor ลงทะเบียนเซนเซอร์ใหม่ คืนค่า ErrDataConflict ถ้า ID ซ้ำ (ID ไม่ซ้ำกันทุก tenant)
+// error ของ ID ซ้ำเหมือนกันไม่ว่าเซนเซอร์เดิมเป็นของ tenant ใด เพื่อไม่บอกผู้เรียกว่าเป็นเซนเซอร์ของ tenant อื่น
+// เซนเซอร์ที่ยังไม่เคยส่งข้อมูลมีสถานะ offline จนกว่าจะได้รับ reading แรก และเซนเซอร์ที่ไม่ระบุ tenant อยู่ใน DefaultTenant
+func (r *SensorRepository) CreateSensor(sensor *model.SensorModel) (*model.SensorModel, error) {
+	created := copySensor(sensor)
+	created.Status = model.StatusOffline
+	if created.Tenant == "" {
+		created.Tenant = model.DefaultTenant
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	r.mutex.Lock()
+	if _, ok := r.sensors[created.ID]; ok {
+		r.mutex.Unlock()
+		return nil, apierror.Wrap(apierror.ErrDataConflict, fmt.Sprintf("sensor ID %s is not available", created.ID))
+	}
+	r.sensors[created.ID] = created
+	created = copySensor(created)
+	r.mutex.Unlock()
+
+	r.persistRegistry()
+	r.notify([]*model.SensorModel{created})
+
+	return copySensor(created), nil
+}
+
+// UpdateSensor แก้ไข metadata ของเซนเซอร์ผ่าน apply ภายใต้ lock เดียวกัน
+// ID tenant ค่าที่วัดได้ และสถานะจะไม่ถูกเปลี่ยนแม้ apply จะเขียนทับ
+func (r *SensorRepository) UpdateSensor(id string, apply func(sensor *model.SensorModel)) (*model.SensorModel, error) {
+	return r.updateSensor("", id, apply)
+}
+
+// UpdateTenantSensor แก้ไข metadata ของเซนเซอร์เฉพาะเมื่อเป็นของ tenant โดยตรวจเจ้าของภายใต้ lock เดียวกับการแก้ไข
+func (r *SensorRepository) UpdateTenantSensor(tenant string, id string, apply func(sensor *model.SensorModel)) (*model.SensorModel, error) {
+	return r.updateSensor(tenant, id, apply)
+}
+
+// updateSensor แก้ไข metadata ของเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant)
+func (r *SensorRepository) updateSensor(tenant string, id string, apply func(sensor *model.SensorModel)) (*model.SensorModel, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	r.mutex.Lock()
+
+	sensor, err := r.lookup(tenant, id)
+	if err != nil {
+		r.mutex.Unlock()
+		return nil, err
+	}
+
+	updated := copySensor(sensor)
+	apply(updated)
+	updated.ID = sensor.ID
+	updated.Tenant = sensor.Tenant
+	updated.Timestamp = sensor.Timestamp
+	updated.Status = sensor.Status
+	updated.LastSeen = sensor.LastSeen
+
+	r.sensors[id] = updated
+	updated = copySensor(updated)
+	r.mutex.Unlock()
+
+	r.persistRegistry()
+	r.notify([]*model.SensorModel{updated})
+
+	return copySensor(updated), nil
+}
+
+// DeleteSensor ลบเซนเซอร์ออกจาก registry และแจ้ง listener ของการลบ
+// ข้อมูลย้อนหลังใน storage ถูกซ่อนไว้จนถูกลบตาม retention (ดู storage.FileStore.Forget)
+// เพื่อไม่ให้เซนเซอร์ที่ลงทะเบียนด้วย ID เดิมในภายหลัง (อาจเป็นของ tenant อื่น) เห็นข้อมูลของเซนเซอร์เดิม
+func (r *SensorRepository) DeleteSensor(id string) error {
+	return r.deleteSensor("", id)
+}
+
+// DeleteTenantSensor ลบเซนเซอร์เฉพาะเมื่อเป็นของ tenant โดยตรวจเจ้าของภายใต้ lock เดียวกับการลบ
+func (r *SensorRepository) DeleteTenantSensor(tenant string, id string) error {
+	return r.deleteSensor(tenant, id)
+}
+
+// deleteSensor ลบเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant)
+func (r *SensorRepository) deleteSensor(tenant string, id string) error {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	r.mutex.RLock()
+	sensor, err := r.lookup(tenant, id)
+	var latest time.Time
+	if err == nil {
+		latest = sensor.Timestamp
+	}
+	r.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	// registry และ storage ถูกเขียนภายใต้ writeMu เท่านั้น จึงซ่อนข้อมูลก่อนลบได้โดยไม่ต้องถือ mutex ระหว่างเขียนไฟล์
+	// reading ที่บันทึกไว้ทั้งหมดมี timestamp ไม่เกินค่าล่าสุดของเซนเซอร์ (reading ที่ใหม่กว่าจะกลายเป็นค่าล่าสุดเสมอ)
+	if r.store != nil {
+		if err := r.store.Forget(id, latestTime(latest, time.Now())); err != nil {
+			r.logger.Error("Failed to forget sensor history", zap.String("id", id), zap.Error(err))
+			return err
+		}
+	}
+
+	r.mutex.Lock()
+	sensor = r.sensors[id]
+	delete(r.sensors, id)
+	delete(r.mocks, id)
+	removed := copySensor(sensor)
+	r.mutex.Unlock()
+
+	r.persistRegistry()
+	r.notifyRemoved([]*model.SensorModel{removed})
+
+	return nil
+}
+
+// latestTime คืนค่าเวลาที่ช้ากว่า
+func latestTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// persistRegistry บันทึกสถานะทันทีหลังแก้ไข registry เพื่อไม่ให้การเปลี่ยนแปลงหายถ้า process หยุดก่อน snapshot ถัดไป
+// ถ้าบันทึกไม่สำเร็จ snapshot ถัดไปจะบันทึกให้อีกครั้ง
+func (r *SensorRepository) persistRegistry() {
+	if r.store == nil {
+		return
+	}
+	if err := r.saveState(); err != nil {
+		r.logger.Error("Failed to save sensor registry", zap.Error(err))
+	}
+}
@@ -3,11 +3,17 @@ package repository
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
+	"go.uber.org/zap"
+
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/storage"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
 )
 
 // ISensorRepository คือ interface สำหรับการเข้าถึงข้อมูล sensor
@@ -18,75 +24,148 @@ type ISensorRepository interface {
 	// GetSensorByID คืนค่าข้อมูล sensor ตาม ID
 	GetSensorByID(id string) (*model.SensorModel, error)
 
+	// GetSensorsByTenant คืนค่าข้อมูล sensor ทั้งหมดของ tenant
+	GetSensorsByTenant(tenant string) ([]*model.SensorModel, error)
+
+	// GetTenantSensorByID คืนค่าข้อมูล sensor ตาม ID เฉพาะเมื่อเป็นของ tenant
+	// เซนเซอร์ของ tenant อื่นคืนค่า ErrDataNotFound เหมือนไม่มีอยู่ เพื่อไม่ให้รู้ว่ามี ID นี้
+	GetTenantSensorByID(tenant string, id string) (*model.SensorModel, error)
+
 	// UpdateRandomSensorData อัปเดตข้อมูลเซนเซอร์แบบสุ่ม
 	UpdateRandomSensorData()
+
+	// SaveReadings บันทึกค่าที่อุปกรณ์ส่งเข้ามาและคืนค่าสถานะล่าสุดของเซนเซอร์
+	SaveReadings(id string, readings []model.ReadingModel) (*model.SensorModel, error)
+
+	// SaveTenantReadings บันทึกค่าเหมือน SaveReadings เฉพาะเมื่อเซนเซอร์เป็นของ tenant (ตรวจภายใต้ lock เดียวกับการเขียน)
+	SaveTenantReadings(tenant string, id string, readings []model.ReadingModel) (*model.SensorModel, error)
+
+	// SetStatus เปลี่ยน Status ของเซนเซอร์ เฉพาะเมื่อ LastSeen ยังเท่ากับ seenAt
+	// คืนค่า false ถ้ามีข้อมูลใหม่เข้ามาหลังจากผู้เรียกอ่านค่า (ผู้เรียกควรประเมินใหม่)
+	SetStatus(id string, status string, seenAt time.Time) (bool, error)
+
+	// CreateSensor ลงทะเบียนเซนเซอร์ใหม่ คืนค่า ErrDataConflict ถ้า ID ซ้ำ
+	CreateSensor(sensor *model.SensorModel) (*model.SensorModel, error)
+
+	// UpdateSensor แก้ไข metadata ของเซนเซอร์ผ่าน apply ภายใต้ lock เดียวกัน
+	UpdateSensor(id string, apply func(sensor *model.SensorModel)) (*model.SensorModel, error)
+
+	// UpdateTenantSensor แก้ไข metadata เหมือน UpdateSensor เฉพาะเมื่อเซนเซอร์เป็นของ tenant
+	UpdateTenantSensor(tenant string, id string, apply func(sensor *model.SensorModel)) (*model.SensorModel, error)
+
+	// DeleteSensor ลบเซนเซอร์ออกจาก registry
+	DeleteSensor(id string) error
+
+	// DeleteTenantSensor ลบเซนเซอร์เฉพาะเมื่อเป็นของ tenant
+	DeleteTenantSensor(tenant string, id string) error
+
+	// GetHistory คืนค่าข้อมูลย้อนหลังของเซนเซอร์ในช่วง [from, to) สรุปเป็น bucket ละ step
+	GetHistory(id string, from time.Time, to time.Time, step time.Duration) ([]storage.Bucket, error)
+
+	// GetTenantHistory คืนค่าข้อมูลย้อนหลังเหมือน GetHistory เฉพาะเมื่อเซนเซอร์เป็นของ tenant
+	GetTenantHistory(tenant string, id string, from time.Time, to time.Time, step time.Duration) ([]storage.Bucket, error)
+
+	// AddChangeListener ลงทะเบียน listener ที่จะถูกเรียกทุกครั้งที่มีการเขียนข้อมูล
+	AddChangeListener(listener ChangeListener)
+
+	// AddRemoveListener ลงทะเบียน listener ที่จะถูกเรียกด้วยสำเนาของเซนเซอร์ที่ถูกลบ
+	AddRemoveListener(listener ChangeListener)
+
+	// Close บันทึกสถานะล่าสุดและปิด storage (ถ้ามี)
+	Close() error
 }
 
-// SensorRepository เป็น implementation ของ ISensorRepository ที่ใช้ข้อมูลจำลอง
+// ChangeListener คือ callback ที่รับสำเนาของเซนเซอร์ที่ถูกเปลี่ยนแปลงในการเขียนแต่ละครั้ง
+type ChangeListener func(changed []*model.SensorModel)
+
+// SensorRepository เป็น implementation ของ ISensorRepository ที่เก็บค่าล่าสุดไว้ในหน่วยความจำ
+// ถ้ามี store จะบันทึกทุก reading ลง storage ด้วย (ดู NewStoredSensorRepository)
 type SensorRepository struct {
-	sensors map[string]*model.SensorModel
-	mutex   sync.RWMutex
+	sensors   map[string]*model.SensorModel
+	mutex     sync.RWMutex
+	listeners []ChangeListener
+	removals  []ChangeListener
+	listenMu  sync.RWMutex
+
+	// writeMu เรียงลำดับการเขียนกับการแจ้ง listener ให้ listener ได้รับการเปลี่ยนแปลงตามลำดับที่เขียน
+	// ถือไว้ระหว่างเรียก listener แทน mutex เพื่อให้การอ่านไม่ต้องรอ listener ที่ช้า
+	writeMu sync.Mutex
+
+	// mocks คือ ID ของเซนเซอร์จำลองที่ mock loop สุ่มค่าให้
+	// เซนเซอร์ที่ได้รับข้อมูลจริงหรือถูกลบจะถูกนำออก เพื่อไม่ให้ mock loop เขียนทับ
+	mocks map[string]struct{}
+
+	store  *storage.FileStore
+	logger *zap.Logger
+
+	// done ถูกปิดเมื่อ Close เพื่อหยุด goroutine เบื้องหลัง และ loops รอให้ goroutine เหล่านั้นจบก่อนบันทึกสถานะ
+	done      chan struct{}
+	loops     sync.WaitGroup
+	closeOnce sync.Once
 }
 
+// MockDataInterval คือระยะเวลาระหว่างการสุ่มค่าเซนเซอร์จำลองแต่ละครั้ง
+const MockDataInterval = 2 * time.Second
+
 // NewSensorRepository สร้าง repository ใหม่สำหรับ sensor
 func NewSensorRepository() *SensorRepository {
 	repo := &SensorRepository{
 		sensors: make(map[string]*model.SensorModel),
+		logger:  zap.NewNop(),
+		done:    make(chan struct{}),
 	}
 
 	// สร้างข้อมูลจำลอง
 	repo.initMockSensors()
 
-	// เริ่มต้น goroutine สำหรับการจำลองข้อมูลเซนเซอร์
-	go repo.mockSensorDataLoop()
-
 	return repo
 }
 
+// StartMockDataLoop เริ่มต้น goroutine สำหรับการจำลองข้อมูลเซนเซอร์ทุก interval จนกว่าจะเรียก Close
+func (r *SensorRepository) StartMockDataLoop(interval time.Duration) {
+	r.loops.Add(1)
+	go r.mockSensorDataLoop(interval)
+}
+
+// mockSensors คือเซนเซอร์จำลองที่สร้างตอนเริ่มต้นเมื่อยังไม่มีสถานะที่บันทึกไว้
+var mockSensors = []struct {
+	id     string
+	name   string
+	typ    string
+	values map[string]float64
+	tags   []string
+}{
+	{"temp-001", "Temperature Sensor 1", "temperature", map[string]float64{model.MetricTemperature: 25.0}, []string{"building:A", "room:101"}},
+	{"temp-002", "Temperature Sensor 2", "temperature", map[string]float64{model.MetricTemperature: 22.5}, []string{"building:B", "room:201"}},
+	{"humid-001", "Humidity Sensor 1", "humidity", map[string]float64{model.MetricHumidity: 45.0}, []string{"building:A", "room:101"}},
+	{"combined-001", "Combined Sensor 1", "combined", map[string]float64{model.MetricTemperature: 24.0, model.MetricHumidity: 40.0}, []string{"building:A", "room:102"}},
+	{"co2-001", "CO2 Sensor 1", "co2", map[string]float64{model.MetricCO2: 650, model.MetricBattery: 90}, []string{"building:A", "room:102"}},
+}
+
 // initMockSensors สร้างข้อมูลเซนเซอร์จำลอง
 func (r *SensorRepository) initMockSensors() {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	// สร้างเซนเซอร์จำลอง
-	r.sensors["temp-001"] = &model.SensorModel{
-		ID:          "temp-001",
-		Name:        "Temperature Sensor 1",
-		Type:        "temperature",
-		Temperature: 25.0,
-		Humidity:    0,
-		Timestamp:   time.Now(),
-		Status:      "active",
-	}
-
-	r.sensors["temp-002"] = &model.SensorModel{
-		ID:          "temp-002",
-		Name:        "Temperature Sensor 2",
-		Type:        "temperature",
-		Temperature: 22.5,
-		Humidity:    0,
-		Timestamp:   time.Now(),
-		Status:      "active",
-	}
-
-	r.sensors["humid-001"] = &model.SensorModel{
-		ID:          "humid-001",
-		Name:        "Humidity Sensor 1",
-		Type:        "humidity",
-		Temperature: 0,
-		Humidity:    45.0,
-		Timestamp:   time.Now(),
-		Status:      "active",
-	}
+	now := time.Now()
 
-	r.sensors["combined-001"] = &model.SensorModel{
-		ID:          "combined-001",
-		Name:        "Combined Sensor 1",
-		Type:        "combined",
-		Temperature: 24.0,
-		Humidity:    40.0,
-		Timestamp:   time.Now(),
-		Status:      "active",
+	r.mocks = make(map[string]struct{}, len(mockSensors))
+	for _, m := range mockSensors {
+		sensor := &model.SensorModel{
+			ID:        m.id,
+			Name:      m.name,
+			Type:      m.typ,
+			Timestamp: now,
+			Status:    model.StatusActive,
+			LastSeen:  now,
+			Tags:      m.tags,
+			Tenant:    model.DefaultTenant,
+		}
+		for metric, value := range m.values {
+			sensor.SetValue(metric, value)
+		}
+		r.sensors[m.id] = sensor
+		r.mocks[m.id] = struct{}{}
 	}
 }
 
@@ -95,13 +174,7 @@ func (r *SensorRepository) GetAllSensors() ([]*model.SensorModel, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	// สร้าง slice จากข้อมูล map
-	sensors := make([]*model.SensorModel, 0, len(r.sensors))
-	for _, sensor := range r.sensors {
-		sensors = append(sensors, sensor)
-	}
-
-	return sensors, nil
+	return r.snapshotLocked(), nil
 }
 
 // GetSensorByID คืนค่าข้อมูล sensor ตาม ID
@@ -112,52 +185,353 @@ func (r *SensorRepository) GetSensorByID(id string) (*model.SensorModel, error)
 	// ค้นหาเซนเซอร์ตาม ID
 	sensor, ok := r.sensors[id]
 	if !ok {
-		return nil, fmt.Errorf("sensor with ID %s not found", id)
+		return nil, apierror.Wrap(apierror.ErrDataNotFound, fmt.Sprintf("sensor with ID %s not found", id))
 	}
 
+	// คืนค่าเป็นสำเนา เพื่อไม่ให้ผู้เรียกอ่านข้อมูลชนกับการเขียน
+	return copySensor(sensor), nil
+}
+
+// GetSensorsByTenant คืนค่าข้อมูล sensor ทั้งหมดของ tenant เรียงตาม ID
+func (r *SensorRepository) GetSensorsByTenant(tenant string) ([]*model.SensorModel, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	sensors := make([]*model.SensorModel, 0)
+	for _, sensor := range r.sensors {
+		if sensor.Tenant == tenant {
+			sensors = append(sensors, copySensor(sensor))
+		}
+	}
+	sortByID(sensors)
+
+	return sensors, nil
+}
+
+// GetTenantSensorByID คืนค่าข้อมูล sensor ตาม ID เฉพาะเมื่อเป็นของ tenant
+func (r *SensorRepository) GetTenantSensorByID(tenant string, id string) (*model.SensorModel, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	sensor, err := r.lookup(tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return copySensor(sensor), nil
+}
+
+// lookup คืนค่าเซนเซอร์ตาม ID เฉพาะเมื่อเป็นของ tenant (ค่าว่างคือทุก tenant) ผู้เรียกต้องถือ mutex
+// เซนเซอร์ของ tenant อื่นคืนค่า ErrDataNotFound เหมือนไม่มีอยู่
+func (r *SensorRepository) lookup(tenant string, id string) (*model.SensorModel, error) {
+	sensor, ok := r.sensors[id]
+	if !ok || (tenant != "" && sensor.Tenant != tenant) {
+		return nil, apierror.Wrap(apierror.ErrDataNotFound, fmt.Sprintf("sensor with ID %s not found", id))
+	}
 	return sensor, nil
 }
 
-// UpdateRandomSensorData อัปเดตข้อมูลเซนเซอร์แบบสุ่ม
+// UpdateRandomSensorData อัปเดตข้อมูลเซนเซอร์จำลองแบบสุ่ม
+// แต่ละ metric ขยับจากค่าเดิมไม่เกิน 1% ของช่วงค่าที่ถูกต้องตาม registry ของชนิดเซนเซอร์
+// เซนเซอร์ที่ลงทะเบียนผ่าน registry หรือได้รับข้อมูลจริงจะไม่ถูกแตะ เพื่อให้ watchdog ประเมินได้ถูกต้อง
 func (r *SensorRepository) UpdateRandomSensorData() {
-	// สุ่มค่าอุณหภูมิ 20-30°C และความชื้น 30-50%
-	temp := 20 + rand.Float64()*10
-	humidity := 30 + rand.Float64()*20
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
 
 	r.mutex.Lock()
-	defer r.mutex.Unlock()
 
-	// อัปเดตค่าให้กับเซนเซอร์ทั้งหมด
 	now := time.Now()
-	for _, sensor := range r.sensors {
+	changed := make([]*model.SensorModel, 0, len(r.mocks))
+	for id := range r.mocks {
+		sensor, ok := r.sensors[id]
+		if !ok {
+			delete(r.mocks, id)
+			continue
+		}
+
 		sensor.Timestamp = now
+		sensor.LastSeen = now
+		sensor.Status = model.StatusActive
 
-		switch sensor.Type {
-		case "temperature":
-			// ให้มีความแตกต่างเล็กน้อยระหว่างเซนเซอร์
-			sensor.Temperature = temp + (rand.Float64()-0.5)*2
-		case "humidity":
-			// ให้มีความแตกต่างเล็กน้อยระหว่างเซนเซอร์
-			sensor.Humidity = humidity + (rand.Float64()-0.5)*5
-		case "combined":
-			// สำหรับเซนเซอร์แบบรวม อัปเดตทั้งอุณหภูมิและความชื้น
-			sensor.Temperature = temp + (rand.Float64()-0.5)*2
-			sensor.Humidity = humidity + (rand.Float64()-0.5)*5
+		if sensorType, ok := model.LookupSensorType(sensor.Type); ok {
+			for _, spec := range sensorType.Metrics {
+				value, ok := sensor.Value(spec.Name)
+				if !ok {
+					// เริ่มจากกึ่งกลางของช่วงค่าสำหรับ metric ที่ยังไม่มีค่า
+					value = (spec.Min + spec.Max) / 2
+				}
+				value += (rand.Float64() - 0.5) * (spec.Max - spec.Min) * 0.02
+				sensor.SetValue(spec.Name, math.Max(spec.Min, math.Min(spec.Max, value)))
+			}
 		}
+		changed = append(changed, copySensor(sensor))
+	}
+	r.mutex.Unlock()
+
+	if len(changed) == 0 {
+		return
 	}
+	sortByID(changed)
+
+	if err := r.appendRecords(sensorRecords(changed)); err != nil {
+		r.logger.Error("Failed to store sensor readings", zap.Error(err))
+	}
+
+	// แจ้ง listener หลังปล่อย lock เพื่อไม่ให้ listener ที่ช้าถ่วงการอ่านข้อมูล (writeMu ยังคงลำดับการแจ้งไว้)
+	r.notify(changed)
+}
+
+// SaveReadings บันทึกค่าที่อุปกรณ์ส่งเข้ามาและคืนค่าสถานะล่าสุดของเซนเซอร์
+// reading ที่ไม่มี timestamp จะใช้เวลาปัจจุบัน และ reading ที่เก่ากว่าค่าปัจจุบันจะไม่เขียนทับค่าล่าสุด
+func (r *SensorRepository) SaveReadings(id string, readings []model.ReadingModel) (*model.SensorModel, error) {
+	return r.saveReadings("", id, readings)
 }
 
-// mockSensorDataLoop ใช้สำหรับสุ่มค่าเซนเซอร์เป็นระยะ
-func (r *SensorRepository) mockSensorDataLoop() {
+// SaveTenantReadings บันทึกค่าที่อุปกรณ์ส่งเข้ามาเฉพาะเมื่อเซนเซอร์เป็นของ tenant
+// การตรวจสอบเจ้าของอยู่ภายใต้ writeMu เดียวกับการเขียน เพื่อไม่ให้เซนเซอร์ที่ถูกลบแล้วลงทะเบียนใหม่โดย tenant อื่นระหว่างนั้นถูกเขียน
+func (r *SensorRepository) SaveTenantReadings(tenant string, id string, readings []model.ReadingModel) (*model.SensorModel, error) {
+	return r.saveReadings(tenant, id, readings)
+}
+
+// saveReadings บันทึกค่าของเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant)
+func (r *SensorRepository) saveReadings(tenant string, id string, readings []model.ReadingModel) (*model.SensorModel, error) {
+	now := time.Now()
+	stamped := make([]model.ReadingModel, len(readings))
+	for i, reading := range readings {
+		if reading.Timestamp.IsZero() {
+			reading.Timestamp = now
+		}
+		stamped[i] = reading
+	}
+
+	r.mutex.RLock()
+	current, err := r.lookup(tenant, id)
+	var sensorTypeName string
+	if err == nil {
+		sensorTypeName = current.Type
+	}
+	r.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// ตรวจสอบ metric และช่วงค่าตามชนิดของเซนเซอร์ ชนิดที่ไม่มีใน registry รับทุก metric
+	if sensorType, ok := model.LookupSensorType(sensorTypeName); ok {
+		for _, reading := range stamped {
+			if err := sensorType.ValidateValues(reading.Values()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	// ตรวจเจ้าของอีกครั้งแล้วบันทึกทุก reading (รวมถึงค่าที่มาช้ากว่าค่าล่าสุด) ลง storage ก่อนอัปเดตค่าในหน่วยความจำ
+	// ภายใต้ writeMu เพื่อไม่ให้เขียนลงเซนเซอร์ที่ถูกลบ (หลัง DeleteSensor ซ่อนข้อมูลแล้ว) หรือถูกลงทะเบียนใหม่โดย tenant อื่นระหว่างนั้น
+	r.mutex.RLock()
+	_, err = r.lookup(tenant, id)
+	r.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.appendRecords(readingRecords(id, stamped)); err != nil {
+		return nil, fmt.Errorf("store readings: %w", err)
+	}
+
+	r.mutex.Lock()
+	sensor := r.sensors[id]
+
+	// การได้รับข้อมูลใด ๆ (แม้จะเป็นข้อมูลย้อนหลัง) แสดงว่าอุปกรณ์ยังทำงานอยู่
+	// และหลังจากนี้ค่าของเซนเซอร์มาจากอุปกรณ์จริง mock loop จึงหยุดสุ่มค่าให้
+	delete(r.mocks, id)
+	sensor.LastSeen = now
+	sensor.Status = model.StatusActive
+
+	for _, reading := range stamped {
+		if reading.Timestamp.Before(sensor.Timestamp) {
+			continue
+		}
+
+		sensor.Timestamp = reading.Timestamp
+		for metric, value := range reading.Values() {
+			sensor.SetValue(metric, value)
+		}
+	}
+
+	updated := copySensor(sensor)
+	r.mutex.Unlock()
+
+	r.notify([]*model.SensorModel{updated})
+
+	return copySensor(updated), nil
+}
+
+// SetStatus เปลี่ยน Status ของเซนเซอร์ เฉพาะเมื่อ LastSeen ยังเท่ากับ seenAt
+// เพื่อไม่ให้ watchdog เขียนทับสถานะ active ของข้อมูลที่เพิ่งเข้ามา
+func (r *SensorRepository) SetStatus(id string, status string, seenAt time.Time) (bool, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	r.mutex.Lock()
+
+	sensor, ok := r.sensors[id]
+	if !ok {
+		r.mutex.Unlock()
+		return false, apierror.Wrap(apierror.ErrDataNotFound, fmt.Sprintf("sensor with ID %s not found", id))
+	}
+
+	if !sensor.LastSeen.Equal(seenAt) {
+		r.mutex.Unlock()
+		return false, nil
+	}
+	if sensor.Status == status {
+		r.mutex.Unlock()
+		return true, nil
+	}
+
+	sensor.Status = status
+	updated := copySensor(sensor)
+	r.mutex.Unlock()
+
+	r.notify([]*model.SensorModel{updated})
+
+	return true, nil
+}
+
+// GetHistory คืนค่าข้อมูลย้อนหลังของเซนเซอร์ในช่วง [from, to) สรุปเป็น bucket ละ step
+// repository ที่ไม่มี store เก็บเฉพาะค่าล่าสุด จึงคืนค่าเฉพาะค่าล่าสุดถ้าอยู่ในช่วงเวลา
+func (r *SensorRepository) GetHistory(id string, from time.Time, to time.Time, step time.Duration) ([]storage.Bucket, error) {
+	return r.getHistory("", id, from, to, step)
+}
+
+// GetTenantHistory คืนค่าข้อมูลย้อนหลังของเซนเซอร์เฉพาะเมื่อเป็นของ tenant
+func (r *SensorRepository) GetTenantHistory(tenant string, id string, from time.Time, to time.Time, step time.Duration) ([]storage.Bucket, error) {
+	return r.getHistory(tenant, id, from, to, step)
+}
+
+// getHistory คืนค่าข้อมูลย้อนหลังของเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant)
+func (r *SensorRepository) getHistory(tenant string, id string, from time.Time, to time.Time, step time.Duration) ([]storage.Bucket, error) {
+	sensor, err := r.GetTenantSensorByID(tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.store != nil {
+		buckets, err := r.store.History(id, from, to, step)
+		if err != nil {
+			return nil, err
+		}
+		// store ถูกอ่านโดยไม่ถือ lock ถ้าเซนเซอร์ถูกลบและลงทะเบียนใหม่โดย tenant อื่นระหว่างนั้น ข้อมูลที่อ่านได้ไม่ใช่ของ tenant
+		if _, err := r.GetTenantSensorByID(tenant, id); err != nil {
+			return nil, err
+		}
+		return buckets, nil
+	}
+
+	if sensor.Timestamp.Before(from) || !sensor.Timestamp.Before(to) {
+		return []storage.Bucket{}, nil
+	}
+	return storage.Bucketize(sensorRecords([]*model.SensorModel{sensor}), from, step), nil
+}
+
+// AddChangeListener ลงทะเบียน listener ที่จะถูกเรียกทุกครั้งที่มีการเขียนข้อมูล
+func (r *SensorRepository) AddChangeListener(listener ChangeListener) {
+	r.listenMu.Lock()
+	defer r.listenMu.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// AddRemoveListener ลงทะเบียน listener ที่จะถูกเรียกด้วยสำเนาของเซนเซอร์ที่ถูกลบ
+// เพื่อให้ผู้ที่เก็บสถานะของเซนเซอร์ไว้ (cache, alert, stream) ล้างสถานะนั้นได้
+func (r *SensorRepository) AddRemoveListener(listener ChangeListener) {
+	r.listenMu.Lock()
+	defer r.listenMu.Unlock()
+
+	r.removals = append(r.removals, listener)
+}
+
+// Close หยุด goroutine เบื้องหลัง บันทึกสถานะล่าสุดและปิด storage (ถ้ามี)
+// เมื่อ Close คืนค่าแล้ว mock loop จะไม่เขียนข้อมูลอีก
+func (r *SensorRepository) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.loops.Wait()
+		if r.store == nil {
+			return
+		}
+
+		err = r.saveState()
+		if closeErr := r.store.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// notify เรียก listener ทั้งหมดด้วยข้อมูลเซนเซอร์ที่เปลี่ยนแปลง
+func (r *SensorRepository) notify(changed []*model.SensorModel) {
+	r.listenMu.RLock()
+	defer r.listenMu.RUnlock()
+
+	for _, listener := range r.listeners {
+		listener(changed)
+	}
+}
+
+// notifyRemoved เรียก listener ของการลบด้วยข้อมูลเซนเซอร์ที่ถูกลบ
+func (r *SensorRepository) notifyRemoved(removed []*model.SensorModel) {
+	r.listenMu.RLock()
+	defer r.listenMu.RUnlock()
+
+	for _, listener := range r.removals {
+		listener(removed)
+	}
+}
+
+// snapshotLocked คืนค่าสำเนาของเซนเซอร์ทั้งหมดเรียงตาม ID (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (r *SensorRepository) snapshotLocked() []*model.SensorModel {
+	sensors := make([]*model.SensorModel, 0, len(r.sensors))
+	for _, sensor := range r.sensors {
+		sensors = append(sensors, copySensor(sensor))
+	}
+	sortByID(sensors)
+
+	return sensors
+}
+
+// sortByID เรียงเซนเซอร์ตาม ID เพื่อให้ผลลัพธ์คงที่ทุกครั้ง
+func sortByID(sensors []*model.SensorModel) {
+	sort.Slice(sensors, func(i, j int) bool {
+		return sensors[i].ID < sensors[j].ID
+	})
+}
+
+// mockSensorDataLoop สุ่มค่าเซนเซอร์ทันทีและทุก interval จนกว่า repository จะถูกปิด
+func (r *SensorRepository) mockSensorDataLoop(interval time.Duration) {
+	defer r.loops.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
 	for {
-		// อัปเดตข้อมูลแบบสุ่ม
 		r.UpdateRandomSensorData()
 
-		// รอ 2 วินาทีก่อนอัปเดตค่าถัดไป
-		time.Sleep(2 * time.Second)
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
+// copySensor สร้างสำเนาของเซนเซอร์รวมถึง map และ slice ภายใน
+func copySensor(sensor *model.SensorModel) *model.SensorModel {
+	return sensor.Clone()
+}
+
 // SerializeSensor แปลงข้อมูล SensorModel เป็น JSON string
 func SerializeSensor(sensor *model.SensorModel) (string, error) {
 	data, err := json.Marshal(sensor)
@@ -0,0 +1,277 @@
+package repository_test
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/repository"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func value(sensor *model.SensorModel, metric string) float64 {
+	v, _ := sensor.Value(metric)
+	return v
+}
+
+func TestSaveReadings(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	// เก็บการแจ้งเตือนจาก repository
+	var notified [][]*model.SensorModel
+	repo.AddChangeListener(func(changed []*model.SensorModel) {
+		notified = append(notified, changed)
+	})
+
+	// เซนเซอร์จำลองมี timestamp เป็นเวลาที่สร้าง จึงใช้เวลาหลังจากนั้น
+	now := time.Now().Add(time.Minute)
+	sensor, err := repo.SaveReadings("combined-001", []model.ReadingModel{
+		{Temperature: float(30), Timestamp: now.Add(-time.Second)},
+		{Humidity: float(55), Timestamp: now},
+	})
+	require.NoError(t, err)
+
+	// ค่าล่าสุดต้องมาจากทั้งสอง reading ตามลำดับเวลา
+	assert.Equal(t, 30.0, value(sensor, "temperature"))
+	assert.Equal(t, 55.0, value(sensor, "humidity"))
+	assert.True(t, sensor.Timestamp.Equal(now))
+
+	// listener ต้องได้เฉพาะเซนเซอร์ที่เปลี่ยน
+	require.Len(t, notified, 1)
+	require.Len(t, notified[0], 1)
+	assert.Equal(t, "combined-001", notified[0][0].ID)
+
+	// reading ที่เก่ากว่าค่าปัจจุบันต้องไม่เขียนทับ
+	sensor, err = repo.SaveReadings("combined-001", []model.ReadingModel{
+		{Temperature: float(10), Timestamp: now.Add(-time.Hour)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, value(sensor, "temperature"))
+}
+
+func TestSaveReadingsNotifiesInOrder(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	// listener ต้องได้รับค่าตามลำดับที่เขียน ไม่เช่นนั้นค่าเก่าอาจทับค่าใหม่ใน cache และ stream
+	var (
+		mu       sync.Mutex
+		last     time.Time
+		outdated int
+	)
+	repo.AddChangeListener(func(changed []*model.SensorModel) {
+		// ให้ goroutine อื่นได้ทำงานก่อน เพื่อให้การเขียนพร้อมกันมีโอกาสแจ้งสลับลำดับ
+		runtime.Gosched()
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, sensor := range changed {
+			if sensor.ID != "temp-001" {
+				continue
+			}
+			if sensor.Timestamp.Before(last) {
+				outdated++
+			}
+			last = sensor.Timestamp
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, err := repo.SaveReadings("temp-001", []model.ReadingModel{{Temperature: float(20)}})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, outdated)
+}
+
+func TestMockDataLoopStopsOnClose(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	var mu sync.Mutex
+	writes := 0
+	repo.AddChangeListener(func([]*model.SensorModel) {
+		mu.Lock()
+		defer mu.Unlock()
+		writes++
+	})
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return writes
+	}
+
+	repo.StartMockDataLoop(5 * time.Millisecond)
+	require.Eventually(t, func() bool { return count() >= 3 }, time.Second, time.Millisecond)
+
+	// หลัง Close คืนค่า mock loop ต้องไม่เขียนข้อมูลอีก
+	require.NoError(t, repo.Close())
+	closed := count()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, closed, count())
+}
+
+func TestSaveReadingsUnknownSensor(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	_, err := repo.SaveReadings("missing", []model.ReadingModel{{Temperature: float(20)}})
+
+	assert.True(t, errors.Is(err, apierror.ErrDataNotFound))
+}
+
+func TestSaveReadingsMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		reading model.ReadingModel
+		wantErr error
+	}{
+		{
+			name:    "valid_metrics",
+			reading: model.ReadingModel{Metrics: map[string]float64{"co2": 812, "battery": 76}},
+		},
+		{
+			name:    "out_of_range",
+			reading: model.ReadingModel{Metrics: map[string]float64{"co2": -5}},
+			wantErr: apierror.ErrDataInvalid,
+		},
+		{
+			// เซนเซอร์ CO2 ไม่ได้วัดอุณหภูมิ
+			name:    "unsupported_metric",
+			reading: model.ReadingModel{Temperature: float(25)},
+			wantErr: apierror.ErrDataInvalid,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := repository.NewSensorRepository()
+
+			sensor, err := repo.SaveReadings("co2-001", []model.ReadingModel{tc.reading})
+
+			if tc.wantErr != nil {
+				assert.True(t, errors.Is(err, tc.wantErr), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, model.Measurement{Value: 812, Unit: "ppm"}, sensor.Metrics["co2"])
+			assert.Equal(t, 76.0, value(sensor, "battery"))
+		})
+	}
+}
+
+func TestSensorRegistry(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	var notified [][]*model.SensorModel
+	repo.AddChangeListener(func(changed []*model.SensorModel) {
+		notified = append(notified, changed)
+	})
+
+	// ลงทะเบียนเซนเซอร์ใหม่ ต้องเริ่มเป็น offline จนกว่าจะได้รับข้อมูล
+	created, err := repo.CreateSensor(&model.SensorModel{ID: "co2-101", Name: "CO2", Type: "co2"})
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusOffline, created.Status)
+	require.Len(t, notified, 1)
+
+	// ID ซ้ำต้องได้ ErrDataConflict
+	_, err = repo.CreateSensor(&model.SensorModel{ID: "temp-001", Name: "Dup", Type: "temperature"})
+	assert.True(t, errors.Is(err, apierror.ErrDataConflict))
+
+	// เปลี่ยนชนิดเป็น humidity ต้องลบค่าอุณหภูมิที่ชนิดใหม่ไม่ได้วัด และ ID ต้องไม่เปลี่ยน
+	updated, err := repo.UpdateSensor("combined-001", func(sensor *model.SensorModel) {
+		sensor.ID = "hijacked"
+		sensor.Owner = "ops"
+		model.SensorPatchModel{Type: stringPtr("humidity")}.Apply(sensor)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "combined-001", updated.ID)
+	assert.Equal(t, "ops", updated.Owner)
+	_, hasTemperature := updated.Value("temperature")
+	assert.False(t, hasTemperature)
+	assert.Equal(t, 40.0, value(updated, "humidity"))
+
+	require.NoError(t, repo.DeleteSensor("co2-101"))
+	_, err = repo.GetSensorByID("co2-101")
+	assert.True(t, errors.Is(err, apierror.ErrDataNotFound))
+	assert.True(t, errors.Is(repo.DeleteSensor("co2-101"), apierror.ErrDataNotFound))
+}
+
+func TestSensorTenants(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	_, err := repo.CreateSensor(&model.SensorModel{ID: "acme-1", Name: "Acme", Type: "co2", Tenant: "acme"})
+	require.NoError(t, err)
+
+	// เซนเซอร์จำลองอยู่ใน tenant เริ่มต้น และแต่ละ tenant เห็นเฉพาะเซนเซอร์ของตัวเอง
+	defaults, err := repo.GetSensorsByTenant(model.DefaultTenant)
+	require.NoError(t, err)
+	assert.Len(t, defaults, 5)
+	acme, err := repo.GetSensorsByTenant("acme")
+	require.NoError(t, err)
+	require.Len(t, acme, 1)
+	assert.Equal(t, "acme-1", acme[0].ID)
+
+	_, err = repo.GetTenantSensorByID("acme", "temp-001")
+	assert.True(t, errors.Is(err, apierror.ErrDataNotFound))
+	_, err = repo.GetTenantSensorByID("acme", "acme-1")
+	assert.NoError(t, err)
+
+	// tenant ย้ายไม่ได้ด้วยการแก้ไข metadata
+	updated, err := repo.UpdateSensor("acme-1", func(sensor *model.SensorModel) {
+		sensor.Tenant = model.DefaultTenant
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", updated.Tenant)
+}
+
+// TestTenantScopedWrites ทดสอบว่าการเขียนของ tenant ตรวจเจ้าของในการเรียกเดียวกับการเขียน
+// เซนเซอร์ที่ถูกลบแล้วลงทะเบียนใหม่โดย tenant อื่นต้องเขียนไม่ได้ แม้ผู้เรียกเคยตรวจพบว่าเป็นของตัวเองมาก่อน
+func TestTenantScopedWrites(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	_, err := repo.CreateSensor(&model.SensorModel{ID: "lab-1", Name: "Acme", Type: "temperature", Tenant: "acme"})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTenantSensor("acme", "lab-1"))
+	_, err = repo.CreateSensor(&model.SensorModel{ID: "lab-1", Name: "Globex", Type: "temperature", Tenant: "globex"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	_, err = repo.SaveTenantReadings("acme", "lab-1", []model.ReadingModel{{Temperature: float(99), Timestamp: now}})
+	assert.True(t, errors.Is(err, apierror.ErrDataNotFound))
+	_, err = repo.UpdateTenantSensor("acme", "lab-1", func(sensor *model.SensorModel) { sensor.Name = "Stolen" })
+	assert.True(t, errors.Is(err, apierror.ErrDataNotFound))
+	_, err = repo.GetTenantHistory("acme", "lab-1", now.Add(-time.Hour), now.Add(time.Hour), time.Hour)
+	assert.True(t, errors.Is(err, apierror.ErrDataNotFound))
+	assert.True(t, errors.Is(repo.DeleteTenantSensor("acme", "lab-1"), apierror.ErrDataNotFound))
+
+	sensor, err := repo.GetTenantSensorByID("globex", "lab-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Globex", sensor.Name)
+	assert.Empty(t, sensor.Metrics)
+
+	_, err = repo.SaveTenantReadings("globex", "lab-1", []model.ReadingModel{{Temperature: float(21), Timestamp: now}})
+	require.NoError(t, err)
+	_, err = repo.UpdateTenantSensor("globex", "lab-1", func(sensor *model.SensorModel) { sensor.Name = "Globex Lab" })
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteTenantSensor("globex", "lab-1"))
+}
+
+func stringPtr(v string) *string {
+	return &v
+}
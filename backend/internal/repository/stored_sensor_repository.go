@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/storage"
+)
+
+// stateSnapshotInterval คือระยะเวลาระหว่างการบันทึกสถานะล่าสุดลง storage
+// ข้อมูลที่เขียนหลัง snapshot ล่าสุดจะถูกกู้คืนจาก segment ตอนเริ่มต้น
+const stateSnapshotInterval = time.Minute
+
+// NewStoredSensorRepository สร้าง repository ที่บันทึกทุก reading ลง store
+// และกู้คืนสถานะล่าสุดจาก snapshot กับ segment ที่เขียนหลัง snapshot นั้น
+func NewStoredSensorRepository(store *storage.FileStore, logger *zap.Logger) (*SensorRepository, error) {
+	repo := &SensorRepository{
+		sensors: make(map[string]*model.SensorModel),
+		store:   store,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+
+	if err := repo.restore(); err != nil {
+		return nil, err
+	}
+
+	repo.loops.Add(1)
+	go repo.stateSnapshotLoop()
+
+	return repo, nil
+}
+
+// restore โหลดสถานะล่าสุดจาก store ถ้ายังไม่เคยบันทึกจะเริ่มจากเซนเซอร์จำลอง
+func (r *SensorRepository) restore() error {
+	sensors, savedAt, found, err := r.store.LoadState()
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+
+	if !found {
+		r.initMockSensors()
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, sensor := range sensors {
+		// สถานะที่บันทึกก่อนรองรับหลาย tenant ไม่มี tenant
+		if sensor.Tenant == "" {
+			sensor.Tenant = model.DefaultTenant
+		}
+		r.sensors[sensor.ID] = sensor
+	}
+
+	// สถานะที่บันทึกไว้ไม่ได้แยกเซนเซอร์จำลองออกจากเซนเซอร์จริง
+	// จึงถือว่าเซนเซอร์ของ tenant เริ่มต้นที่ ID ตรงกับเซนเซอร์จำลองเป็นเซนเซอร์จำลอง
+	r.mocks = make(map[string]struct{}, len(mockSensors))
+	for _, m := range mockSensors {
+		if sensor, ok := r.sensors[m.id]; ok && sensor.Tenant == model.DefaultTenant {
+			r.mocks[m.id] = struct{}{}
+		}
+	}
+
+	// เล่น record ที่เขียนหลัง snapshot ซ้ำ เผื่อ process ถูกหยุดก่อนได้บันทึกสถานะ
+	records, err := r.store.Since(savedAt.Add(-stateSnapshotInterval))
+	if err != nil {
+		return fmt.Errorf("replay segments: %w", err)
+	}
+
+	for _, record := range records {
+		sensor, ok := r.sensors[record.SensorID]
+		if !ok || record.Timestamp.Before(sensor.Timestamp) {
+			continue
+		}
+		applyRecord(sensor, record)
+	}
+
+	r.logger.Info("Restored sensor state from storage",
+		zap.Int("sensors", len(r.sensors)),
+		zap.Int("replayed", len(records)),
+		zap.Time("savedAt", savedAt))
+
+	return nil
+}
+
+// stateSnapshotLoop บันทึกสถานะล่าสุดเป็นระยะจนกว่า repository จะถูกปิด
+func (r *SensorRepository) stateSnapshotLoop() {
+	defer r.loops.Done()
+
+	ticker := time.NewTicker(stateSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			if err := r.saveState(); err != nil {
+				r.logger.Error("Failed to save sensor state", zap.Error(err))
+			}
+		}
+	}
+}
+
+// saveState บันทึกสำเนาของเซนเซอร์ทั้งหมดลง store
+func (r *SensorRepository) saveState() error {
+	r.mutex.RLock()
+	sensors := r.snapshotLocked()
+	r.mutex.RUnlock()
+
+	return r.store.SaveState(sensors)
+}
+
+// appendRecords บันทึก record ลง store สำหรับ repository ที่ไม่มี store จะไม่ทำอะไร
+func (r *SensorRepository) appendRecords(records []storage.Record) error {
+	if r.store == nil {
+		return nil
+	}
+	return r.store.Append(records)
+}
+
+// readingRecords แปลง reading ที่อุปกรณ์ส่งเข้ามาเป็น record สำหรับบันทึก
+func readingRecords(id string, readings []model.ReadingModel) []storage.Record {
+	records := make([]storage.Record, 0, len(readings))
+	for _, reading := range readings {
+		records = append(records, storage.Record{SensorID: id, Timestamp: reading.Timestamp, Values: reading.Values()})
+	}
+	return records
+}
+
+// sensorRecords แปลงค่าล่าสุดของทุก metric ของเซนเซอร์เป็น record
+func sensorRecords(sensors []*model.SensorModel) []storage.Record {
+	records := make([]storage.Record, 0, len(sensors))
+	for _, sensor := range sensors {
+		records = append(records, storage.Record{SensorID: sensor.ID, Timestamp: sensor.Timestamp, Values: sensor.Values()})
+	}
+	return records
+}
+
+// applyRecord เขียนค่าจาก record ทับค่าล่าสุดของเซนเซอร์
+func applyRecord(sensor *model.SensorModel, record storage.Record) {
+	sensor.Timestamp = record.Timestamp
+	for metric, value := range record.Values {
+		sensor.SetValue(metric, value)
+	}
+}
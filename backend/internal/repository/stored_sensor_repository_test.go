@@ -0,0 +1,150 @@
+package repository_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/repository"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/storage"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+func TestStoredSensorRepositoryRestore(t *testing.T) {
+	dir := t.TempDir()
+	logger := zaptest.NewLogger(t)
+
+	store, err := storage.OpenFileStore(dir, storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	repo, err := repository.NewStoredSensorRepository(store, logger)
+	require.NoError(t, err)
+
+	now := time.Now().Add(time.Minute)
+	_, err = repo.SaveReadings("temp-001", []model.ReadingModel{
+		{Temperature: float(26), Timestamp: now},
+		{Temperature: float(24), Timestamp: now.Add(-time.Hour)},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	// เปิดใหม่จากไดเรกทอรีเดิม ต้องได้ค่าล่าสุดกลับมา
+	store, err = storage.OpenFileStore(dir, storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	restored, err := repository.NewStoredSensorRepository(store, logger)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	sensor, err := restored.GetSensorByID("temp-001")
+	require.NoError(t, err)
+	assert.Equal(t, 26.0, value(sensor, "temperature"))
+	assert.True(t, sensor.Timestamp.Equal(now))
+
+	// ทุก reading รวมถึงค่าที่มาช้าต้องถูกเก็บไว้
+	records, err := store.Query("temp-001", now.Add(-2*time.Hour), now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestStoredSensorRepositoryReplaysAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	logger := zaptest.NewLogger(t)
+
+	store, err := storage.OpenFileStore(dir, storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	repo, err := repository.NewStoredSensorRepository(store, logger)
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	// เขียน segment โดยตรงเหมือน process ถูกหยุดก่อนได้บันทึกสถานะ
+	now := time.Now().Add(time.Minute)
+	require.NoError(t, store.Append([]storage.Record{
+		{SensorID: "humid-001", Timestamp: now, Values: map[string]float64{"humidity": 61}},
+	}))
+
+	restored, err := repository.NewStoredSensorRepository(store, logger)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	sensor, err := restored.GetSensorByID("humid-001")
+	require.NoError(t, err)
+	assert.Equal(t, 61.0, value(sensor, "humidity"))
+}
+
+func TestStoredSensorRegistry(t *testing.T) {
+	dir := t.TempDir()
+	logger := zaptest.NewLogger(t)
+
+	store, err := storage.OpenFileStore(dir, storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	repo, err := repository.NewStoredSensorRepository(store, logger)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	_, err = repo.CreateSensor(&model.SensorModel{
+		ID:       "co2-101",
+		Name:     "Meeting Room CO2",
+		Type:     "co2",
+		Location: &model.LocationModel{Building: "A", Room: "301"},
+		Owner:    "facilities",
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteSensor("temp-002"))
+
+	// เปิดใหม่โดยไม่ได้ Close (จำลอง process ถูกหยุด) การแก้ไข registry ต้องถูกบันทึกไว้แล้ว
+	reopened, err := storage.OpenFileStore(dir, storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	restored, err := repository.NewStoredSensorRepository(reopened, logger)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	sensor, err := restored.GetSensorByID("co2-101")
+	require.NoError(t, err)
+	assert.Equal(t, "facilities", sensor.Owner)
+	assert.Equal(t, "301", sensor.Location.Room)
+
+	_, err = restored.GetSensorByID("temp-002")
+	assert.True(t, errors.Is(err, apierror.ErrDataNotFound))
+}
+
+// TestStoredSensorDeleteHidesHistory ทดสอบว่าเซนเซอร์ที่ลงทะเบียนด้วย ID ของเซนเซอร์ที่ถูกลบ (โดย tenant อื่น) ไม่เห็นข้อมูลย้อนหลังของเซนเซอร์เดิม
+func TestStoredSensorDeleteHidesHistory(t *testing.T) {
+	dir := t.TempDir()
+	logger := zaptest.NewLogger(t)
+
+	store, err := storage.OpenFileStore(dir, storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	repo, err := repository.NewStoredSensorRepository(store, logger)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	now := time.Now()
+	original, err := repo.SaveReadings("temp-001", []model.ReadingModel{
+		{Temperature: float(99), Timestamp: now.Add(-10 * time.Minute)},
+		{Temperature: float(98), Timestamp: now.Add(time.Minute)},
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteSensor("temp-001"))
+
+	_, err = repo.CreateSensor(&model.SensorModel{ID: "temp-001", Name: "Globex Lab", Type: original.Type, Tenant: "globex"})
+	require.NoError(t, err)
+	_, err = repo.SaveReadings("temp-001", []model.ReadingModel{{Temperature: float(21), Timestamp: now.Add(2 * time.Minute)}})
+	require.NoError(t, err)
+
+	buckets, err := repo.GetHistory("temp-001", now.Add(-time.Hour), now.Add(time.Hour), 2*time.Hour)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	assert.Equal(t, storage.Stats{Count: 1, Sum: 21, Min: 21, Max: 21, Last: 21}, buckets[0].Metrics["temperature"])
+
+	// ข้อมูลที่ถูกซ่อนต้องยังคงถูกซ่อนหลังเปิด store ใหม่
+	reopened, err := storage.OpenFileStore(dir, storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	records, err := reopened.Query("temp-001", now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 21.0, records[0].Values["temperature"])
+}
@@ -0,0 +1,114 @@
+package router
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/handler"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/config"
+)
+
+// authSetup คือ guard และ handler ของการยืนยันตัวตนที่ setupRoutes ใช้กำหนด scope ของแต่ละ route
+type authSetup struct {
+	// guard ใช้กับ REST endpoint ทั้งหมด
+	guard *auth.Guard
+
+	// streamGuard ใช้กับ SSE stream ซึ่งรับ stream ticket เพิ่มจาก guard
+	streamGuard *auth.Guard
+
+	// handler ออก stream ticket (nil เมื่อปิดการยืนยันตัวตน)
+	handler *handler.AuthHandler
+
+	// keys คือ API key ที่โหลดจากไฟล์ ใช้แยก bucket ของ rate limiter (nil เมื่อไม่ได้กำหนดไฟล์)
+	keys *auth.KeyStore
+}
+
+// setupAuth สร้าง authenticator ตาม config (API key, JWT และ stream ticket)
+// และลงทะเบียน endpoint ของ local issuer เมื่อเปิดใช้ในโหมด dev
+func setupAuth(e *echo.Echo, cfg *config.Config, log *zap.Logger) (*authSetup, error) {
+	if !cfg.AuthEnabled {
+		disabled := auth.NewGuard()
+		return &authSetup{guard: disabled, streamGuard: disabled}, nil
+	}
+
+	var authenticators []auth.Authenticator
+	var keyStore *auth.KeyStore
+	if cfg.AuthKeysFile != "" {
+		keys, err := auth.LoadAPIKeys(cfg.AuthKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Loaded API keys", zap.String("file", cfg.AuthKeysFile), zap.Int("keys", len(keys)))
+		keyStore = auth.NewKeyStore(keys)
+		authenticators = append(authenticators, keyStore)
+	}
+
+	var issuer *auth.LocalIssuer
+	if cfg.AuthJWTEnabled {
+		roleScopes, err := auth.ParseRoleScopes(cfg.AuthJWTRoleScopes)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys auth.KeySet
+		switch {
+		case cfg.AuthJWTLocalIssuer:
+			if !cfg.IsDevelopment() {
+				return nil, apierror.Wrap(apierror.ErrInvalidConfig, "APP_AUTH_JWT_LOCAL_ISSUER is only allowed in dev")
+			}
+			issuer, err = auth.NewLocalIssuer(cfg.AuthJWTIssuer, cfg.AuthJWTAudience)
+			if err != nil {
+				return nil, err
+			}
+			keys = issuer
+			log.Warn("Local JWT issuer enabled, tokens are signed with a throwaway key")
+
+		case cfg.AuthJWTJWKS != "":
+			jwks := auth.NewJWKS(cfg.AuthJWTJWKS, cfg.AuthJWTJWKSCacheTTL)
+			if err := jwks.Refresh(); err != nil {
+				// identity provider อาจยังไม่พร้อม จะลองโหลดใหม่เมื่อมี request แรก
+				log.Warn("Failed to load JWKS", zap.String("jwks", cfg.AuthJWTJWKS), zap.Error(err))
+			}
+			keys = jwks
+
+		default:
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, "APP_AUTH_JWT_ENABLED requires APP_AUTH_JWT_JWKS or APP_AUTH_JWT_LOCAL_ISSUER")
+		}
+
+		authenticators = append(authenticators, auth.NewJWTAuthenticator(keys, auth.JWTConfig{
+			Issuer:      cfg.AuthJWTIssuer,
+			Audience:    cfg.AuthJWTAudience,
+			RolesClaim:  cfg.AuthJWTRolesClaim,
+			RoleScopes:  roleScopes,
+			TenantClaim: cfg.AuthJWTTenantClaim,
+		}))
+	}
+
+	if len(authenticators) == 0 {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, "APP_AUTH_ENABLED requires APP_AUTH_KEYS_FILE or APP_AUTH_JWT_ENABLED")
+	}
+
+	tickets, err := auth.NewTicketIssuer([]byte(cfg.AuthStreamTicketSecret), cfg.AuthStreamTicketTTL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AuthStreamTicketSecret == "" {
+		log.Info("APP_AUTH_STREAM_TICKET_SECRET not set, stream tickets are only valid on this instance")
+	}
+
+	authHandler := handler.NewAuthHandler(tickets, issuer, cfg.AuthJWTRolesClaim, cfg.AuthJWTTenantClaim, log)
+	if issuer != nil {
+		e.GET("/auth/local/jwks.json", authHandler.GetLocalJWKS)
+		e.POST("/auth/local/token", authHandler.IssueLocalToken)
+	}
+
+	guard := auth.NewGuard(authenticators...)
+	return &authSetup{
+		guard:       guard,
+		streamGuard: guard.With(tickets),
+		handler:     authHandler,
+		keys:        keyStore,
+	}, nil
+}
@@ -2,17 +2,21 @@ package router
 
 import (
 	"net/http"
-	"strings"
-	"time"
+	"net/url"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
-	"golang.org/x/time/rate"
 
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/handler"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/service"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/config"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
+	appmiddleware "github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/middleware"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/tenant"
 )
 
 // IRouter คือ interface สำหรับจัดการ router
@@ -32,41 +36,43 @@ func NewRouter() *Router {
 func SetupRouter(cfg *config.Config, log *zap.Logger) *echo.Echo {
 	e := echo.New()
 
-	// จำกัดจำนวน concurrent requests
-	store := middleware.NewRateLimiterMemoryStoreWithConfig(
-		middleware.RateLimiterMemoryStoreConfig{
-			Rate:      rate.Limit(cfg.MaxConnections),
-			Burst:     int(float64(cfg.MaxConnections) * 1.5),
-			ExpiresIn: time.Duration(cfg.WriteTimeout),
-		},
-	)
-
-	e.Use(middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
-		Skipper: middleware.DefaultSkipper,
-		Store:   store,
-		IdentifierExtractor: func(ctx echo.Context) (string, error) {
-			id := ctx.RealIP()
-			return id, nil
-		},
-		ErrorHandler: func(context echo.Context, err error) error {
-			return &echo.HTTPError{
-				Code:     http.StatusTooManyRequests,
-				Message:  "Too many requests",
-				Internal: err,
-			}
-		},
-		DenyHandler: func(context echo.Context, identifier string, err error) error {
-			return &echo.HTTPError{
-				Code:     http.StatusTooManyRequests,
-				Message:  "Too many requests",
-				Internal: err,
+	// client IP ของ limiter อ่านจาก X-Forwarded-For เฉพาะเมื่อมาจาก proxy ที่เชื่อถือ
+	ipExtractor, err := appmiddleware.NewIPExtractor(config.SplitList(cfg.TrustedProxies))
+	if err != nil {
+		log.Fatal("Failed to setup trusted proxies", zap.Error(err))
+	}
+	e.IPExtractor = ipExtractor
+
+	// วัด latency ของทุก request (รวม request ที่ถูก rate limiter ปฏิเสธ)
+	e.Use(metrics.Middleware())
+
+	// ยืนยันตัวตนของ API (ปิดอยู่ทุก route ผ่านได้) แต่ละ route กำหนด scope ที่ต้องใช้ใน setupRoutes
+	authn, err := setupAuth(e, cfg, log)
+	if err != nil {
+		log.Fatal("Failed to setup authentication", zap.Error(err))
+	}
+
+	// จำกัดอัตรา request ตาม policy ของแต่ละ route group (จำนวน stream ที่เปิดพร้อมกันจำกัดแยกใน setupRoutes)
+	if cfg.RateLimitEnabled {
+		policies := appmiddleware.DefaultRateLimitPolicies()
+		if cfg.RateLimitFile != "" {
+			policies, err = appmiddleware.LoadRateLimitPolicies(cfg.RateLimitFile)
+			if err != nil {
+				log.Fatal("Failed to load rate limit policies", zap.Error(err))
 			}
-		},
-	}))
+			log.Info("Loaded rate limit policies", zap.String("file", cfg.RateLimitFile), zap.Int("policies", len(policies)))
+		}
+
+		e.Use(appmiddleware.NewRateLimiter(appmiddleware.RateLimiterConfig{
+			Policies: policies,
+			Exempt:   config.SplitList(cfg.RateLimitExempt),
+			Keys:     authn.keys,
+		}).Middleware())
+	}
 
 	// ตั้งค่า CORS
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: strings.Split(cfg.CORSHosts, ","),
+		AllowOrigins: config.SplitList(cfg.CORSHosts),
 		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
 	}))
 
@@ -86,6 +92,7 @@ func SetupRouter(cfg *config.Config, log *zap.Logger) *echo.Echo {
 		LogError:    true,
 		HandleError: true, // forwards error to the global error handler, so it can decide appropriate status code
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			v.URI = redactCredentials(v.URI)
 			if v.Error == nil {
 				log.Debug(v.URI,
 					zap.Int("status", v.Status))
@@ -117,13 +124,36 @@ func SetupRouter(cfg *config.Config, log *zap.Logger) *echo.Echo {
 	e.HTTPErrorHandler = customHTTPErrorHandler(log)
 
 	// เรียกฟังก์ชัน setupRoutes
-	if err := setupRoutes(e, cfg, log); err != nil {
+	if err := setupRoutes(e, cfg, authn, log); err != nil {
 		log.Fatal("Failed to setup routes", zap.Error(err))
 	}
 
 	return e
 }
 
+// redactCredentials ซ่อนค่า access_token และ stream ticket ใน URI ก่อนบันทึก log เพื่อไม่ให้ credential หลุดไปอยู่ใน log
+func redactCredentials(uri string) string {
+	u, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := u.Query()
+	redacted := false
+	for _, name := range []string{auth.QueryAccessToken, auth.QueryStreamTicket} {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return uri
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
 // customHTTPErrorHandler สร้าง HTTP error handler แบบกำหนดเอง
 func customHTTPErrorHandler(log *zap.Logger) echo.HTTPErrorHandler {
 	return func(err error, c echo.Context) {
@@ -164,21 +194,80 @@ func customHTTPErrorHandler(log *zap.Logger) echo.HTTPErrorHandler {
 	}
 }
 
+// chain รวม middleware หลายตัวเป็นตัวเดียว โดยตัวแรกทำงานก่อน
+func chain(middlewares ...echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
 // setupRoutes ตั้งค่า routes สำหรับแอปพลิเคชัน
-func setupRoutes(e *echo.Echo, cfg *config.Config, log *zap.Logger) error {
+func setupRoutes(e *echo.Echo, cfg *config.Config, authn *authSetup, log *zap.Logger) error {
 	// Server static files จาก frontend
 	e.Static("/", cfg.StaticPath)
 
 	// ตั้งค่า API routes
 	api := e.Group("/api")
 
+	// ระบุ tenant หลังยืนยันตัวตน เพื่อให้ tenant ของ credential มาก่อน tenant ของ host
+	tenantHosts, err := tenant.ParseHosts(cfg.TenantHosts)
+	if err != nil {
+		return err
+	}
+	tenants, err := tenant.NewResolver(tenantHosts, cfg.TenantDefault)
+	if err != nil {
+		return err
+	}
+	resolveTenant := tenants.Middleware()
+
+	read := chain(authn.guard.Require(auth.ScopeReadSensors), resolveTenant)
+	write := chain(authn.guard.Require(auth.ScopeWriteReadings), resolveTenant)
+	admin := chain(authn.guard.Require(auth.ScopeAdmin), resolveTenant)
+
 	// สร้าง handler instances
-	sensorHandler := handler.NewSensorHandler(log)
+	sensorHandler := handler.NewSensorHandler(service.GetSensorService(cfg, log), log)
+
+	// จำกัดจำนวน SSE stream ที่เปิดพร้อมกันทั้ง server และต่อ client IP
+	streamLimiter := appmiddleware.NewConnectionLimiterWithConfig(appmiddleware.ConnectionLimiterConfig{
+		MaxConnections: int64(cfg.MaxConnections),
+		MaxPerIP:       cfg.MaxConnsPerIP,
+	})
 
 	// Sensor endpoints
-	api.GET("/sensors/stream", sensorHandler.HandleSSE)
-	api.GET("/sensors", sensorHandler.GetSensorData)
-	api.GET("/sensors/:id", sensorHandler.GetSensorByID)
+	api.GET("/sensors/stream", sensorHandler.HandleSSE, authn.streamGuard.Require(auth.ScopeReadSensors), resolveTenant, streamLimiter.Middleware())
+	if authn.handler != nil {
+		api.POST("/sensors/stream/ticket", authn.handler.IssueStreamTicket, read)
+	}
+	api.GET("/sensors", sensorHandler.GetSensorData, read)
+	api.GET("/sensors/:id", sensorHandler.GetSensorByID, read)
+	api.GET("/sensors/:id/history", sensorHandler.GetSensorHistory, read)
+	api.POST("/sensors/:id/readings", sensorHandler.IngestReadings, write)
+	api.POST("/sensors", sensorHandler.CreateSensor, admin)
+	api.PUT("/sensors/:id", sensorHandler.UpdateSensor, admin)
+	api.PATCH("/sensors/:id", sensorHandler.PatchSensor, admin)
+	api.DELETE("/sensors/:id", sensorHandler.DeleteSensor, admin)
+	api.GET("/alerts", sensorHandler.GetAlerts, read)
+
+	// Webhook endpoints
+	webhookHandler := handler.NewWebhookHandler(service.GetNotifier(cfg, log), log)
+	api.GET("/webhooks/deliveries", webhookHandler.GetDeliveries, admin)
+	api.GET("/webhooks/dead-letters", webhookHandler.GetDeadLetters, admin)
+	api.POST("/webhooks/dead-letters/:id/redeliver", webhookHandler.RedeliverDeadLetter, admin)
+
+	// Replay control endpoints (only when a recording is being replayed)
+	if replayer := service.GetReplayer(cfg, log); replayer != nil {
+		replayHandler := handler.NewReplayHandler(replayer, log)
+		api.GET("/replay", replayHandler.GetReplayStatus, read)
+		api.POST("/replay/step", replayHandler.StepReplay, admin)
+	}
+
+	// Sensor type registry (metrics, units and valid ranges)
+	api.GET("/sensor-types", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, model.SensorTypes())
+	}, read)
 
 	// Environment endpoint
 	api.GET("/environment", func(c echo.Context) error {
@@ -188,7 +277,12 @@ func setupRoutes(e *echo.Echo, cfg *config.Config, log *zap.Logger) error {
 			"uat":         cfg.IsUAT(),
 			"development": cfg.IsDevelopment(),
 		})
-	})
+	}, read)
+
+	// Prometheus metrics endpoint
+	if cfg.MetricsEnabled {
+		e.GET("/metrics", metrics.Handler())
+	}
 
 	// Health check endpoint
 	e.GET("/health", func(c echo.Context) error {
@@ -1,20 +1,28 @@
 package service
 
 import (
+	"encoding/json"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/alert"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/notify"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/recording"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/repository"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/simulator"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/storage"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/watchdog"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/cache"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/config"
 )
 
 const (
-	// CacheTTL กำหนดเวลาหมดอายุของ cache
-	CacheTTL = 30 * time.Second
-
-	// SensorDataCacheKey คีย์สำหรับ cache ข้อมูลเซนเซอร์ทั้งหมด
+	// SensorDataCacheKey คีย์สำหรับ cache ข้อมูลเซนเซอร์ทั้งหมด (tenant ใช้ key นี้ต่อท้าย prefix ของ tenant ดู tenantCacheKey)
 	SensorDataCacheKey = "all_sensors"
 )
 
@@ -25,85 +33,713 @@ type ISensorService interface {
 
 	// GetSensorByID คืนค่าข้อมูล sensor ตาม ID ในรูปแบบ JSON
 	GetSensorByID(id string) (string, error)
+
+	// IngestReadings บันทึกค่าที่อุปกรณ์ส่งเข้ามาและคืนค่าข้อมูล sensor ล่าสุดในรูปแบบ JSON
+	IngestReadings(id string, readings []model.ReadingModel) (string, error)
+
+	// GetSensorHistory คืนค่าข้อมูลย้อนหลังของ sensor แบบแบ่งช่วงเวลาในรูปแบบ JSON
+	GetSensorHistory(id string, query model.HistoryQuery) (string, error)
+
+	// CreateSensor ลงทะเบียน sensor ใหม่และคืนค่าข้อมูล sensor ในรูปแบบ JSON
+	CreateSensor(registration model.SensorRegistrationModel) (string, error)
+
+	// UpdateSensor แทนที่ metadata ทั้งหมดของ sensor และคืนค่าข้อมูล sensor ในรูปแบบ JSON
+	UpdateSensor(id string, registration model.SensorRegistrationModel) (string, error)
+
+	// PatchSensor แก้ไข metadata บางส่วนของ sensor และคืนค่าข้อมูล sensor ในรูปแบบ JSON
+	PatchSensor(id string, patch model.SensorPatchModel) (string, error)
+
+	// DeleteSensor ลบ sensor ออกจาก registry
+	DeleteSensor(id string) error
+
+	// GetAlerts คืนค่า alert ที่ยัง pending หรือ firing อยู่ในรูปแบบ JSON
+	GetAlerts() (string, error)
+
+	// GetSnapshot คืนค่าข้อมูลเซนเซอร์ล่าสุดทั้งหมดในรูปแบบ event ที่กรองตาม Filter ได้
+	GetSnapshot() (stream.Event, error)
+
+	// Subscribe ลงทะเบียนรับ event การอัปเดตข้อมูลเซนเซอร์ พร้อม event ที่พลาดไปตาม lastEventID
+	Subscribe(lastEventID string) (*stream.Subscriber, stream.Replay)
+
+	// Unsubscribe ยกเลิกการรับ event
+	Unsubscribe(sub *stream.Subscriber)
+
+	// ServerID คืนค่า ID ของ server ที่ใส่ไว้ใน payload ของ stream
+	ServerID() string
+
+	// ForTenant คืนค่า service ที่อ่านและเขียนได้เฉพาะเซนเซอร์ของ tenant (ค่าว่างคือ model.DefaultTenant)
+	ForTenant(tenant string) ISensorService
+
+	// Close ปิด repository และบันทึกสถานะล่าสุดลง storage
+	Close() error
 }
 
 // SensorService เป็น implementation ของ ISensorService ที่ใช้ cache
+// ตัว SensorService เองเห็นเซนเซอร์ทุก tenant (ใช้โดย simulator และ ingest) ส่วน API ใช้ ForTenant
 type SensorService struct {
 	repository repository.ISensorRepository
 	cache      *cache.Cache
+	broker     *stream.Broker
+	alerts     *alert.Engine
+	notifier   notify.INotifier
+	watchdog   *watchdog.Watchdog
+	simulator  *simulator.Simulator
+	recorder   *recording.Recorder
+	replayer   *recording.Replayer
+	loads      singleflight.Group
+	cacheTTL   time.Duration
+	staleTTL   time.Duration
+	freshUntil map[string]time.Time
+	versions   map[string]uint64
+	freshMu    sync.Mutex
+	statuses   map[string]string
+	statusMu   sync.Mutex
+	serverID   string
+	done       chan struct{}
 	logger     *zap.Logger
 }
 
 // NewSensorService สร้าง service ใหม่สำหรับ sensor ที่ใช้ cache
-func NewSensorService(repo repository.ISensorRepository, logger *zap.Logger) *SensorService {
-	return &SensorService{
+// และลงทะเบียนกับ repository เพื่อล้าง cache และกระจายข้อมูลไปยัง broker ทุกครั้งที่มีการเขียน
+func NewSensorService(repo repository.ISensorRepository, broker *stream.Broker, logger *zap.Logger) *SensorService {
+	s := &SensorService{
 		repository: repo,
 		cache:      cache.NewCache(),
+		broker:     broker,
+		cacheTTL:   config.DefaultCacheTTL,
+		freshUntil: make(map[string]time.Time),
+		versions:   make(map[string]uint64),
+		statuses:   make(map[string]string),
+		serverID:   stream.ServerID(),
+		done:       make(chan struct{}),
 		logger:     logger,
 	}
+
+	// จำ Status เริ่มต้นไว้ เพื่อแจ้งเฉพาะการเปลี่ยนแปลงที่เกิดขึ้นหลังจากนี้
+	if sensors, err := repo.GetAllSensors(); err == nil {
+		for _, sensor := range sensors {
+			s.statuses[sensor.ID] = sensor.Status
+		}
+	}
+
+	// ล้าง cache ก่อนส่ง event เพื่อให้ client ที่เรียก REST API หลังได้รับ event เห็นค่าล่าสุดเสมอ
+	repo.AddChangeListener(s.invalidateChanged)
+	repo.AddChangeListener(s.publishSensors)
+	repo.AddChangeListener(s.detectStatusChanges)
+	repo.AddRemoveListener(s.handleRemoved)
+
+	return s
 }
 
-// GetAllSensors คืนค่าข้อมูล sensor ทั้งหมดในรูปแบบ JSON
-func (s *SensorService) GetAllSensors() (string, error) {
-	// ลองดึงข้อมูลจาก cache ก่อน
-	if cachedData, found := s.cache.Get(SensorDataCacheKey); found {
-		s.logger.Debug("Cache hit for all sensors data")
-		return string(cachedData), nil
+// SetCachePolicy กำหนดอายุของ cache และช่วง stale-while-revalidate
+// เมื่อ staleTTL มากกว่า 0 ข้อมูลที่อายุเกิน ttl แต่ไม่เกิน ttl+staleTTL จะถูกคืนให้ผู้เรียกทันที
+// ระหว่างที่ refresh เพียงครั้งเดียวทำงานอยู่เบื้องหลัง
+func (s *SensorService) SetCachePolicy(ttl time.Duration, staleTTL time.Duration) {
+	s.freshMu.Lock()
+	defer s.freshMu.Unlock()
+
+	s.cacheTTL = ttl
+	s.staleTTL = staleTTL
+}
+
+// StartWatchdog เริ่ม watchdog ที่เปลี่ยน Status ของเซนเซอร์เป็น stale/offline เมื่อไม่ได้รับข้อมูลตาม policy
+// การเปลี่ยน Status จะถูกส่งบน stream เป็น event "sensor.status"
+func (s *SensorService) StartWatchdog(w *watchdog.Watchdog, interval time.Duration) {
+	s.watchdog = w
+	w.Start(interval)
+}
+
+// StartSimulator ลงทะเบียนเซนเซอร์ของ scenario และเริ่มเขียนข้อมูลจำลองผ่าน service
+func (s *SensorService) StartSimulator(sim *simulator.Simulator) error {
+	if err := sim.Start(); err != nil {
+		return err
 	}
+	s.simulator = sim
+	return nil
+}
 
-	// ถ้าไม่พบใน cache ดึงข้อมูลจริง
-	sensors, err := s.repository.GetAllSensors()
+// AttachRecorder บันทึก reading ที่เขียนเข้า repository หรือ event ที่ส่งทาง stream ตาม Mode ของ recorder
+func (s *SensorService) AttachRecorder(rec *recording.Recorder) {
+	s.recorder = rec
+	if rec.Mode() == recording.ModeEvents {
+		rec.RecordEvents(s.broker)
+		return
+	}
+	s.repository.AddChangeListener(rec.RecordChanges)
+}
+
+// StartReplay เริ่มเล่นไฟล์ recording ผ่าน repository
+func (s *SensorService) StartReplay(replayer *recording.Replayer) {
+	s.replayer = replayer
+	replayer.Start()
+}
+
+// AttachNotifier ส่ง webhook เมื่อ alert firing/resolved และเมื่อ Status ของเซนเซอร์เปลี่ยน
+func (s *SensorService) AttachNotifier(notifier notify.INotifier) {
+	s.notifier = notifier
+}
+
+// AttachAlerts ให้ engine ประเมินกฎทุกครั้งที่ repository มีการเขียน
+// และกระจายการเปลี่ยนสถานะของ alert ไปยัง stream เป็น event "alert"
+func (s *SensorService) AttachAlerts(engine *alert.Engine) {
+	s.alerts = engine
+	s.repository.AddChangeListener(engine.Evaluate)
+	s.repository.AddRemoveListener(engine.Remove)
+	engine.AddListener(s.handleAlert)
+}
+
+// GetAlerts คืนค่า alert ที่ยัง pending หรือ firing อยู่ในรูปแบบ JSON
+func (s *SensorService) GetAlerts() (string, error) {
+	return s.getAlerts("")
+}
+
+// getAlerts คืนค่า alert ที่ active ของ tenant ในรูปแบบ JSON (ค่าว่างคือทุก tenant)
+func (s *SensorService) getAlerts(tenant string) (string, error) {
+	alerts := []alert.Alert{}
+	if s.alerts != nil {
+		for _, a := range s.alerts.Active() {
+			if tenant == "" || a.Tenant == tenant {
+				alerts = append(alerts, a)
+			}
+		}
+	}
+
+	data, err := json.Marshal(alerts)
 	if err != nil {
-		s.logger.Error("Failed to get all sensors", zap.Error(err))
+		s.logger.Error("Failed to serialize alerts", zap.Error(err))
 		return "", err
 	}
 
-	// แปลงเป็น JSON string
-	jsonData, err := repository.SerializeSensors(sensors)
+	return string(data), nil
+}
+
+// handleAlert กระจายการเปลี่ยนสถานะของ alert ไปยัง stream และส่ง webhook เมื่อ firing หรือ resolved
+func (s *SensorService) handleAlert(a alert.Alert) {
+	s.publishAlert(a)
+
+	if s.notifier == nil {
+		return
+	}
+	switch a.State {
+	case alert.StateFiring:
+		s.notifier.Notify(notify.EventAlertFiring, a.Tenant, a)
+	case alert.StateResolved:
+		s.notifier.Notify(notify.EventAlertResolved, a.Tenant, a)
+	}
+}
+
+// detectStatusChanges เปรียบเทียบ Status ของเซนเซอร์ที่เปลี่ยนแปลงกับค่าก่อนหน้า และแจ้งเมื่อแตกต่าง
+func (s *SensorService) detectStatusChanges(changed []*model.SensorModel) {
+	var changes []model.StatusChangeModel
+
+	s.statusMu.Lock()
+	for _, sensor := range changed {
+		previous, known := s.statuses[sensor.ID]
+		s.statuses[sensor.ID] = sensor.Status
+		if !known || previous == sensor.Status {
+			continue
+		}
+
+		changes = append(changes, model.StatusChangeModel{
+			SensorID:       sensor.ID,
+			SensorType:     sensor.Type,
+			Tags:           sensor.Tags,
+			Tenant:         sensor.Tenant,
+			PreviousStatus: previous,
+			Status:         sensor.Status,
+			LastSeen:       sensor.LastSeen,
+			ChangedAt:      time.Now().UTC(),
+		})
+	}
+	s.statusMu.Unlock()
+
+	for _, change := range changes {
+		s.logger.Info("Sensor status changed",
+			zap.String("id", change.SensorID),
+			zap.String("from", change.PreviousStatus),
+			zap.String("to", change.Status))
+
+		s.publishStatusChange(change)
+		if s.notifier != nil {
+			s.notifier.Notify(notify.EventSensorStatus, change.Tenant, change)
+		}
+	}
+}
+
+// publishStatusChange กระจายการเปลี่ยน Status ไปยัง stream โดยใช้ข้อมูลเซนเซอร์สำหรับ Filter
+func (s *SensorService) publishStatusChange(change model.StatusChangeModel) {
+	data, err := json.Marshal(change)
 	if err != nil {
-		s.logger.Error("Failed to serialize sensors", zap.Error(err))
-		return "", err
+		s.logger.Error("Failed to serialize status change", zap.String("id", change.SensorID), zap.Error(err))
+		return
+	}
+
+	s.broker.Publish(stream.NewSensorEvent(stream.EventSensorStatus, s.serverID, []stream.Item{{
+		SensorID:   change.SensorID,
+		SensorType: change.SensorType,
+		Tags:       change.Tags,
+		Tenant:     change.Tenant,
+		JSON:       data,
+	}}))
+}
+
+// publishAlert กระจายการเปลี่ยนสถานะของ alert โดยใช้ข้อมูลเซนเซอร์ของ alert สำหรับ Filter
+func (s *SensorService) publishAlert(a alert.Alert) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		s.logger.Error("Failed to serialize alert for stream", zap.String("id", a.ID), zap.Error(err))
+		return
 	}
 
-	// เก็บลง cache
-	s.cache.Set(SensorDataCacheKey, []byte(jsonData), CacheTTL)
-	s.logger.Debug("Cached all sensors data", zap.Duration("ttl", CacheTTL))
+	s.broker.Publish(stream.NewSensorEvent(stream.EventAlert, s.serverID, []stream.Item{{
+		SensorID:   a.SensorID,
+		SensorType: a.SensorType,
+		Tags:       a.Tags,
+		Tenant:     a.Tenant,
+		JSON:       data,
+	}}))
+}
 
-	return jsonData, nil
+// Subscribe ลงทะเบียนรับ event การอัปเดตข้อมูลเซนเซอร์ พร้อม event ที่พลาดไปตาม lastEventID
+func (s *SensorService) Subscribe(lastEventID string) (*stream.Subscriber, stream.Replay) {
+	return s.broker.Subscribe(lastEventID)
 }
 
-// GetSensorByID คืนค่าข้อมูล sensor ตาม ID ในรูปแบบ JSON
-func (s *SensorService) GetSensorByID(id string) (string, error) {
-	// สร้าง cache key สำหรับ sensor ID นี้
-	cacheKey := "sensor_" + id
+// Unsubscribe ยกเลิกการรับ event
+func (s *SensorService) Unsubscribe(sub *stream.Subscriber) {
+	s.broker.Unsubscribe(sub)
+}
 
-	// ลองดึงข้อมูลจาก cache ก่อน
-	if cachedData, found := s.cache.Get(cacheKey); found {
-		s.logger.Debug("Cache hit for sensor", zap.String("id", id))
-		return string(cachedData), nil
+// ServerID คืนค่า ID ของ server ที่ใส่ไว้ใน payload ของ stream
+func (s *SensorService) ServerID() string {
+	return s.serverID
+}
+
+// Close หยุดแหล่งข้อมูลจำลอง watchdog recorder และ resync ปิด repository และบันทึกสถานะล่าสุดลง storage
+func (s *SensorService) Close() error {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	if s.replayer != nil {
+		s.replayer.Stop()
+	}
+	if s.simulator != nil {
+		s.simulator.Stop()
+	}
+	if s.recorder != nil {
+		if err := s.recorder.Close(); err != nil {
+			s.logger.Error("Failed to close recording", zap.Error(err))
+		}
 	}
+	if s.watchdog != nil {
+		s.watchdog.Stop()
+	}
+	if s.alerts != nil {
+		s.alerts.Stop()
+	}
+	return s.repository.Close()
+}
 
-	// ถ้าไม่พบใน cache ดึงข้อมูลจริง
-	sensor, err := s.repository.GetSensorByID(id)
+// IngestReadings บันทึกค่าที่อุปกรณ์ส่งเข้ามาและคืนค่าข้อมูล sensor ล่าสุดในรูปแบบ JSON
+// repository จะแจ้งให้ล้าง cache ที่เกี่ยวข้องและส่ง delta ไปยัง SSE client ก่อนที่ SaveReadings จะคืนค่า
+func (s *SensorService) IngestReadings(id string, readings []model.ReadingModel) (string, error) {
+	return s.ingestReadings("", id, readings)
+}
+
+// ingestReadings บันทึกค่าของเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant)
+func (s *SensorService) ingestReadings(tenant string, id string, readings []model.ReadingModel) (string, error) {
+	sensor, err := s.repository.SaveTenantReadings(tenant, id, readings)
 	if err != nil {
-		s.logger.Error("Failed to get sensor by ID", zap.String("id", id), zap.Error(err))
+		s.logger.Error("Failed to save sensor readings", zap.String("id", id), zap.Error(err))
 		return "", err
 	}
 
-	// แปลงเป็น JSON string
 	jsonData, err := repository.SerializeSensor(sensor)
 	if err != nil {
 		s.logger.Error("Failed to serialize sensor", zap.String("id", id), zap.Error(err))
 		return "", err
 	}
 
-	// เก็บลง cache
-	s.cache.Set(cacheKey, []byte(jsonData), CacheTTL)
-	s.logger.Debug("Cached sensor data", zap.String("id", id), zap.Duration("ttl", CacheTTL))
+	return jsonData, nil
+}
+
+// CreateSensor ลงทะเบียน sensor ใหม่ใน model.DefaultTenant คืนค่า ErrDataConflict ถ้า ID ซ้ำ
+// repository จะแจ้ง broker ให้ส่ง sensor.updated เหมือนการอัปเดตค่าปกติ
+func (s *SensorService) CreateSensor(registration model.SensorRegistrationModel) (string, error) {
+	return s.createSensor(model.DefaultTenant, registration)
+}
+
+// createSensor ลงทะเบียน sensor ใหม่ให้ tenant
+func (s *SensorService) createSensor(tenant string, registration model.SensorRegistrationModel) (string, error) {
+	sensor := &model.SensorModel{Tenant: tenant}
+	registration.Apply(sensor)
+
+	created, err := s.repository.CreateSensor(sensor)
+	if err != nil {
+		s.logger.Error("Failed to create sensor", zap.String("id", registration.ID), zap.Error(err))
+		return "", err
+	}
+	s.logger.Info("Sensor registered",
+		zap.String("id", created.ID),
+		zap.String("type", created.Type),
+		zap.String("tenant", created.Tenant))
+
+	return s.sensorChanged(created)
+}
+
+// UpdateSensor แทนที่ metadata ทั้งหมดของ sensor โดยค่าที่วัดได้และสถานะคงเดิม
+func (s *SensorService) UpdateSensor(id string, registration model.SensorRegistrationModel) (string, error) {
+	return s.updateSensor("", id, registration.Apply)
+}
+
+// PatchSensor แก้ไขเฉพาะ metadata ที่ส่งมา
+func (s *SensorService) PatchSensor(id string, patch model.SensorPatchModel) (string, error) {
+	return s.updateSensor("", id, patch.Apply)
+}
+
+// updateSensor แก้ไข metadata ของเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant) ผ่าน apply
+func (s *SensorService) updateSensor(tenant string, id string, apply func(sensor *model.SensorModel)) (string, error) {
+	updated, err := s.repository.UpdateTenantSensor(tenant, id, apply)
+	if err != nil {
+		s.logger.Error("Failed to update sensor", zap.String("id", id), zap.Error(err))
+		return "", err
+	}
+
+	return s.sensorChanged(updated)
+}
+
+// DeleteSensor ลบ sensor ออกจาก registry
+// cache, สถานะ และ alert ของเซนเซอร์ถูกล้างโดย handleRemoved และ alert engine ที่รับแจ้งจาก repository
+func (s *SensorService) DeleteSensor(id string) error {
+	return s.deleteSensor("", id)
+}
+
+// deleteSensor ลบเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant)
+func (s *SensorService) deleteSensor(tenant string, id string) error {
+	if err := s.repository.DeleteTenantSensor(tenant, id); err != nil {
+		s.logger.Error("Failed to delete sensor", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	s.logger.Info("Sensor deleted", zap.String("id", id))
+
+	return nil
+}
+
+// handleRemoved ล้าง cache และสถานะของเซนเซอร์ที่ถูกลบ แล้วแจ้ง client ด้วย event "sensor.deleted"
+func (s *SensorService) handleRemoved(removed []*model.SensorModel) {
+	s.statusMu.Lock()
+	for _, sensor := range removed {
+		delete(s.statuses, sensor.ID)
+	}
+	s.statusMu.Unlock()
+
+	s.invalidateChanged(removed)
+
+	items := make([]stream.Item, 0, len(removed))
+	for _, sensor := range removed {
+		data, err := json.Marshal(map[string]string{"id": sensor.ID, "type": sensor.Type})
+		if err != nil {
+			continue
+		}
+		items = append(items, stream.Item{
+			SensorID:   sensor.ID,
+			SensorType: sensor.Type,
+			Tags:       sensor.Tags,
+			Tenant:     sensor.Tenant,
+			JSON:       data,
+		})
+	}
+	if len(items) > 0 {
+		s.broker.Publish(stream.NewSensorEvent(stream.EventSensorDeleted, s.serverID, items))
+	}
+}
+
+// sensorChanged คืนค่า sensor ที่ถูกแก้ไข metadata เป็น JSON (cache ถูกล้างโดย invalidateChanged แล้ว)
+func (s *SensorService) sensorChanged(sensor *model.SensorModel) (string, error) {
+	jsonData, err := repository.SerializeSensor(sensor)
+	if err != nil {
+		s.logger.Error("Failed to serialize sensor", zap.String("id", sensor.ID), zap.Error(err))
+		return "", err
+	}
 
 	return jsonData, nil
 }
 
+// GetSensorHistory คืนค่าข้อมูลย้อนหลังของ sensor แบบแบ่งช่วงเวลาในรูปแบบ JSON
+// แต่ละ bucket ถูกสรุปด้วย query.Agg และไม่ถูกเก็บใน cache เพราะช่วงเวลาแตกต่างกันทุก request
+func (s *SensorService) GetSensorHistory(id string, query model.HistoryQuery) (string, error) {
+	return s.getSensorHistory("", id, query)
+}
+
+// getSensorHistory คืนค่าข้อมูลย้อนหลังของเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant)
+func (s *SensorService) getSensorHistory(tenant string, id string, query model.HistoryQuery) (string, error) {
+	buckets, err := s.repository.GetTenantHistory(tenant, id, query.From, query.To, query.Step)
+	if err != nil {
+		s.logger.Error("Failed to get sensor history", zap.String("id", id), zap.Error(err))
+		return "", err
+	}
+
+	history := model.HistoryModel{
+		SensorID: id,
+		From:     query.From,
+		To:       query.To,
+		Step:     query.Step.String(),
+		Agg:      query.Agg,
+		Series:   make(map[string][]model.HistoryPoint),
+	}
+
+	for _, bucket := range buckets {
+		for name, stats := range bucket.Metrics {
+			history.Series[name] = append(history.Series[name], model.HistoryPoint{
+				Time:  bucket.Start,
+				Value: aggregateValue(stats, query.Agg),
+				Count: stats.Count,
+			})
+		}
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		s.logger.Error("Failed to serialize sensor history", zap.String("id", id), zap.Error(err))
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// aggregateValue เลือกค่าสรุปของ bucket ตามวิธีที่ร้องขอ
+func aggregateValue(stats storage.Stats, agg model.HistoryAgg) float64 {
+	switch agg {
+	case model.AggMin:
+		return stats.Min
+	case model.AggMax:
+		return stats.Max
+	case model.AggLast:
+		return stats.Last
+	default:
+		return stats.Avg()
+	}
+}
+
+// GetSnapshot คืนค่าข้อมูลเซนเซอร์ล่าสุดทั้งหมดในรูปแบบ event ที่กรองตาม Filter ได้
+func (s *SensorService) GetSnapshot() (stream.Event, error) {
+	return s.snapshot(s.repository.GetAllSensors())
+}
+
+// snapshot สร้าง event snapshot จากผลการอ่านเซนเซอร์ของ repository
+func (s *SensorService) snapshot(sensors []*model.SensorModel, err error) (stream.Event, error) {
+	if err != nil {
+		s.logger.Error("Failed to get sensors for snapshot", zap.Error(err))
+		return stream.Event{}, err
+	}
+
+	return s.sensorEvent(stream.EventSnapshot, sensors)
+}
+
+// StartResync เริ่ม goroutine ที่กระจาย snapshot ทั้งหมดทุก interval จนกว่า service จะถูกปิด
+// เพื่อให้ client ที่อาจพลาด delta ได้ข้อมูลที่ถูกต้องกลับมา (interval เป็น 0 คือปิด)
+func (s *SensorService) StartResync(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				evt, err := s.GetSnapshot()
+				if err != nil {
+					continue
+				}
+				s.broker.Publish(evt)
+			}
+		}
+	}()
+}
+
+// publishSensors serialize เฉพาะเซนเซอร์ที่เปลี่ยนแปลงครั้งเดียวต่อการอัปเดต แล้วกระจายไปยังทุก subscriber
+func (s *SensorService) publishSensors(changed []*model.SensorModel) {
+	if len(changed) == 0 {
+		return
+	}
+
+	evt, err := s.sensorEvent(stream.EventSensorUpdated, changed)
+	if err != nil {
+		return
+	}
+
+	s.broker.Publish(evt)
+}
+
+// sensorEvent serialize เซนเซอร์ทีละตัวเป็น item แล้วสร้าง event สำหรับ stream
+func (s *SensorService) sensorEvent(eventType string, sensors []*model.SensorModel) (stream.Event, error) {
+	items := make([]stream.Item, 0, len(sensors))
+	for _, sensor := range sensors {
+		jsonData, err := repository.SerializeSensor(sensor)
+		if err != nil {
+			s.logger.Error("Failed to serialize sensor for stream", zap.String("id", sensor.ID), zap.Error(err))
+			return stream.Event{}, err
+		}
+
+		items = append(items, stream.Item{
+			SensorID:   sensor.ID,
+			SensorType: sensor.Type,
+			Tags:       sensor.Tags,
+			Tenant:     sensor.Tenant,
+			JSON:       []byte(jsonData),
+		})
+	}
+
+	return stream.NewSensorEvent(eventType, s.serverID, items), nil
+}
+
+// GetAllSensors คืนค่าข้อมูล sensor ทั้งหมดในรูปแบบ JSON
+func (s *SensorService) GetAllSensors() (string, error) {
+	return s.cached(SensorDataCacheKey, func() (string, error) {
+		sensors, err := s.repository.GetAllSensors()
+		if err != nil {
+			s.logger.Error("Failed to get all sensors", zap.Error(err))
+			return "", err
+		}
+
+		// แปลงเป็น JSON string
+		jsonData, err := repository.SerializeSensors(sensors)
+		if err != nil {
+			s.logger.Error("Failed to serialize sensors", zap.Error(err))
+			return "", err
+		}
+		return jsonData, nil
+	})
+}
+
+// GetSensorByID คืนค่าข้อมูล sensor ตาม ID ในรูปแบบ JSON
+func (s *SensorService) GetSensorByID(id string) (string, error) {
+	return s.cached(sensorCacheKey(id), func() (string, error) {
+		sensor, err := s.repository.GetSensorByID(id)
+		if err != nil {
+			s.logger.Error("Failed to get sensor by ID", zap.String("id", id), zap.Error(err))
+			return "", err
+		}
+
+		// แปลงเป็น JSON string
+		jsonData, err := repository.SerializeSensor(sensor)
+		if err != nil {
+			s.logger.Error("Failed to serialize sensor", zap.String("id", id), zap.Error(err))
+			return "", err
+		}
+		return jsonData, nil
+	})
+}
+
+// cached คืนค่าจาก cache ถ้ามี ถ้าไม่มีจะเรียก load เพียงครั้งเดียวต่อ key แม้มีผู้เรียกพร้อมกันหลายราย
+// ข้อมูลที่อยู่ในช่วง stale-while-revalidate จะถูกคืนทันทีและ refresh อยู่เบื้องหลัง
+func (s *SensorService) cached(key string, load func() (string, error)) (string, error) {
+	if cachedData, found := s.cache.Get(key); found {
+		if s.isStale(key) {
+			// DoChan ไม่รอผลลัพธ์ และถ้ามีการโหลด key นี้อยู่แล้วจะไม่เริ่มใหม่
+			s.loads.DoChan(key, s.loadFunc(key, load))
+			s.logger.Debug("Serving stale cache while revalidating", zap.String("key", key))
+		}
+		return string(cachedData), nil
+	}
+
+	value, err, shared := s.loads.Do(key, s.loadFunc(key, load))
+	if err != nil {
+		return "", err
+	}
+	if shared {
+		s.logger.Debug("Coalesced concurrent cache load", zap.String("key", key))
+	}
+	return value.(string), nil
+}
+
+// loadFunc ห่อ load ให้เก็บผลลัพธ์ลง cache สำหรับใช้กับ singleflight
+// ถ้า key ถูกล้างระหว่างโหลด ผลลัพธ์จะคืนให้ผู้เรียกแต่ไม่ถูกเก็บ เพื่อไม่ให้ข้อมูลก่อนการเขียนกลับเข้า cache
+func (s *SensorService) loadFunc(key string, load func() (string, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		s.freshMu.Lock()
+		version := s.versions[key]
+		s.freshMu.Unlock()
+
+		jsonData, err := load()
+		if err != nil {
+			return "", err
+		}
+
+		s.freshMu.Lock()
+		defer s.freshMu.Unlock()
+
+		if s.versions[key] != version {
+			s.logger.Debug("Discarding cache load superseded by a write", zap.String("key", key))
+			return jsonData, nil
+		}
+
+		// cache เก็บข้อมูลไว้ต่อจนสิ้นสุดช่วง stale ส่วนความสดของข้อมูลดูจาก freshUntil
+		s.freshUntil[key] = time.Now().Add(s.cacheTTL)
+		s.cache.Set(key, []byte(jsonData), s.cacheTTL+s.staleTTL)
+		s.logger.Debug("Cached sensor data", zap.String("key", key), zap.Duration("ttl", s.cacheTTL))
+
+		return jsonData, nil
+	}
+}
+
+// isStale ตรวจสอบว่าข้อมูลใน cache อายุเกิน TTL แล้วหรือยัง (เกิดได้เฉพาะเมื่อเปิด stale-while-revalidate)
+func (s *SensorService) isStale(key string) bool {
+	s.freshMu.Lock()
+	defer s.freshMu.Unlock()
+
+	freshUntil, ok := s.freshUntil[key]
+	return ok && time.Now().After(freshUntil)
+}
+
+// invalidateChanged ล้าง cache ของเซนเซอร์ที่เปลี่ยนแปลงและข้อมูลเซนเซอร์ทั้งหมด (ใช้เป็น repository.ChangeListener)
+// ทั้ง key รวมทุก tenant และ key ของ tenant เจ้าของเซนเซอร์ ส่วน cache ของ tenant อื่นไม่ถูกล้าง
+func (s *SensorService) invalidateChanged(changed []*model.SensorModel) {
+	keys := make([]string, 0, 2+3*len(changed))
+	keys = append(keys, SensorDataCacheKey)
+	tenants := make(map[string]bool)
+	for _, sensor := range changed {
+		keys = append(keys, sensorCacheKey(sensor.ID), tenantCacheKey(sensor.Tenant, sensorCacheKey(sensor.ID)))
+		if !tenants[sensor.Tenant] {
+			tenants[sensor.Tenant] = true
+			keys = append(keys, tenantCacheKey(sensor.Tenant, SensorDataCacheKey))
+		}
+	}
+	s.invalidate(keys...)
+}
+
+// invalidate ล้าง cache ของ key ที่ระบุ การโหลดที่ค้างอยู่ของ key เหล่านี้จะไม่ถูกเก็บลง cache
+// และผู้เรียกรายถัดไปจะเริ่มโหลดใหม่แทนการรอผลของการโหลดเดิม
+func (s *SensorService) invalidate(keys ...string) {
+	s.freshMu.Lock()
+	defer s.freshMu.Unlock()
+
+	for _, key := range keys {
+		s.versions[key]++
+		s.loads.Forget(key)
+		s.cache.Delete(key)
+		delete(s.freshUntil, key)
+	}
+}
+
+// sensorCacheKey สร้าง cache key สำหรับเซนเซอร์แต่ละตัว
+func sensorCacheKey(id string) string {
+	return "sensor_" + id
+}
+
+// tenantCacheKey เติม prefix ของ tenant ให้ cache key เพื่อให้แต่ละ tenant มี cache ของตัวเอง
+// ชื่อ tenant มี "|" ไม่ได้ (ดู tenant.Valid) key ของ tenant ต่างกันจึงไม่ชนกัน
+func tenantCacheKey(tenant string, key string) string {
+	return "tenant:" + tenant + "|" + key
+}
+
 // SensorServiceInstance กำหนดตัวแปรสำหรับ singleton pattern
 var (
 	sensorServiceInstance ISensorService
@@ -111,10 +747,148 @@ var (
 )
 
 // GetSensorService คืนค่า instance ของ ISensorService แบบ singleton
-func GetSensorService(logger *zap.Logger) ISensorService {
+func GetSensorService(cfg *config.Config, logger *zap.Logger) ISensorService {
 	sensorServiceOnce.Do(func() {
-		repo := repository.NewSensorRepository()
-		sensorServiceInstance = NewSensorService(repo, logger)
+		// ลงทะเบียนชนิดเซนเซอร์เพิ่มเติมก่อนสร้าง repository ที่ใช้ registry ตอนกู้คืนข้อมูล
+		if cfg.SensorTypesFile != "" {
+			types, err := model.LoadSensorTypes(cfg.SensorTypesFile)
+			if err != nil {
+				logger.Fatal("Failed to load sensor types", zap.Error(err))
+			}
+			logger.Info("Loaded sensor types", zap.String("file", cfg.SensorTypesFile), zap.Int("types", len(types)))
+		}
+
+		repo, err := newSensorRepository(cfg, logger)
+		if err != nil {
+			logger.Fatal("Failed to open sensor storage", zap.Error(err))
+		}
+		broker := stream.NewBroker(stream.DefaultSubscriberBuffer, stream.DefaultHistorySize, logger)
+		s := NewSensorService(repo, broker, logger)
+		s.SetCachePolicy(cfg.CacheTTL, cfg.CacheStaleWhileRevalidate)
+		s.AttachNotifier(GetNotifier(cfg, logger))
+		if cfg.AlertRulesFile != "" {
+			rules, err := alert.LoadRules(cfg.AlertRulesFile)
+			if err != nil {
+				logger.Fatal("Failed to load alert rules", zap.Error(err))
+			}
+			logger.Info("Loaded alert rules", zap.String("file", cfg.AlertRulesFile), zap.Int("rules", len(rules)))
+			engine := alert.NewEngine(rules, logger.Named("alert"))
+			s.AttachAlerts(engine)
+			engine.Start(alert.DefaultCheckInterval)
+		}
+		if cfg.WatchdogEnabled {
+			w, err := newWatchdog(cfg, repo, logger)
+			if err != nil {
+				logger.Fatal("Failed to load watchdog policies", zap.Error(err))
+			}
+			s.StartWatchdog(w, cfg.WatchdogCheckInterval)
+		}
+		if cfg.RecordFile != "" {
+			rec, err := recording.NewRecorder(cfg.RecordFile, recording.Mode(cfg.RecordMode), logger.Named("recorder"))
+			if err != nil {
+				logger.Fatal("Failed to create recording", zap.Error(err))
+			}
+			s.AttachRecorder(rec)
+			logger.Info("Recording sensor data", zap.String("file", cfg.RecordFile), zap.String("mode", cfg.RecordMode))
+		}
+		// เริ่มเขียนข้อมูลหลังจากต่อ listener ครบแล้ว replay ใช้แทน simulator และ simulator ใช้แทน mock loop
+		if cfg.ReplayFile != "" {
+			_, entries, err := recording.ReadFile(cfg.ReplayFile)
+			if err != nil {
+				logger.Fatal("Failed to load recording", zap.Error(err))
+			}
+			s.StartReplay(recording.NewReplayer(entries, repo, recording.ReplayOptions{
+				Speed: cfg.ReplaySpeed,
+				Step:  cfg.ReplayStep,
+				Loop:  cfg.ReplayLoop,
+			}, logger.Named("replay")))
+		} else if cfg.SimulatorEnabled {
+			scenario, err := simulator.LoadScenario(cfg.SimulatorScenarioFile)
+			if err != nil {
+				logger.Fatal("Failed to load simulator scenario", zap.Error(err))
+			}
+			if err := s.StartSimulator(simulator.New(scenario, s, logger.Named("simulator"))); err != nil {
+				logger.Fatal("Failed to start simulator", zap.Error(err))
+			}
+		} else if cfg.MockData {
+			repo.StartMockDataLoop(repository.MockDataInterval)
+		}
+		s.StartResync(cfg.SSEResyncInterval)
+		sensorServiceInstance = s
 	})
 	return sensorServiceInstance
 }
+
+// GetReplayer คืนค่า replayer ของ sensor service (nil ถ้าไม่ได้เปิด replay)
+func GetReplayer(cfg *config.Config, logger *zap.Logger) *recording.Replayer {
+	if s, ok := GetSensorService(cfg, logger).(*SensorService); ok {
+		return s.replayer
+	}
+	return nil
+}
+
+// notifierInstance กำหนดตัวแปรสำหรับ singleton ของ webhook notifier
+var (
+	notifierInstance *notify.Notifier
+	notifierOnce     sync.Once
+)
+
+// GetNotifier คืนค่า instance ของ webhook notifier แบบ singleton (ไม่มี URL คือไม่ส่ง)
+func GetNotifier(cfg *config.Config, logger *zap.Logger) *notify.Notifier {
+	notifierOnce.Do(func() {
+		urls := config.SplitList(cfg.WebhookURLs)
+
+		notifierInstance = notify.NewNotifier(notify.Config{
+			URLs:           urls,
+			Secret:         cfg.WebhookSecret,
+			MaxAttempts:    cfg.WebhookMaxAttempts,
+			InitialBackoff: cfg.WebhookInitialBackoff,
+			Timeout:        cfg.WebhookTimeout,
+		}, logger.Named("webhook"))
+	})
+	return notifierInstance
+}
+
+// newWatchdog สร้าง watchdog จาก policy เริ่มต้นใน config และ policy ต่อชนิดเซนเซอร์จากไฟล์ (ถ้ามี)
+func newWatchdog(cfg *config.Config, repo repository.ISensorRepository, logger *zap.Logger) (*watchdog.Watchdog, error) {
+	fallback := watchdog.Policy{
+		ExpectedInterval: cfg.WatchdogExpectedInterval,
+		StaleAfterMissed: cfg.WatchdogStaleAfterMissed,
+		OfflineAfter:     cfg.WatchdogOfflineAfter,
+	}
+
+	var policies map[string]watchdog.Policy
+	if cfg.WatchdogPolicyFile != "" {
+		var err error
+		if policies, err = watchdog.LoadPolicies(cfg.WatchdogPolicyFile, fallback); err != nil {
+			return nil, err
+		}
+	}
+
+	return watchdog.New(repo, fallback, policies, logger.Named("watchdog")), nil
+}
+
+// newSensorRepository สร้าง repository ตาม storage driver ที่กำหนดใน config
+func newSensorRepository(cfg *config.Config, logger *zap.Logger) (*repository.SensorRepository, error) {
+	if cfg.StorageDriver != "file" {
+		return repository.NewSensorRepository(), nil
+	}
+
+	store, err := storage.OpenFileStore(cfg.StoragePath, storage.RetentionPolicy{
+		Raw:                cfg.StorageRawRetention,
+		Minute:             cfg.StorageMinuteRetention,
+		Hour:               cfg.StorageHourRetention,
+		CompactionInterval: cfg.StorageCompactionInterval,
+	}, logger.Named("storage"))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Using file storage for sensor readings",
+		zap.String("path", cfg.StoragePath),
+		zap.Duration("rawRetention", cfg.StorageRawRetention),
+		zap.Duration("minuteRetention", cfg.StorageMinuteRetention),
+		zap.Duration("hourRetention", cfg.StorageHourRetention))
+
+	return repository.NewStoredSensorRepository(store, logger.Named("repository"))
+}
@@ -0,0 +1,282 @@
+package service_test
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/alert"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/repository"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/service"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// countingRepository นับจำนวนครั้งที่ GetAllSensors ถูกเรียก และหน่วงเวลาเพื่อให้ผู้เรียกพร้อมกันซ้อนกัน
+type countingRepository struct {
+	*repository.SensorRepository
+	loads atomic.Int32
+	delay time.Duration
+}
+
+func (r *countingRepository) GetAllSensors() ([]*model.SensorModel, error) {
+	r.loads.Add(1)
+	time.Sleep(r.delay)
+	return r.SensorRepository.GetAllSensors()
+}
+
+func newService(t *testing.T, delay time.Duration) (*service.SensorService, *countingRepository) {
+	logger := zaptest.NewLogger(t)
+	repo := &countingRepository{SensorRepository: repository.NewSensorRepository(), delay: delay}
+	s := service.NewSensorService(repo, stream.NewBroker(0, 0, logger), logger)
+
+	// ไม่นับการอ่านสถานะเริ่มต้นตอนสร้าง service
+	repo.loads.Store(0)
+	return s, repo
+}
+
+// TestGetAllSensorsCoalescing ทดสอบว่าผู้เรียกพร้อมกันตอน cache ว่างทำให้โหลดจาก repository เพียงครั้งเดียว
+func TestGetAllSensorsCoalescing(t *testing.T) {
+	s, repo := newService(t, 50*time.Millisecond)
+
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, err := s.GetAllSensors()
+			assert.NoError(t, err)
+			results[i] = data
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), repo.loads.Load())
+	for _, data := range results {
+		assert.Equal(t, results[0], data)
+	}
+
+	// ครั้งถัดไปได้จาก cache
+	_, err := s.GetAllSensors()
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), repo.loads.Load())
+}
+
+// TestGetAllSensorsStaleWhileRevalidate ทดสอบว่าข้อมูลที่เกิน TTL ถูกคืนทันทีและ refresh อยู่เบื้องหลังเพียงครั้งเดียว
+func TestGetAllSensorsStaleWhileRevalidate(t *testing.T) {
+	s, repo := newService(t, 20*time.Millisecond)
+	s.SetCachePolicy(10*time.Millisecond, time.Minute)
+
+	first, err := s.GetAllSensors()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), repo.loads.Load())
+
+	time.Sleep(20 * time.Millisecond)
+
+	// ข้อมูลเกิน TTL แล้ว ผู้เรียกทุกรายได้ค่าเดิมทันทีโดยไม่ต้องรอ repository
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		data, err := s.GetAllSensors()
+		require.NoError(t, err)
+		assert.Equal(t, first, data)
+	}
+	assert.Less(t, time.Since(start), 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return repo.loads.Load() == 2
+	}, time.Second, 5*time.Millisecond)
+
+	// หลัง refresh ข้อมูลสดอีกครั้ง ไม่มีการโหลดเพิ่ม
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(2), repo.loads.Load())
+}
+
+// TestCacheInvalidatedByRepositoryWrites ทดสอบว่าการเขียนที่ไม่ได้ผ่าน service (เช่น mock loop, replay) ล้าง cache ทันที
+func TestCacheInvalidatedByRepositoryWrites(t *testing.T) {
+	s, repo := newService(t, 0)
+
+	before, err := s.GetSensorByID("temp-001")
+	require.NoError(t, err)
+	all, err := s.GetAllSensors()
+	require.NoError(t, err)
+
+	value := 42.5
+	_, err = repo.SaveReadings("temp-001", []model.ReadingModel{{Temperature: &value, Timestamp: time.Now()}})
+	require.NoError(t, err)
+
+	after, err := s.GetSensorByID("temp-001")
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+	assert.Contains(t, after, `"temperature":42.5`)
+
+	allAfter, err := s.GetAllSensors()
+	require.NoError(t, err)
+	assert.NotEqual(t, all, allAfter)
+	assert.Equal(t, int32(2), repo.loads.Load())
+}
+
+// TestCacheLoadSupersededByWrite ทดสอบว่าการโหลดที่เริ่มก่อนการเขียนไม่ถูกเก็บลง cache ทับค่าใหม่
+func TestCacheLoadSupersededByWrite(t *testing.T) {
+	s, repo := newService(t, 50*time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = s.GetAllSensors()
+	}()
+
+	// เขียนระหว่างที่การโหลดแรกยังไม่เสร็จ
+	time.Sleep(10 * time.Millisecond)
+	value := 11.5
+	_, err := repo.SaveReadings("temp-001", []model.ReadingModel{{Temperature: &value, Timestamp: time.Now()}})
+	require.NoError(t, err)
+	<-done
+
+	data, err := s.GetAllSensors()
+	require.NoError(t, err)
+	assert.Contains(t, data, `"temperature":11.5`)
+	assert.Equal(t, int32(2), repo.loads.Load())
+}
+
+// TestTenantIsolation ทดสอบว่า service ของ tenant ไม่เห็นและเขียนเซนเซอร์ของ tenant อื่นไม่ได้ ทั้งผ่าน cache และ stream
+func TestTenantIsolation(t *testing.T) {
+	s, _ := newService(t, 0)
+	acme := s.ForTenant("acme")
+	globex := s.ForTenant("globex")
+
+	_, err := acme.CreateSensor(model.SensorRegistrationModel{ID: "acme-1", Name: "Acme", Type: "temperature"})
+	require.NoError(t, err)
+
+	// globex ไม่เห็นทั้งเซนเซอร์ของ acme และเซนเซอร์จำลองของ tenant เริ่มต้น
+	globexAll, err := globex.GetAllSensors()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, globexAll)
+
+	acmeAll, err := acme.GetAllSensors()
+	require.NoError(t, err)
+	assert.Contains(t, acmeAll, `"id":"acme-1"`)
+	assert.NotContains(t, acmeAll, `"id":"temp-001"`)
+
+	defaultAll, err := s.ForTenant("").GetAllSensors()
+	require.NoError(t, err)
+	assert.Contains(t, defaultAll, `"id":"temp-001"`)
+	assert.NotContains(t, defaultAll, `"id":"acme-1"`)
+
+	// เซนเซอร์ของ tenant อื่นมีผลเหมือนไม่มีอยู่
+	value := 30.0
+	readings := []model.ReadingModel{{Temperature: &value, Timestamp: time.Now()}}
+	_, err = globex.GetSensorByID("acme-1")
+	assert.ErrorIs(t, err, apierror.ErrDataNotFound)
+	_, err = globex.IngestReadings("acme-1", readings)
+	assert.ErrorIs(t, err, apierror.ErrDataNotFound)
+	_, err = globex.PatchSensor("acme-1", model.SensorPatchModel{})
+	assert.ErrorIs(t, err, apierror.ErrDataNotFound)
+	assert.ErrorIs(t, globex.DeleteSensor("acme-1"), apierror.ErrDataNotFound)
+
+	// ID ที่ tenant อื่นใช้แล้วได้ error เดียวกับ ID ที่ tenant ตัวเองใช้แล้ว ไม่บอกว่าเป็นเซนเซอร์ของใคร
+	_, foreignErr := globex.CreateSensor(model.SensorRegistrationModel{ID: "acme-1", Name: "Globex", Type: "temperature"})
+	assert.ErrorIs(t, foreignErr, apierror.ErrDataConflict)
+	_, ownErr := acme.CreateSensor(model.SensorRegistrationModel{ID: "acme-1", Name: "Acme", Type: "temperature"})
+	require.Error(t, ownErr)
+	assert.Equal(t, ownErr.Error(), foreignErr.Error())
+
+	snapshot, err := globex.GetSnapshot()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, stripServerID(t, snapshot.Data))
+
+	// stream ของ globex ไม่ได้รับการอัปเดตของ acme ส่วน stream ของ acme ได้รับ
+	globexSub, _ := globex.Subscribe("")
+	defer globex.Unsubscribe(globexSub)
+	acmeSub, _ := acme.Subscribe("")
+	defer acme.Unsubscribe(acmeSub)
+
+	_, err = acme.IngestReadings("acme-1", readings)
+	require.NoError(t, err)
+
+	evt := <-acmeSub.Events()
+	assert.Contains(t, string(evt.Data), `"id":"acme-1"`)
+	assert.Empty(t, globexSub.Events())
+
+	// การเขียนล้าง cache ของ tenant เจ้าของเซนเซอร์
+	acmeSensor, err := acme.GetSensorByID("acme-1")
+	require.NoError(t, err)
+	assert.Contains(t, acmeSensor, `"temperature":30`)
+	assert.Contains(t, acmeSensor, `"tenant":"acme"`)
+}
+
+// TestDeleteSensorClosesAlerts ทดสอบว่าการลบเซนเซอร์ปิด alert ที่ active และแจ้ง client ว่าเซนเซอร์ถูกลบ
+func TestDeleteSensorClosesAlerts(t *testing.T) {
+	s, _ := newService(t, 0)
+
+	rule, err := alert.NewRule("hot", "temperature > 28")
+	require.NoError(t, err)
+	engine := alert.NewEngine([]alert.Rule{rule}, zaptest.NewLogger(t))
+	var states []alert.State
+	engine.AddListener(func(a alert.Alert) {
+		states = append(states, a.State)
+	})
+	s.AttachAlerts(engine)
+
+	_, err = s.CreateSensor(model.SensorRegistrationModel{ID: "temp-100", Name: "Boiler", Type: "temperature"})
+	require.NoError(t, err)
+	value := 30.0
+	_, err = s.IngestReadings("temp-100", []model.ReadingModel{{Temperature: &value, Timestamp: time.Now()}})
+	require.NoError(t, err)
+
+	alerts, err := s.GetAlerts()
+	require.NoError(t, err)
+	assert.Contains(t, alerts, `"sensor_id":"temp-100"`)
+	_, err = s.GetSensorByID("temp-100")
+	require.NoError(t, err)
+
+	sub, _ := s.Subscribe("")
+	defer s.Unsubscribe(sub)
+
+	require.NoError(t, s.DeleteSensor("temp-100"))
+
+	// alert ต้องไม่ค้างอยู่หลังลบ และผู้รับ alert ได้รับแจ้งว่า resolved
+	alerts, err = s.GetAlerts()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, alerts)
+	assert.Equal(t, []alert.State{alert.StateFiring, alert.StateResolved}, states)
+
+	// cache ของเซนเซอร์ต้องถูกล้าง
+	_, err = s.GetSensorByID("temp-100")
+	assert.ErrorIs(t, err, apierror.ErrDataNotFound)
+
+	evt := <-sub.Events()
+	assert.Equal(t, stream.EventSensorDeleted, evt.Type)
+	assert.Contains(t, string(evt.Data), `"id":"temp-100"`)
+}
+
+// stripServerID คืนค่า data ของ payload ที่ห่อด้วย server_id
+func stripServerID(t *testing.T, payload []byte) string {
+	var wrapped struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &wrapped))
+	return string(wrapped.Data)
+}
+
+// TestResyncStopsOnClose ทดสอบว่า resync หยุดกระจาย snapshot หลังจากปิด service
+func TestResyncStopsOnClose(t *testing.T) {
+	s, repo := newService(t, 0)
+	s.StartResync(5 * time.Millisecond)
+
+	require.Eventually(t, func() bool { return repo.loads.Load() > 0 }, time.Second, time.Millisecond)
+	require.NoError(t, s.Close())
+
+	// รอให้รอบที่อาจกำลังทำงานอยู่จบก่อน แล้วจึงตรวจว่าไม่มีการอ่านเพิ่ม
+	time.Sleep(20 * time.Millisecond)
+	loads := repo.loads.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, loads, repo.loads.Load())
+}
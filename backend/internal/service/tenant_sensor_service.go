@@ -0,0 +1,129 @@
+package service
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/repository"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
+)
+
+// ForTenant คืนค่า service ที่เห็นเฉพาะเซนเซอร์ของ tenant โดยใช้ cache, broker และ alert ร่วมกับ SensorService
+// tenant ว่างใช้ model.DefaultTenant เพื่อไม่ให้ request ที่ไม่ได้ระบุ tenant เห็นข้อมูลของทุก tenant
+func (s *SensorService) ForTenant(tenant string) ISensorService {
+	if tenant == "" {
+		tenant = model.DefaultTenant
+	}
+	return &tenantSensorService{service: s, tenant: tenant}
+}
+
+// tenantSensorService เป็น ISensorService ของ tenant เดียว
+// เซนเซอร์ของ tenant อื่นมีผลเหมือนไม่มีอยู่ (ErrDataNotFound) ทั้งการอ่านและการเขียน
+// repository ตรวจเจ้าของภายใต้ lock เดียวกับการเขียน เพื่อไม่ให้เขียนลงเซนเซอร์ที่ถูกลบแล้วลงทะเบียนใหม่โดย tenant อื่นระหว่างนั้น
+type tenantSensorService struct {
+	service *SensorService
+	tenant  string
+}
+
+// GetAllSensors คืนค่าข้อมูล sensor ทั้งหมดของ tenant ในรูปแบบ JSON
+func (t *tenantSensorService) GetAllSensors() (string, error) {
+	s := t.service
+	return s.cached(tenantCacheKey(t.tenant, SensorDataCacheKey), func() (string, error) {
+		sensors, err := s.repository.GetSensorsByTenant(t.tenant)
+		if err != nil {
+			s.logger.Error("Failed to get tenant sensors", zap.String("tenant", t.tenant), zap.Error(err))
+			return "", err
+		}
+
+		jsonData, err := repository.SerializeSensors(sensors)
+		if err != nil {
+			s.logger.Error("Failed to serialize sensors", zap.String("tenant", t.tenant), zap.Error(err))
+			return "", err
+		}
+		return jsonData, nil
+	})
+}
+
+// GetSensorByID คืนค่าข้อมูล sensor ของ tenant ตาม ID ในรูปแบบ JSON
+func (t *tenantSensorService) GetSensorByID(id string) (string, error) {
+	s := t.service
+	return s.cached(tenantCacheKey(t.tenant, sensorCacheKey(id)), func() (string, error) {
+		sensor, err := s.repository.GetTenantSensorByID(t.tenant, id)
+		if err != nil {
+			s.logger.Error("Failed to get sensor by ID", zap.String("id", id), zap.String("tenant", t.tenant), zap.Error(err))
+			return "", err
+		}
+
+		jsonData, err := repository.SerializeSensor(sensor)
+		if err != nil {
+			s.logger.Error("Failed to serialize sensor", zap.String("id", id), zap.Error(err))
+			return "", err
+		}
+		return jsonData, nil
+	})
+}
+
+// IngestReadings บันทึกค่าของเซนเซอร์ที่เป็นของ tenant
+func (t *tenantSensorService) IngestReadings(id string, readings []model.ReadingModel) (string, error) {
+	return t.service.ingestReadings(t.tenant, id, readings)
+}
+
+// GetSensorHistory คืนค่าข้อมูลย้อนหลังของเซนเซอร์ที่เป็นของ tenant
+func (t *tenantSensorService) GetSensorHistory(id string, query model.HistoryQuery) (string, error) {
+	return t.service.getSensorHistory(t.tenant, id, query)
+}
+
+// CreateSensor ลงทะเบียน sensor ใหม่ให้ tenant
+func (t *tenantSensorService) CreateSensor(registration model.SensorRegistrationModel) (string, error) {
+	return t.service.createSensor(t.tenant, registration)
+}
+
+// UpdateSensor แทนที่ metadata ของเซนเซอร์ที่เป็นของ tenant
+func (t *tenantSensorService) UpdateSensor(id string, registration model.SensorRegistrationModel) (string, error) {
+	return t.service.updateSensor(t.tenant, id, registration.Apply)
+}
+
+// PatchSensor แก้ไข metadata บางส่วนของเซนเซอร์ที่เป็นของ tenant
+func (t *tenantSensorService) PatchSensor(id string, patch model.SensorPatchModel) (string, error) {
+	return t.service.updateSensor(t.tenant, id, patch.Apply)
+}
+
+// DeleteSensor ลบเซนเซอร์ที่เป็นของ tenant
+func (t *tenantSensorService) DeleteSensor(id string) error {
+	return t.service.deleteSensor(t.tenant, id)
+}
+
+// GetAlerts คืนค่า alert ที่ active ของเซนเซอร์ใน tenant
+func (t *tenantSensorService) GetAlerts() (string, error) {
+	return t.service.getAlerts(t.tenant)
+}
+
+// GetSnapshot คืนค่าข้อมูลล่าสุดของเซนเซอร์ใน tenant ในรูปแบบ event
+func (t *tenantSensorService) GetSnapshot() (stream.Event, error) {
+	return t.service.snapshot(t.service.repository.GetSensorsByTenant(t.tenant))
+}
+
+// Subscribe ลงทะเบียนรับเฉพาะ event ที่มีข้อมูลของ tenant
+func (t *tenantSensorService) Subscribe(lastEventID string) (*stream.Subscriber, stream.Replay) {
+	return t.service.broker.SubscribeTenant(lastEventID, t.tenant)
+}
+
+// Unsubscribe ยกเลิกการรับ event
+func (t *tenantSensorService) Unsubscribe(sub *stream.Subscriber) {
+	t.service.Unsubscribe(sub)
+}
+
+// ServerID คืนค่า ID ของ server ที่ใส่ไว้ใน payload ของ stream
+func (t *tenantSensorService) ServerID() string {
+	return t.service.ServerID()
+}
+
+// ForTenant คืนค่า service ของ tenant ที่ระบุ
+func (t *tenantSensorService) ForTenant(tenant string) ISensorService {
+	return t.service.ForTenant(tenant)
+}
+
+// Close ไม่ทำอะไร เพราะ repository เป็นของ SensorService ที่ใช้ร่วมกันทุก tenant
+func (t *tenantSensorService) Close() error {
+	return nil
+}
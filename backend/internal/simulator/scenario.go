@@ -0,0 +1,173 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// DefaultInterval คือระยะเวลาระหว่างการส่งข้อมูลเมื่อ scenario ไม่ได้กำหนด
+const DefaultInterval = 2 * time.Second
+
+var validate = validator.New()
+
+// Scenario กำหนดเซนเซอร์ที่จะจำลองและรูปแบบของค่าที่สร้าง
+// seed เดียวกันกับลำดับเวลาเดียวกันจะได้ค่าเดิมทุกครั้ง
+type Scenario struct {
+	Seed     int64
+	Interval time.Duration
+	Groups   []Group
+}
+
+// Group คือกลุ่มเซนเซอร์ชนิดเดียวกันที่ใช้รูปแบบค่าเดียวกัน
+// ระบุ IDs เพื่อจำลองเซนเซอร์ที่มีอยู่แล้ว หรือ Count เพื่อสร้างเซนเซอร์ใหม่ชื่อ <IDPrefix>-001, -002, ...
+type Group struct {
+	Type            string
+	IDs             []string
+	Count           int
+	IDPrefix        string
+	Name            string
+	Tags            []string
+	Dropout         float64
+	DropoutDuration time.Duration
+	Metrics         map[string]Pattern
+}
+
+// Pattern กำหนดรูปแบบค่าของ metric หนึ่ง
+// ค่า = Baseline + random walk + รอบรายวัน (sine) + noise + spike แล้วจำกัดให้อยู่ในช่วงของชนิดเซนเซอร์
+type Pattern struct {
+	Baseline         float64 `json:"baseline" yaml:"baseline"`
+	RandomWalk       float64 `json:"random_walk" yaml:"random_walk" validate:"gte=0"`
+	DailyAmplitude   float64 `json:"daily_amplitude" yaml:"daily_amplitude" validate:"gte=0"`
+	PeakHour         float64 `json:"peak_hour" yaml:"peak_hour" validate:"gte=0,lt=24"`
+	Noise            float64 `json:"noise" yaml:"noise" validate:"gte=0"`
+	SpikeProbability float64 `json:"spike_probability" yaml:"spike_probability" validate:"gte=0,lte=1"`
+	SpikeMagnitude   float64 `json:"spike_magnitude" yaml:"spike_magnitude"`
+}
+
+// scenarioFile คือรูปแบบของไฟล์ scenario ที่ระยะเวลาเป็น string เช่น "2s", "5m"
+type scenarioFile struct {
+	Seed     int64       `json:"seed" yaml:"seed"`
+	Interval string      `json:"interval" yaml:"interval"`
+	Sensors  []groupFile `json:"sensors" yaml:"sensors" validate:"min=1,dive"`
+}
+
+type groupFile struct {
+	Type            string             `json:"type" yaml:"type" validate:"required"`
+	IDs             []string           `json:"ids" yaml:"ids" validate:"required_without=Count,dive,required"`
+	Count           int                `json:"count" yaml:"count" validate:"gte=0,lte=10000"`
+	IDPrefix        string             `json:"id_prefix" yaml:"id_prefix"`
+	Name            string             `json:"name" yaml:"name"`
+	Tags            []string           `json:"tags" yaml:"tags"`
+	Dropout         float64            `json:"dropout" yaml:"dropout" validate:"gte=0,lte=1"`
+	DropoutDuration string             `json:"dropout_duration" yaml:"dropout_duration"`
+	Metrics         map[string]Pattern `json:"metrics" yaml:"metrics" validate:"dive"`
+}
+
+// LoadScenario อ่าน scenario จากไฟล์ JSON หรือ YAML (เลือกตามนามสกุลไฟล์)
+func LoadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, apierror.Wrap(apierror.ErrConfigNotFound, fmt.Sprintf("simulator scenario %s: %v", path, err))
+	}
+
+	var file scenarioFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return Scenario{}, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("simulator scenario %s: %v", path, err))
+	}
+
+	return file.compile()
+}
+
+// compile ตรวจสอบและแปลงไฟล์ scenario เป็น Scenario
+func (f scenarioFile) compile() (Scenario, error) {
+	if err := validate.Struct(f); err != nil {
+		return Scenario{}, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("simulator scenario: %v", err))
+	}
+
+	scenario := Scenario{Seed: f.Seed, Interval: DefaultInterval}
+	if f.Interval != "" {
+		interval, err := time.ParseDuration(f.Interval)
+		if err != nil || interval <= 0 {
+			return Scenario{}, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("simulator scenario: invalid interval %q", f.Interval))
+		}
+		scenario.Interval = interval
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range f.Sensors {
+		sensorType, ok := model.LookupSensorType(entry.Type)
+		if !ok {
+			return Scenario{}, groupError(i, fmt.Sprintf("unknown sensor type %s", entry.Type))
+		}
+		for metric := range entry.Metrics {
+			if _, ok := sensorType.Metric(metric); !ok {
+				return Scenario{}, groupError(i, fmt.Sprintf("metric %s is not supported by sensor type %s", metric, entry.Type))
+			}
+		}
+
+		group := Group{
+			Type:     entry.Type,
+			IDs:      entry.IDs,
+			Count:    entry.Count,
+			IDPrefix: entry.IDPrefix,
+			Name:     entry.Name,
+			Tags:     entry.Tags,
+			Dropout:  entry.Dropout,
+			Metrics:  entry.Metrics,
+		}
+		if group.IDPrefix == "" {
+			group.IDPrefix = "sim-" + entry.Type
+		}
+		if entry.DropoutDuration != "" {
+			duration, err := time.ParseDuration(entry.DropoutDuration)
+			if err != nil {
+				return Scenario{}, groupError(i, fmt.Sprintf("invalid dropout_duration %q", entry.DropoutDuration))
+			}
+			group.DropoutDuration = duration
+		}
+
+		for _, id := range group.sensorIDs() {
+			if seen[id] {
+				return Scenario{}, groupError(i, fmt.Sprintf("duplicate sensor id %s", id))
+			}
+			seen[id] = true
+		}
+
+		scenario.Groups = append(scenario.Groups, group)
+	}
+
+	return scenario, nil
+}
+
+// sensorIDs คืนค่า ID ของเซนเซอร์ทั้งหมดในกลุ่ม
+func (g Group) sensorIDs() []string {
+	if len(g.IDs) > 0 {
+		return g.IDs
+	}
+	ids := make([]string, g.Count)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s-%03d", g.IDPrefix, i+1)
+	}
+	return ids
+}
+
+// groupError สร้าง error สำหรับกลุ่มเซนเซอร์ที่ไม่ถูกต้องใน scenario
+func groupError(index int, message string) error {
+	return apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("simulator scenario: sensors #%d: %s", index+1, message))
+}
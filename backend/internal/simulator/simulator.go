@@ -0,0 +1,267 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// walkReversion คือสัดส่วนที่ random walk ถูกดึงกลับหา baseline ทุกครั้ง เพื่อไม่ให้ค่าลอยออกไปเรื่อย ๆ
+const walkReversion = 0.05
+
+// Target คือปลายทางที่ simulator ลงทะเบียนเซนเซอร์และเขียน reading เข้าไป (SensorService)
+type Target interface {
+	GetSensorByID(id string) (string, error)
+	CreateSensor(registration model.SensorRegistrationModel) (string, error)
+	IngestReadings(id string, readings []model.ReadingModel) (string, error)
+}
+
+// Sample คือ reading ที่ simulator สร้างให้เซนเซอร์หนึ่งตัวในหนึ่งรอบ
+type Sample struct {
+	SensorID string
+	Reading  model.ReadingModel
+}
+
+// Simulator สร้างข้อมูลเซนเซอร์ตาม Scenario และเขียนผ่าน Target ทุก Interval
+type Simulator struct {
+	scenario Scenario
+	seed     int64
+	sensors  []*simSensor
+	target   Target
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// simSensor เก็บสถานะของเซนเซอร์จำลองหนึ่งตัว แต่ละตัวมี random source ของตัวเอง
+// เพื่อให้ค่าของเซนเซอร์ไม่ขึ้นกับลำดับหรือจำนวนของเซนเซอร์อื่นใน scenario
+type simSensor struct {
+	id         string
+	name       string
+	group      Group
+	sensorType model.SensorType
+	rng        *rand.Rand
+	walk       map[string]float64
+	downUntil  time.Time
+}
+
+// New สร้าง simulator จาก scenario ถ้า Seed เป็น 0 จะสุ่ม seed และบันทึกไว้ใน log เพื่อให้ทำซ้ำได้
+func New(scenario Scenario, target Target, logger *zap.Logger) *Simulator {
+	seed := scenario.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	s := &Simulator{
+		scenario: scenario,
+		seed:     seed,
+		target:   target,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+
+	for _, group := range scenario.Groups {
+		// scenario ผ่านการตรวจสอบชนิดเซนเซอร์แล้วใน LoadScenario
+		sensorType, _ := model.LookupSensorType(group.Type)
+		for i, id := range group.sensorIDs() {
+			s.sensors = append(s.sensors, &simSensor{
+				id:         id,
+				name:       sensorName(group, i),
+				group:      group,
+				sensorType: sensorType,
+				rng:        rand.New(rand.NewSource(seed ^ int64(hashID(id)))),
+				walk:       make(map[string]float64),
+			})
+		}
+	}
+
+	return s
+}
+
+// Seed คืนค่า seed ที่ใช้จริง
+func (s *Simulator) Seed() int64 {
+	return s.seed
+}
+
+// Start ลงทะเบียนเซนเซอร์ที่ยังไม่มีแล้วเริ่มส่งข้อมูลทุก Interval
+func (s *Simulator) Start() error {
+	if err := s.register(); err != nil {
+		return err
+	}
+
+	s.logger.Info("Simulator started",
+		zap.Int64("seed", s.seed),
+		zap.Int("sensors", len(s.sensors)),
+		zap.Duration("interval", s.scenario.Interval))
+
+	go s.loop()
+	return nil
+}
+
+// Stop หยุดส่งข้อมูล
+func (s *Simulator) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// Tick สร้าง reading ของทุกเซนเซอร์ที่ไม่ได้อยู่ในช่วง dropout ณ เวลา now เรียงตามลำดับใน scenario
+func (s *Simulator) Tick(now time.Time) []Sample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := make([]Sample, 0, len(s.sensors))
+	for _, sensor := range s.sensors {
+		values, ok := sensor.next(now, s.scenario.Interval)
+		if !ok {
+			continue
+		}
+		samples = append(samples, Sample{
+			SensorID: sensor.id,
+			Reading:  model.ReadingModel{Metrics: values, Timestamp: now},
+		})
+	}
+	return samples
+}
+
+// loop ส่งข้อมูลทุก Interval จนกว่าจะถูกหยุด
+func (s *Simulator) loop() {
+	ticker := time.NewTicker(s.scenario.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			for _, sample := range s.Tick(now) {
+				if _, err := s.target.IngestReadings(sample.SensorID, []model.ReadingModel{sample.Reading}); err != nil {
+					s.logger.Warn("Failed to write simulated reading", zap.String("id", sample.SensorID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// register สร้างเซนเซอร์ที่ยังไม่มีใน registry เซนเซอร์ที่มีอยู่แล้วต้องเป็นชนิดเดียวกับใน scenario
+func (s *Simulator) register() error {
+	for _, sensor := range s.sensors {
+		existing, err := s.target.GetSensorByID(sensor.id)
+		if err == nil {
+			var current model.SensorModel
+			if err := json.Unmarshal([]byte(existing), &current); err != nil {
+				return err
+			}
+			if current.Type != sensor.group.Type {
+				return apierror.Wrap(apierror.ErrInvalidConfig,
+					fmt.Sprintf("simulator scenario: sensor %s is type %s, not %s", sensor.id, current.Type, sensor.group.Type))
+			}
+			continue
+		}
+		if !errors.Is(err, apierror.ErrDataNotFound) {
+			return err
+		}
+
+		_, err = s.target.CreateSensor(model.SensorRegistrationModel{
+			ID:   sensor.id,
+			Name: sensor.name,
+			Type: sensor.group.Type,
+			Tags: sensor.group.Tags,
+		})
+		if err != nil && !errors.Is(err, apierror.ErrDataConflict) {
+			return err
+		}
+	}
+	return nil
+}
+
+// next สร้างค่าของทุก metric ของเซนเซอร์ ณ เวลา now คืนค่า false ถ้าเซนเซอร์อยู่ในช่วง dropout
+func (s *simSensor) next(now time.Time, interval time.Duration) (map[string]float64, bool) {
+	if now.Before(s.downUntil) {
+		return nil, false
+	}
+	if s.group.Dropout > 0 && s.rng.Float64() < s.group.Dropout {
+		duration := s.group.DropoutDuration
+		if duration < interval {
+			duration = interval
+		}
+		s.downUntil = now.Add(duration)
+		return nil, false
+	}
+
+	values := make(map[string]float64, len(s.sensorType.Metrics))
+	for _, spec := range s.sensorType.Metrics {
+		pattern, ok := s.group.Metrics[spec.Name]
+		if !ok {
+			pattern = defaultPattern(spec)
+		}
+		values[spec.Name] = s.value(spec, pattern, now)
+	}
+	return values, true
+}
+
+// value คำนวณค่าของ metric หนึ่งตาม pattern และจำกัดให้อยู่ในช่วงของชนิดเซนเซอร์
+func (s *simSensor) value(spec model.MetricSpec, pattern Pattern, now time.Time) float64 {
+	walk := s.walk[spec.Name] + s.rng.NormFloat64()*pattern.RandomWalk
+	walk -= walk * walkReversion
+	s.walk[spec.Name] = walk
+
+	value := pattern.Baseline + walk
+
+	// รอบรายวันมีค่าสูงสุดที่ PeakHour (เวลา UTC) เพื่อให้ผลลัพธ์ไม่ขึ้นกับ timezone ของเครื่อง
+	if pattern.DailyAmplitude > 0 {
+		utc := now.UTC()
+		hour := float64(utc.Hour()) + float64(utc.Minute())/60 + float64(utc.Second())/3600
+		value += pattern.DailyAmplitude * math.Cos(2*math.Pi*(hour-pattern.PeakHour)/24)
+	}
+
+	if pattern.Noise > 0 {
+		value += s.rng.NormFloat64() * pattern.Noise
+	}
+
+	if pattern.SpikeProbability > 0 && s.rng.Float64() < pattern.SpikeProbability {
+		if s.rng.Intn(2) == 0 {
+			value += pattern.SpikeMagnitude
+		} else {
+			value -= pattern.SpikeMagnitude
+		}
+	}
+
+	return math.Max(spec.Min, math.Min(spec.Max, value))
+}
+
+// defaultPattern ใช้กับ metric ที่ไม่ได้กำหนดใน scenario ให้ค่าขยับเล็กน้อยรอบกึ่งกลางของช่วงค่า
+func defaultPattern(spec model.MetricSpec) Pattern {
+	return Pattern{
+		Baseline:   (spec.Min + spec.Max) / 2,
+		RandomWalk: (spec.Max - spec.Min) * 0.005,
+	}
+}
+
+// sensorName สร้างชื่อของเซนเซอร์ลำดับที่ index ในกลุ่ม
+func sensorName(group Group, index int) string {
+	name := group.Name
+	if name == "" {
+		name = "Simulated " + group.Type
+	}
+	return fmt.Sprintf("%s %d", name, index+1)
+}
+
+// hashID แปลง ID เป็นตัวเลขสำหรับผสมกับ seed
+func hashID(id string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	return h.Sum64()
+}
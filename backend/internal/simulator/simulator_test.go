@@ -0,0 +1,173 @@
+package simulator_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/simulator"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// fakeTarget จำลอง registry ที่มีเซนเซอร์ temp-001 อยู่แล้ว
+type fakeTarget struct {
+	created []model.SensorRegistrationModel
+}
+
+func (f *fakeTarget) GetSensorByID(id string) (string, error) {
+	if id == "temp-001" {
+		return `{"id":"temp-001","type":"temperature"}`, nil
+	}
+	return "", apierror.Wrap(apierror.ErrDataNotFound, "sensor with ID "+id+" not found")
+}
+
+func (f *fakeTarget) CreateSensor(registration model.SensorRegistrationModel) (string, error) {
+	f.created = append(f.created, registration)
+	return `{}`, nil
+}
+
+func (f *fakeTarget) IngestReadings(id string, readings []model.ReadingModel) (string, error) {
+	return `{}`, nil
+}
+
+func writeScenario(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+const scenarioYAML = `
+seed: 7
+interval: 1s
+sensors:
+  - type: temperature
+    ids: [temp-001]
+    metrics:
+      temperature: {baseline: 24, random_walk: 0.2, daily_amplitude: 3, peak_hour: 14, noise: 0.1}
+  - type: co2
+    count: 2
+    id_prefix: room-co2
+    name: Room CO2
+    metrics:
+      co2: {baseline: 9990, noise: 50, spike_probability: 1, spike_magnitude: 5000}
+`
+
+func run(t *testing.T, scenario simulator.Scenario, ticks int) [][]simulator.Sample {
+	sim := simulator.New(scenario, &fakeTarget{}, zaptest.NewLogger(t))
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var out [][]simulator.Sample
+	for i := 0; i < ticks; i++ {
+		out = append(out, sim.Tick(start.Add(time.Duration(i)*scenario.Interval)))
+	}
+	return out
+}
+
+func TestSimulatorDeterministic(t *testing.T) {
+	scenario, err := simulator.LoadScenario(writeScenario(t, "scenario.yaml", scenarioYAML))
+	require.NoError(t, err)
+
+	// seed เดียวกันต้องได้ค่าเดิมทุกครั้ง
+	first := run(t, scenario, 20)
+	second := run(t, scenario, 20)
+	assert.Equal(t, first, second)
+
+	// seed ต่างกันต้องได้ค่าต่างกัน
+	scenario.Seed = 8
+	assert.NotEqual(t, first, run(t, scenario, 20))
+
+	require.Len(t, first[0], 3)
+	assert.Equal(t, "temp-001", first[0][0].SensorID)
+	assert.Equal(t, "room-co2-001", first[0][1].SensorID)
+	assert.Equal(t, "room-co2-002", first[0][2].SensorID)
+
+	// metric ที่ไม่ได้กำหนด pattern (battery) ต้องมีค่า และทุกค่าต้องอยู่ในช่วงของชนิดเซนเซอร์
+	co2, _ := model.LookupSensorType("co2")
+	for _, samples := range first {
+		for _, sample := range samples[1:] {
+			assert.Contains(t, sample.Reading.Metrics, "battery")
+			assert.NoError(t, co2.ValidateValues(sample.Reading.Metrics))
+		}
+	}
+}
+
+func TestSimulatorDailyCycle(t *testing.T) {
+	scenario := simulator.Scenario{
+		Seed:     1,
+		Interval: time.Hour,
+		Groups: []simulator.Group{{
+			Type: "temperature",
+			IDs:  []string{"t1"},
+			Metrics: map[string]simulator.Pattern{
+				"temperature": {Baseline: 20, DailyAmplitude: 5, PeakHour: 14},
+			},
+		}},
+	}
+
+	// ไม่มี random walk และ noise ค่าต้องเป็นไปตามรอบรายวันพอดี
+	samples := run(t, scenario, 24)
+	assert.InDelta(t, 25.0, samples[14][0].Reading.Metrics["temperature"], 1e-9)
+	assert.InDelta(t, 15.0, samples[2][0].Reading.Metrics["temperature"], 1e-9)
+}
+
+func TestSimulatorDropout(t *testing.T) {
+	scenario := simulator.Scenario{
+		Seed:     1,
+		Interval: time.Second,
+		Groups: []simulator.Group{{
+			Type:            "humidity",
+			IDs:             []string{"h1"},
+			Dropout:         1,
+			DropoutDuration: 5 * time.Second,
+		}},
+	}
+
+	// dropout ทุกรอบ ต้องไม่มีข้อมูลเลย
+	for _, samples := range run(t, scenario, 10) {
+		assert.Empty(t, samples)
+	}
+}
+
+func TestSimulatorStartRegistersSensors(t *testing.T) {
+	scenario, err := simulator.LoadScenario(writeScenario(t, "scenario.yaml", scenarioYAML))
+	require.NoError(t, err)
+
+	target := &fakeTarget{}
+	sim := simulator.New(scenario, target, zaptest.NewLogger(t))
+	require.NoError(t, sim.Start())
+	sim.Stop()
+
+	// temp-001 มีอยู่แล้ว จึงสร้างเฉพาะเซนเซอร์ CO2
+	require.Len(t, target.created, 2)
+	assert.Equal(t, "room-co2-001", target.created[0].ID)
+	assert.Equal(t, "Room CO2 1", target.created[0].Name)
+	assert.Equal(t, "co2", target.created[0].Type)
+}
+
+func TestLoadScenarioInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "no_sensors", content: `{"seed": 1, "sensors": []}`},
+		{name: "unknown_type", content: `{"sensors": [{"type": "radiation", "count": 1}]}`},
+		{name: "unsupported_metric", content: `{"sensors": [{"type": "humidity", "count": 1, "metrics": {"co2": {"baseline": 1}}}]}`},
+		{name: "bad_interval", content: `{"interval": "soon", "sensors": [{"type": "humidity", "count": 1}]}`},
+		{name: "duplicate_ids", content: `{"sensors": [{"type": "humidity", "ids": ["a"]}, {"type": "temperature", "ids": ["a"]}]}`},
+		{name: "missing_ids_and_count", content: `{"sensors": [{"type": "humidity"}]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := simulator.LoadScenario(writeScenario(t, "scenario.json", tc.content))
+			assert.True(t, errors.Is(err, apierror.ErrInvalidConfig), err)
+		})
+	}
+}
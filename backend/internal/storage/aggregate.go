@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// Stats คือค่าสรุปของ metric หนึ่งในช่วงเวลาหนึ่ง
+type Stats struct {
+	Count int     `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Last  float64 `json:"last"`
+}
+
+// Bucket คือค่าสรุปของทุก metric ของเซนเซอร์หนึ่งตัวในช่วง [Start, Start+step)
+type Bucket struct {
+	Start   time.Time        `json:"start"`
+	Metrics map[string]Stats `json:"metrics"`
+}
+
+// Rollup คือ bucket ที่ถูกบันทึกลง tier แบบสรุป พร้อม ID ของเซนเซอร์
+type Rollup struct {
+	SensorID string `json:"sensor_id"`
+	Bucket
+}
+
+// Avg คืนค่าเฉลี่ยของ metric
+func (s Stats) Avg() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// add รวมค่าหนึ่งค่าเข้ากับ Stats ค่าต้องถูกส่งเข้ามาตามลำดับเวลาเพื่อให้ Last ถูกต้อง
+func (s Stats) add(v float64) Stats {
+	return s.merge(Stats{Count: 1, Sum: v, Min: v, Max: v, Last: v})
+}
+
+// merge รวม Stats สองชุด โดย other ต้องเป็นช่วงเวลาที่ตามหลัง s
+func (s Stats) merge(other Stats) Stats {
+	if other.Count == 0 {
+		return s
+	}
+	if s.Count == 0 {
+		return other
+	}
+
+	s.Count += other.Count
+	s.Sum += other.Sum
+	if other.Min < s.Min {
+		s.Min = other.Min
+	}
+	if other.Max > s.Max {
+		s.Max = other.Max
+	}
+	s.Last = other.Last
+	return s
+}
+
+// Bucketize จัดกลุ่ม record (เรียงตามเวลาแล้ว) เป็นช่วงละ step โดยนับจาก from
+// ช่วงที่ไม่มีข้อมูลจะไม่ถูกสร้าง
+func Bucketize(records []Record, from time.Time, step time.Duration) []Bucket {
+	return bucketizeRollups(recordRollups(records), from, step)
+}
+
+// recordRollups แปลง record แต่ละตัวเป็น rollup ที่มีค่าเดียว
+func recordRollups(records []Record) []Rollup {
+	rollups := make([]Rollup, 0, len(records))
+	for _, record := range records {
+		metrics := make(map[string]Stats, len(record.Values))
+		for name, v := range record.Values {
+			metrics[name] = Stats{}.add(v)
+		}
+		rollups = append(rollups, Rollup{
+			SensorID: record.SensorID,
+			Bucket:   Bucket{Start: record.Timestamp, Metrics: metrics},
+		})
+	}
+	return rollups
+}
+
+// bucketizeRollups รวม rollup (เรียงตามเวลาแล้ว) ของเซนเซอร์เดียวเป็นช่วงละ step โดยนับจาก from
+func bucketizeRollups(rollups []Rollup, from time.Time, step time.Duration) []Bucket {
+	byStart := make(map[int64]*Bucket)
+	for _, rollup := range rollups {
+		start := bucketStart(rollup.Start, from, step)
+		bucket, ok := byStart[start.UnixNano()]
+		if !ok {
+			bucket = &Bucket{Start: start, Metrics: make(map[string]Stats)}
+			byStart[start.UnixNano()] = bucket
+		}
+
+		for name, stats := range rollup.Metrics {
+			bucket.Metrics[name] = bucket.Metrics[name].merge(stats)
+		}
+	}
+
+	buckets := make([]Bucket, 0, len(byStart))
+	for _, bucket := range byStart {
+		buckets = append(buckets, *bucket)
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+
+	return buckets
+}
+
+// compactRollups รวม rollup (เรียงตามเวลาแล้ว) ของหลายเซนเซอร์เป็นช่วงละ resolution
+// โดยจัดแนวช่วงเวลาตาม UTC ผลลัพธ์เรียงตามเซนเซอร์และเวลา
+func compactRollups(rollups []Rollup, resolution time.Duration) []Rollup {
+	type key struct {
+		sensorID string
+		start    int64
+	}
+
+	byKey := make(map[key]*Rollup)
+	for _, rollup := range rollups {
+		start := rollup.Start.UTC().Truncate(resolution)
+		k := key{sensorID: rollup.SensorID, start: start.UnixNano()}
+		compacted, ok := byKey[k]
+		if !ok {
+			compacted = &Rollup{
+				SensorID: rollup.SensorID,
+				Bucket:   Bucket{Start: start, Metrics: make(map[string]Stats)},
+			}
+			byKey[k] = compacted
+		}
+
+		for name, stats := range rollup.Metrics {
+			compacted.Metrics[name] = compacted.Metrics[name].merge(stats)
+		}
+	}
+
+	result := make([]Rollup, 0, len(byKey))
+	for _, rollup := range byKey {
+		result = append(result, *rollup)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SensorID != result[j].SensorID {
+			return result[i].SensorID < result[j].SensorID
+		}
+		return result[i].Start.Before(result[j].Start)
+	})
+
+	return result
+}
+
+// sortRollups เรียง rollup ตามเวลาเริ่มต้น
+func sortRollups(rollups []Rollup) {
+	sort.SliceStable(rollups, func(i, j int) bool {
+		return rollups[i].Start.Before(rollups[j].Start)
+	})
+}
+
+// bucketStart คืนค่าเวลาเริ่มต้นของช่วงที่ t อยู่
+func bucketStart(t time.Time, from time.Time, step time.Duration) time.Time {
+	offset := t.Sub(from)
+	return from.Add(offset - offset%step)
+}
@@ -0,0 +1,39 @@
+package storage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/storage"
+)
+
+func TestBucketize(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []storage.Record{
+		{SensorID: "combined-001", Timestamp: from.Add(10 * time.Second), Values: map[string]float64{"temperature": 20, "humidity": 40}},
+		{SensorID: "combined-001", Timestamp: from.Add(20 * time.Second), Values: map[string]float64{"temperature": 26}},
+		{SensorID: "combined-001", Timestamp: from.Add(50 * time.Second), Values: map[string]float64{"temperature": 23}},
+		{SensorID: "combined-001", Timestamp: from.Add(3 * time.Minute), Values: map[string]float64{"temperature": 30}},
+	}
+
+	buckets := storage.Bucketize(records, from, time.Minute)
+
+	// bucket ที่ไม่มีข้อมูล (นาทีที่ 1-2) ต้องไม่ถูกสร้าง
+	require.Len(t, buckets, 2)
+	assert.True(t, buckets[0].Start.Equal(from))
+	assert.True(t, buckets[1].Start.Equal(from.Add(3*time.Minute)))
+
+	temp := buckets[0].Metrics["temperature"]
+	assert.Equal(t, 3, temp.Count)
+	assert.Equal(t, 20.0, temp.Min)
+	assert.Equal(t, 26.0, temp.Max)
+	assert.Equal(t, 23.0, temp.Last)
+	assert.InDelta(t, 23.0, temp.Avg(), 0.001)
+
+	humidity := buckets[0].Metrics["humidity"]
+	assert.Equal(t, 1, humidity.Count)
+	assert.Equal(t, 40.0, humidity.Avg())
+}
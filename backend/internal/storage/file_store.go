@@ -0,0 +1,645 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+)
+
+const (
+	// segmentDateFormat คือรูปแบบชื่อไฟล์ segment รายวัน (UTC)
+	segmentDateFormat = "2006-01-02"
+
+	// segmentExt คือนามสกุลไฟล์ segment ที่เก็บข้อมูลแบบ JSON หนึ่งบรรทัดต่อหนึ่ง record
+	segmentExt = ".jsonl"
+
+	// stateFile คือไฟล์ snapshot สถานะล่าสุดของเซนเซอร์ทั้งหมด
+	stateFile = "state.json"
+
+	// forgottenFile คือไฟล์ที่เก็บเวลาที่ข้อมูลของเซนเซอร์ที่ถูกลบสิ้นสุด (ดู Forget)
+	forgottenFile = "forgotten.json"
+
+	// DefaultCompactionInterval คือระยะเวลาเริ่มต้นระหว่างการ compact และลบข้อมูลที่หมดอายุ
+	DefaultCompactionInterval = 10 * time.Minute
+)
+
+// Record คือ reading หนึ่งค่าที่ถูกบันทึกลง storage
+type Record struct {
+	SensorID  string             `json:"sensor_id"`
+	Timestamp time.Time          `json:"timestamp"`
+	Values    map[string]float64 `json:"values"`
+}
+
+// RetentionPolicy กำหนดระยะเวลาเก็บข้อมูลของแต่ละ tier (0 คือเก็บตลอดไป)
+type RetentionPolicy struct {
+	// Raw คือระยะเวลาเก็บ reading ดิบ
+	Raw time.Duration
+
+	// Minute คือระยะเวลาเก็บ rollup รายนาที
+	Minute time.Duration
+
+	// Hour คือระยะเวลาเก็บ rollup รายชั่วโมง
+	Hour time.Duration
+
+	// CompactionInterval คือระยะเวลาระหว่างการ compact และลบข้อมูลที่หมดอายุ (0 คือไม่ทำงานเบื้องหลัง)
+	CompactionInterval time.Duration
+}
+
+// tier คือระดับความละเอียดของข้อมูล แต่ละ tier เก็บเป็นไฟล์รายวันในไดเรกทอรีของตัวเอง
+type tier struct {
+	name       string
+	resolution time.Duration
+	retention  time.Duration
+}
+
+// state คือรูปแบบของไฟล์ state.json
+type state struct {
+	SavedAt time.Time            `json:"saved_at"`
+	Sensors []*model.SensorModel `json:"sensors"`
+}
+
+// FileStore เก็บ reading แบบ append-only ลงไฟล์ segment รายวัน
+// และสรุปเป็น rollup รายนาทีและรายชั่วโมงเมื่อสิ้นวัน แต่ละ tier ถูกลบตาม retention ของตัวเอง
+type FileStore struct {
+	dir    string
+	tiers  []tier
+	mu     sync.Mutex
+	done   chan struct{}
+	logger *zap.Logger
+
+	// forgotten คือเวลาสุดท้ายของข้อมูลที่ถูกซ่อนของแต่ละเซนเซอร์ (ดู Forget)
+	forgotten map[string]time.Time
+	forgetMu  sync.RWMutex
+}
+
+// OpenFileStore เปิด (หรือสร้าง) store ในไดเรกทอรีที่กำหนด และเริ่ม goroutine สำหรับ compact และลบข้อมูลที่หมดอายุ
+func OpenFileStore(dir string, policy RetentionPolicy, logger *zap.Logger) (*FileStore, error) {
+	store := &FileStore{
+		dir: dir,
+		tiers: []tier{
+			{name: "raw", retention: policy.Raw},
+			{name: "rollup-1m", resolution: time.Minute, retention: policy.Minute},
+			{name: "rollup-1h", resolution: time.Hour, retention: policy.Hour},
+		},
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+
+	for _, t := range store.tiers {
+		if err := os.MkdirAll(store.tierDir(t), 0o755); err != nil {
+			return nil, fmt.Errorf("create storage directory: %w", err)
+		}
+	}
+
+	forgotten, err := store.loadForgotten()
+	if err != nil {
+		return nil, err
+	}
+	store.forgotten = forgotten
+
+	if policy.CompactionInterval > 0 {
+		go store.startCompactor(policy.CompactionInterval)
+	}
+
+	return store, nil
+}
+
+// Append เขียน record ต่อท้าย segment ตามวันของ timestamp แต่ละ record
+func (s *FileStore) Append(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	// จัดกลุ่มตามวัน เพื่อเปิดแต่ละไฟล์เพียงครั้งเดียว
+	byDay := make(map[string][]Record)
+	for _, record := range records {
+		day := record.Timestamp.UTC().Format(segmentDateFormat)
+		byDay[day] = append(byDay[day], record)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for day, dayRecords := range byDay {
+		if err := appendLines(s.segmentPath(s.tiers[0], day), dayRecords); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Query คืนค่า reading ดิบของเซนเซอร์ในช่วง [from, to) เรียงตามเวลา
+func (s *FileStore) Query(sensorID string, from time.Time, to time.Time) ([]Record, error) {
+	var result []Record
+	for _, day := range daysBetween(from, to) {
+		err := scanLines(s.segmentPath(s.tiers[0], day), func(record Record) {
+			if record.SensorID == sensorID && !record.Timestamp.Before(from) && record.Timestamp.Before(to) && s.visible(record.SensorID, record.Timestamp) {
+				result = append(result, record)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sortRecords(result)
+	return result, nil
+}
+
+// History คืนค่าข้อมูลของเซนเซอร์ในช่วง [from, to) สรุปเป็น bucket ละ step
+// แต่ละวันจะอ่านจาก tier ที่หยาบที่สุดซึ่งยังละเอียดพอสำหรับ step และมีข้อมูลของวันนั้นอยู่
+func (s *FileStore) History(sensorID string, from time.Time, to time.Time, step time.Duration) ([]Bucket, error) {
+	var rollups []Rollup
+	for _, day := range daysBetween(from, to) {
+		t, ok := s.pickTier(day, step)
+		if !ok {
+			continue
+		}
+
+		dayRollups, err := s.readTierDay(t, day, sensorID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rollup := range dayRollups {
+			// rollup ที่คาบเกี่ยวกับ from จะถูกนับรวมใน bucket แรก
+			if !rollup.Start.Add(t.resolution).After(from) || !rollup.Start.Before(to) {
+				continue
+			}
+			if rollup.Start.Before(from) {
+				rollup.Start = from
+			}
+			rollups = append(rollups, rollup)
+		}
+	}
+
+	sortRollups(rollups)
+	return bucketizeRollups(rollups, from, step), nil
+}
+
+// Since คืนค่า reading ดิบทั้งหมดที่มี timestamp ตั้งแต่ from เป็นต้นไป ใช้สำหรับกู้คืนสถานะตอนเริ่มต้น
+func (s *FileStore) Since(from time.Time) ([]Record, error) {
+	days, err := s.tierDays(s.tiers[0])
+	if err != nil {
+		return nil, err
+	}
+
+	fromDay := from.UTC().Format(segmentDateFormat)
+
+	var result []Record
+	for _, day := range days {
+		if day < fromDay {
+			continue
+		}
+
+		err := scanLines(s.segmentPath(s.tiers[0], day), func(record Record) {
+			if !record.Timestamp.Before(from) && s.visible(record.SensorID, record.Timestamp) {
+				result = append(result, record)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sortRecords(result)
+	return result, nil
+}
+
+// Compact สรุปข้อมูลของวันที่จบไปแล้วจาก tier ที่ละเอียดกว่าไปยัง tier ถัดไป
+// วันที่ถูกเขียนข้อมูลเพิ่มหลังจาก compact แล้ว (เช่นอุปกรณ์ส่งข้อมูลย้อนหลัง) จะถูกสรุปใหม่
+func (s *FileStore) Compact(now time.Time) error {
+	today := now.UTC().Format(segmentDateFormat)
+
+	for i := 1; i < len(s.tiers); i++ {
+		src, dst := s.tiers[i-1], s.tiers[i]
+
+		days, err := s.tierDays(src)
+		if err != nil {
+			return err
+		}
+
+		for _, day := range days {
+			if day >= today {
+				continue
+			}
+			if err := s.compactDay(src, dst, day); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// compactDay สรุปข้อมูลของวันหนึ่งจาก src ไปยัง dst โดยถือ lock ไว้ เพื่อไม่ให้ข้อมูลที่เขียนระหว่างนั้นหายไป
+func (s *FileStore) compactDay(src tier, dst tier, day string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isCompacted(src, dst, day) {
+		return nil
+	}
+
+	rollups, err := s.readTierDay(src, day, "")
+	if err != nil {
+		return err
+	}
+
+	return writeLinesAtomic(s.segmentPath(dst, day), compactRollups(rollups, dst.resolution))
+}
+
+// Prune ลบไฟล์รายวันของแต่ละ tier ที่ทั้งวันเก่ากว่า retention ของ tier นั้น
+// ข้อมูลจะถูกลบเฉพาะเมื่อถูกสรุปไปยัง tier ถัดไปแล้ว เพื่อไม่ให้ข้อมูลหายก่อนถูก compact
+func (s *FileStore) Prune(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, t := range s.tiers {
+		if t.retention <= 0 {
+			continue
+		}
+
+		days, err := s.tierDays(t)
+		if err != nil {
+			return err
+		}
+
+		// ไฟล์ของวันใดจะถูกลบเมื่อสิ้นวันนั้นเก่ากว่า retention แล้ว
+		cutoff := now.UTC().Add(-t.retention).Add(-24 * time.Hour).Format(segmentDateFormat)
+
+		for _, day := range days {
+			if day > cutoff {
+				continue
+			}
+			if i+1 < len(s.tiers) && !s.isCompacted(t, s.tiers[i+1], day) {
+				continue
+			}
+			if err := os.Remove(s.segmentPath(t, day)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return s.pruneForgotten(now)
+}
+
+// Forget ซ่อนข้อมูลของเซนเซอร์ที่มีเวลาไม่เกิน until จากการอ่านทุกแบบ ใช้เมื่อเซนเซอร์ถูกลบ
+// เพื่อไม่ให้เซนเซอร์ที่ลงทะเบียนด้วย ID เดิมในภายหลัง (อาจเป็นของ tenant อื่น) เห็นข้อมูลย้อนหลังของเซนเซอร์เดิม
+// rollup ที่เริ่มก่อน until ถูกซ่อนทั้งก้อนเพราะอาจมีข้อมูลของเซนเซอร์เดิมปนอยู่ ข้อมูลที่ถูกซ่อนจะถูกลบตาม retention
+func (s *FileStore) Forget(sensorID string, until time.Time) error {
+	s.forgetMu.Lock()
+	defer s.forgetMu.Unlock()
+
+	if previous, ok := s.forgotten[sensorID]; ok && !until.After(previous) {
+		return nil
+	}
+	s.forgotten[sensorID] = until
+	return s.saveForgotten()
+}
+
+// visible ตรวจสอบว่าข้อมูลของเซนเซอร์ที่เวลา at ไม่ถูกซ่อนด้วย Forget
+func (s *FileStore) visible(sensorID string, at time.Time) bool {
+	s.forgetMu.RLock()
+	defer s.forgetMu.RUnlock()
+
+	until, ok := s.forgotten[sensorID]
+	return !ok || at.After(until)
+}
+
+// pruneForgotten ลบรายการของ Forget ที่ข้อมูลถูกลบตาม retention ของทุก tier แล้ว
+func (s *FileStore) pruneForgotten(now time.Time) error {
+	var longest time.Duration
+	for _, t := range s.tiers {
+		if t.retention <= 0 {
+			return nil
+		}
+		longest = max(longest, t.retention)
+	}
+	cutoff := now.Add(-longest).Add(-24 * time.Hour)
+
+	s.forgetMu.Lock()
+	defer s.forgetMu.Unlock()
+
+	pruned := false
+	for id, until := range s.forgotten {
+		if until.Before(cutoff) {
+			delete(s.forgotten, id)
+			pruned = true
+		}
+	}
+	if !pruned {
+		return nil
+	}
+	return s.saveForgotten()
+}
+
+// loadForgotten อ่านรายการของ Forget ที่บันทึกไว้
+func (s *FileStore) loadForgotten() (map[string]time.Time, error) {
+	forgotten := make(map[string]time.Time)
+	data, err := os.ReadFile(filepath.Join(s.dir, forgottenFile))
+	if os.IsNotExist(err) {
+		return forgotten, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read forgotten sensors: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &forgotten); err != nil {
+		return nil, fmt.Errorf("decode forgotten sensors: %w", err)
+	}
+	return forgotten, nil
+}
+
+// saveForgotten บันทึกรายการของ Forget แบบ atomic ผู้เรียกต้องถือ forgetMu
+func (s *FileStore) saveForgotten() error {
+	data, err := json.Marshal(s.forgotten)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.dir, forgottenFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write forgotten sensors: %w", err)
+	}
+
+	return os.Rename(tmp, filepath.Join(s.dir, forgottenFile))
+}
+
+// SaveState บันทึกสถานะล่าสุดของเซนเซอร์แบบ atomic (เขียนไฟล์ชั่วคราวแล้ว rename)
+func (s *FileStore) SaveState(sensors []*model.SensorModel) error {
+	data, err := json.Marshal(state{SavedAt: time.Now(), Sensors: sensors})
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.dir, stateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write state: %w", err)
+	}
+
+	return os.Rename(tmp, filepath.Join(s.dir, stateFile))
+}
+
+// LoadState อ่านสถานะล่าสุดที่บันทึกไว้ found เป็น false ถ้ายังไม่เคยบันทึก
+func (s *FileStore) LoadState() (sensors []*model.SensorModel, savedAt time.Time, found bool, err error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, stateFile))
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("read state: %w", err)
+	}
+
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("decode state: %w", err)
+	}
+
+	return st.Sensors, st.SavedAt, true, nil
+}
+
+// Close หยุด goroutine สำหรับ compact และลบข้อมูลที่หมดอายุ
+func (s *FileStore) Close() error {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	return nil
+}
+
+// startCompactor compact และลบข้อมูลที่หมดอายุเป็นระยะจนกว่า store จะถูกปิด
+func (s *FileStore) startCompactor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.compactAndPrune(time.Now())
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.compactAndPrune(now)
+		}
+	}
+}
+
+// compactAndPrune compact ก่อนแล้วจึงลบ เพื่อให้ข้อมูลดิบถูกสรุปก่อนถูกลบเสมอ
+// ถ้า compact ไม่สำเร็จจะไม่ลบ และลองใหม่ในรอบถัดไป
+func (s *FileStore) compactAndPrune(now time.Time) {
+	if err := s.Compact(now); err != nil {
+		s.logger.Error("Failed to compact storage, skipping prune", zap.String("dir", s.dir), zap.Error(err))
+		return
+	}
+	if err := s.Prune(now); err != nil {
+		s.logger.Error("Failed to prune storage", zap.String("dir", s.dir), zap.Error(err))
+	}
+}
+
+// pickTier เลือก tier สำหรับอ่านข้อมูลของวันหนึ่ง โดยลองจาก tier ที่หยาบที่สุดที่ step หารลงตัว
+// ถ้าไม่มีข้อมูลที่ละเอียดพอจะใช้ tier ที่หยาบกว่าแทน
+func (s *FileStore) pickTier(day string, step time.Duration) (tier, bool) {
+	for i := len(s.tiers) - 1; i >= 0; i-- {
+		t := s.tiers[i]
+		if t.resolution > 0 && (step < t.resolution || step%t.resolution != 0) {
+			continue
+		}
+		if s.hasDay(t, day) {
+			return t, true
+		}
+	}
+
+	for _, t := range s.tiers {
+		if s.hasDay(t, day) {
+			return t, true
+		}
+	}
+
+	return tier{}, false
+}
+
+// readTierDay อ่านข้อมูลของวันหนึ่งจาก tier ในรูปแบบ rollup (sensorID ว่างคืออ่านทุกเซนเซอร์)
+// ข้อมูลที่ถูกซ่อนด้วย Forget จะไม่ถูกอ่าน จึงไม่ถูกสรุปไปยัง tier ถัดไปด้วย
+func (s *FileStore) readTierDay(t tier, day string, sensorID string) ([]Rollup, error) {
+	path := s.segmentPath(t, day)
+
+	if t.resolution == 0 {
+		var records []Record
+		err := scanLines(path, func(record Record) {
+			if (sensorID == "" || record.SensorID == sensorID) && s.visible(record.SensorID, record.Timestamp) {
+				records = append(records, record)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sortRecords(records)
+		return recordRollups(records), nil
+	}
+
+	var rollups []Rollup
+	err := scanLines(path, func(rollup Rollup) {
+		if (sensorID == "" || rollup.SensorID == sensorID) && s.visible(rollup.SensorID, rollup.Start) {
+			rollups = append(rollups, rollup)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortRollups(rollups)
+	return rollups, nil
+}
+
+// isCompacted ตรวจสอบว่าข้อมูลของวันใน src ถูกสรุปไปยัง dst หลังการเขียนครั้งล่าสุดแล้ว
+func (s *FileStore) isCompacted(src tier, dst tier, day string) bool {
+	srcInfo, err := os.Stat(s.segmentPath(src, day))
+	if err != nil {
+		return true
+	}
+
+	dstInfo, err := os.Stat(s.segmentPath(dst, day))
+	if err != nil {
+		return false
+	}
+
+	return !srcInfo.ModTime().After(dstInfo.ModTime())
+}
+
+// hasDay ตรวจสอบว่า tier มีไฟล์ของวันที่กำหนด
+func (s *FileStore) hasDay(t tier, day string) bool {
+	_, err := os.Stat(s.segmentPath(t, day))
+	return err == nil
+}
+
+// tierDays คืนค่ารายการวันที่มีไฟล์ใน tier เรียงจากเก่าไปใหม่
+func (s *FileStore) tierDays(t tier) ([]string, error) {
+	entries, err := os.ReadDir(s.tierDir(t))
+	if err != nil {
+		return nil, fmt.Errorf("list segments: %w", err)
+	}
+
+	days := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		days = append(days, strings.TrimSuffix(name, segmentExt))
+	}
+
+	sort.Strings(days)
+	return days, nil
+}
+
+// tierDir คืนค่าไดเรกทอรีของ tier
+func (s *FileStore) tierDir(t tier) string {
+	return filepath.Join(s.dir, t.name)
+}
+
+// segmentPath คืนค่า path ของไฟล์รายวันของ tier
+func (s *FileStore) segmentPath(t tier, day string) string {
+	return filepath.Join(s.tierDir(t), day+segmentExt)
+}
+
+// daysBetween คืนค่ารายการวัน (UTC) ที่ช่วง [from, to) ครอบคลุม
+func daysBetween(from time.Time, to time.Time) []string {
+	var days []string
+	last := to.UTC().Format(segmentDateFormat)
+	for d := from.UTC().Truncate(24 * time.Hour); ; d = d.Add(24 * time.Hour) {
+		day := d.Format(segmentDateFormat)
+		if day > last {
+			break
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+// sortRecords เรียง record ตามเวลา
+func sortRecords(records []Record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+}
+
+// appendLines เขียนค่าต่อท้ายไฟล์แบบ JSON หนึ่งบรรทัดต่อหนึ่งค่า
+func appendLines[T any](path string, values []T) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+
+	return encodeLines(f, values)
+}
+
+// writeLinesAtomic เขียนไฟล์ใหม่ทั้งไฟล์ผ่านไฟล์ชั่วคราว เพื่อไม่ให้ผู้อ่านเห็นไฟล์ที่เขียนไม่ครบ
+func writeLinesAtomic[T any](path string, values []T) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create segment: %w", err)
+	}
+
+	if err := encodeLines(f, values); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// encodeLines เขียนค่าแบบ JSON หนึ่งบรรทัดต่อหนึ่งค่า
+func encodeLines[T any](f *os.File, values []T) error {
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("encode record: %w", err)
+		}
+	}
+
+	return w.Flush()
+}
+
+// scanLines อ่านไฟล์ทีละบรรทัด (บรรทัดที่เสียหายจะถูกข้าม)
+func scanLines[T any](path string, fn func(T)) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var v T
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			// บรรทัดสุดท้ายอาจเขียนไม่ครบเมื่อ process ถูกหยุดกะทันหัน
+			continue
+		}
+		fn(v)
+	}
+
+	return scanner.Err()
+}
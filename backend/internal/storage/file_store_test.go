@@ -0,0 +1,224 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/storage"
+)
+
+func TestFileStoreAppendAndQuery(t *testing.T) {
+	store, err := storage.OpenFileStore(t.TempDir(), storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer store.Close()
+
+	day := time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC)
+	require.NoError(t, store.Append([]storage.Record{
+		{SensorID: "temp-001", Timestamp: day.Add(2 * time.Minute), Values: map[string]float64{"temperature": 22}},
+		{SensorID: "temp-001", Timestamp: day, Values: map[string]float64{"temperature": 21}},
+		{SensorID: "temp-002", Timestamp: day, Values: map[string]float64{"temperature": 30}},
+	}))
+
+	// record ข้ามวันต้องถูกอ่านกลับมาเรียงตามเวลา และกรองตามเซนเซอร์
+	records, err := store.Query("temp-001", day.Add(-time.Hour), day.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, 21.0, records[0].Values["temperature"])
+	assert.Equal(t, 22.0, records[1].Values["temperature"])
+
+	// ช่วงเวลาเป็นแบบ [from, to)
+	records, err = store.Query("temp-001", day, day.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.True(t, records[0].Timestamp.Equal(day))
+}
+
+func TestFileStoreSkipsTruncatedLine(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.OpenFileStore(dir, storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Now().UTC()
+	require.NoError(t, store.Append([]storage.Record{
+		{SensorID: "temp-001", Timestamp: now, Values: map[string]float64{"temperature": 25}},
+	}))
+
+	// จำลองบรรทัดที่เขียนไม่ครบตอน process ถูกหยุด
+	path := filepath.Join(dir, "raw", now.Format("2006-01-02")+".jsonl")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"sensor_id":"temp-001","timest`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	records, err := store.Since(now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestFileStoreCompactAndPrune(t *testing.T) {
+	store, err := storage.OpenFileStore(t.TempDir(), storage.RetentionPolicy{
+		Raw:    7 * 24 * time.Hour,
+		Minute: 90 * 24 * time.Hour,
+	}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-100 * 24 * time.Hour)
+	mid := now.Add(-10 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	require.NoError(t, store.Append([]storage.Record{
+		{SensorID: "temp-001", Timestamp: old, Values: map[string]float64{"temperature": 20}},
+		{SensorID: "temp-001", Timestamp: mid, Values: map[string]float64{"temperature": 21}},
+		{SensorID: "temp-001", Timestamp: mid.Add(10 * time.Second), Values: map[string]float64{"temperature": 25}},
+		{SensorID: "temp-001", Timestamp: recent, Values: map[string]float64{"temperature": 22}},
+	}))
+
+	// ข้อมูลดิบที่ยังไม่ถูก compact ต้องไม่ถูกลบ
+	require.NoError(t, store.Prune(now))
+	records, err := store.Query("temp-001", old.Add(-time.Hour), now)
+	require.NoError(t, err)
+	assert.Len(t, records, 4)
+
+	require.NoError(t, store.Compact(now))
+	require.NoError(t, store.Prune(now))
+
+	// เหลือข้อมูลดิบเฉพาะภายใน 7 วัน
+	records, err = store.Query("temp-001", old.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.True(t, records[0].Timestamp.Equal(recent))
+
+	// rollup รายนาทียังเก็บข้อมูล 10 วันก่อนไว้ และรวมสองค่าในนาทีเดียวกัน
+	buckets, err := store.History("temp-001", mid.Truncate(time.Minute), mid.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	stats := buckets[0].Metrics["temperature"]
+	assert.Equal(t, 2, stats.Count)
+	assert.Equal(t, 21.0, stats.Min)
+	assert.Equal(t, 25.0, stats.Max)
+	assert.Equal(t, 25.0, stats.Last)
+
+	// rollup รายชั่วโมงไม่มีวันหมดอายุ จึงยังตอบ query ช่วง 100 วันก่อนได้ และใช้ข้อมูลดิบสำหรับวันนี้
+	buckets, err = store.History("temp-001", old.Add(-24*time.Hour).Truncate(time.Hour), now, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, buckets, 3)
+	assert.Equal(t, 20.0, buckets[0].Metrics["temperature"].Avg())
+	assert.Equal(t, 23.0, buckets[1].Metrics["temperature"].Avg())
+	assert.Equal(t, 22.0, buckets[2].Metrics["temperature"].Avg())
+}
+
+func TestFileStoreRecompactsLateData(t *testing.T) {
+	store, err := storage.OpenFileStore(t.TempDir(), storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour).Truncate(time.Hour)
+	require.NoError(t, store.Append([]storage.Record{
+		{SensorID: "temp-001", Timestamp: yesterday, Values: map[string]float64{"temperature": 20}},
+	}))
+	require.NoError(t, store.Compact(now))
+
+	// อุปกรณ์ส่งข้อมูลย้อนหลังของเมื่อวานเข้ามาหลัง compact
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, store.Append([]storage.Record{
+		{SensorID: "temp-001", Timestamp: yesterday.Add(time.Second), Values: map[string]float64{"temperature": 30}},
+	}))
+	require.NoError(t, store.Compact(now))
+
+	buckets, err := store.History("temp-001", yesterday, yesterday.Add(time.Hour), time.Hour)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	assert.Equal(t, 2, buckets[0].Metrics["temperature"].Count)
+}
+
+func TestFileStoreState(t *testing.T) {
+	store, err := storage.OpenFileStore(t.TempDir(), storage.RetentionPolicy{}, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer store.Close()
+
+	// ยังไม่เคยบันทึกสถานะ
+	_, _, found, err := store.LoadState()
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	sensor := &model.SensorModel{ID: "temp-001", Type: "temperature", Status: "active"}
+	sensor.SetValue("temperature", 23.5)
+	require.NoError(t, store.SaveState([]*model.SensorModel{sensor}))
+
+	sensors, savedAt, found, err := store.LoadState()
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.False(t, savedAt.IsZero())
+	require.Len(t, sensors, 1)
+	assert.Equal(t, model.Measurement{Value: 23.5, Unit: "°C"}, sensors[0].Metrics["temperature"])
+}
+
+func TestFileStoreForget(t *testing.T) {
+	dir := t.TempDir()
+	policy := storage.RetentionPolicy{Raw: 24 * time.Hour, Minute: 24 * time.Hour, Hour: 24 * time.Hour}
+	store, err := storage.OpenFileStore(dir, policy, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+	deletedAt := yesterday.Add(30 * time.Minute)
+	require.NoError(t, store.Append([]storage.Record{
+		{SensorID: "temp-001", Timestamp: yesterday.Add(10 * time.Minute), Values: map[string]float64{"temperature": 99}},
+		{SensorID: "temp-002", Timestamp: yesterday.Add(10 * time.Minute), Values: map[string]float64{"temperature": 30}},
+		{SensorID: "temp-001", Timestamp: yesterday.Add(40 * time.Minute), Values: map[string]float64{"temperature": 21}},
+	}))
+	require.NoError(t, store.Forget("temp-001", deletedAt))
+	require.NoError(t, store.Compact(now))
+
+	// ข้อมูลก่อน Forget ถูกซ่อนจากทุกการอ่าน รวมถึง rollup รายชั่วโมงที่คาบเกี่ยวกับเวลาที่ลบ
+	records, err := store.Query("temp-001", yesterday, now)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 21.0, records[0].Values["temperature"])
+
+	records, err = store.Since(yesterday)
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	buckets, err := store.History("temp-001", yesterday, now, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, buckets, 1)
+	assert.Equal(t, 21.0, buckets[0].Metrics["temperature"].Last)
+
+	buckets, err = store.History("temp-001", yesterday, now, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, buckets)
+
+	buckets, err = store.History("temp-002", yesterday, now, time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, buckets, 1)
+
+	// Forget ถูกบันทึกไว้ และถูกลบเมื่อข้อมูลทุก tier หมดอายุแล้ว
+	reopened, err := storage.OpenFileStore(dir, policy, zaptest.NewLogger(t))
+	require.NoError(t, err)
+	defer reopened.Close()
+	records, err = reopened.Query("temp-001", yesterday, now)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	require.NoError(t, reopened.Prune(now.Add(3*24*time.Hour)))
+	require.NoError(t, reopened.Append([]storage.Record{
+		{SensorID: "temp-001", Timestamp: yesterday, Values: map[string]float64{"temperature": 22}},
+	}))
+	records, err = reopened.Query("temp-001", yesterday, now)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, 22.0, records[0].Values["temperature"])
+}
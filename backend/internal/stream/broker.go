@@ -0,0 +1,206 @@
+package stream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
+)
+
+// DefaultSubscriberBuffer คือขนาด buffer เริ่มต้นของ channel ต่อ client
+const DefaultSubscriberBuffer = 16
+
+// Event คือข้อมูลหนึ่งเหตุการณ์ที่จะถูกส่งไปยัง client ผ่าน SSE
+type Event struct {
+	// Seq คือเลข sequence ที่เพิ่มขึ้นเรื่อยๆ ภายใน stream เดียวกัน
+	Seq uint64
+
+	// ID คือค่าที่จะใส่ในบรรทัด "id:" ประกอบด้วย stream ID และ Seq
+	ID string
+
+	// Type คือชื่อ event ที่จะใส่ในบรรทัด "event:"
+	Type string
+
+	// Data คือ payload ที่ถูก serialize แล้ว ใช้ร่วมกันทุก client ที่ไม่มี Filter
+	Data []byte
+
+	// items และ serverID ใช้ประกอบ payload ใหม่สำหรับ client ที่มี Filter
+	items    []Item
+	serverID string
+}
+
+// Replay คือผลลัพธ์ของการ subscribe พร้อม Last-Event-ID
+type Replay struct {
+	// Events คือ event ที่ client พลาดไประหว่างขาดการเชื่อมต่อ
+	Events []Event
+
+	// Reset เป็น true เมื่อไม่สามารถ replay ได้ (ID เก่าเกินไปหรือมาจาก stream อื่น)
+	// ผู้เรียกต้องส่ง snapshot ทั้งหมดให้ client แทน
+	Reset bool
+
+	// LastID คือ ID ของ event ล่าสุด ณ เวลาที่ subscribe ใช้กำกับ snapshot
+	LastID string
+}
+
+// Subscriber แทน client หนึ่งรายที่รับ event จาก Broker
+type Subscriber struct {
+	events chan Event
+	tenant string
+}
+
+// Events คืนค่า channel สำหรับอ่าน event ซึ่งจะถูกปิดเมื่อ subscriber ถูกถอดออก
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Tenant คืนค่า tenant ของ subscriber (ค่าว่างคือรับ event ของทุก tenant)
+// ผู้อ่าน event ต้องกรอง payload ด้วย Filter.WithTenant เพราะ event หนึ่งอาจมีเซนเซอร์หลาย tenant
+func (s *Subscriber) Tenant() string {
+	return s.tenant
+}
+
+// Broker เป็นศูนย์กลางกระจาย event (fan-out) ไปยัง subscriber ทั้งหมด
+// และเก็บประวัติ event ล่าสุดไว้สำหรับ replay ตาม Last-Event-ID
+type Broker struct {
+	subscribers map[*Subscriber]struct{}
+	bufferSize  int
+	streamID    string
+	seq         uint64
+	history     *history
+	mu          sync.Mutex
+	logger      *zap.Logger
+}
+
+// NewBroker สร้าง Broker ใหม่ โดยกำหนดขนาด buffer ต่อ subscriber และจำนวน event ที่เก็บไว้ replay
+func NewBroker(bufferSize int, historySize int, logger *zap.Logger) *Broker {
+	if bufferSize <= 0 {
+		bufferSize = DefaultSubscriberBuffer
+	}
+
+	return &Broker{
+		subscribers: make(map[*Subscriber]struct{}),
+		bufferSize:  bufferSize,
+		// stream ID เปลี่ยนทุกครั้งที่ server เริ่มใหม่ ทำให้ ID จาก stream เก่าไม่ถูกนำมา replay ผิดๆ
+		streamID: strconv.FormatInt(time.Now().UnixNano(), 36),
+		history:  newHistory(historySize),
+		logger:   logger,
+	}
+}
+
+// Subscribe ลงทะเบียน subscriber ใหม่ที่รับ event ของทุก tenant และคืนค่า event ที่พลาดไปตาม lastEventID
+// การลงทะเบียนและการอ่านประวัติทำภายใต้ lock เดียวกัน จึงไม่มี event ตกหล่นหรือซ้ำ
+func (b *Broker) Subscribe(lastEventID string) (*Subscriber, Replay) {
+	return b.SubscribeTenant(lastEventID, "")
+}
+
+// SubscribeTenant ลงทะเบียน subscriber ที่รับเฉพาะ event ที่มีข้อมูลของ tenant
+// รวมถึง event ที่ replay ให้ตาม lastEventID
+func (b *Broker) SubscribeTenant(lastEventID string, tenant string) (*Subscriber, Replay) {
+	sub := &Subscriber{
+		events: make(chan Event, b.bufferSize),
+		tenant: tenant,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[sub] = struct{}{}
+	replay := Replay{LastID: b.formatID(b.seq)}
+
+	if lastEventID == "" {
+		return sub, replay
+	}
+
+	seq, ok := b.parseID(lastEventID)
+	if !ok || seq > b.seq {
+		replay.Reset = true
+		return sub, replay
+	}
+
+	events, ok := b.history.since(seq)
+	replay.Reset = !ok
+	for _, evt := range events {
+		if evt.visibleTo(tenant) {
+			replay.Events = append(replay.Events, evt)
+		}
+	}
+
+	return sub, replay
+}
+
+// Unsubscribe ถอด subscriber ออกและปิด channel ของมัน (เรียกซ้ำได้อย่างปลอดภัย)
+func (b *Broker) Unsubscribe(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remove(sub)
+}
+
+// Publish กำหนด sequence ID ให้ event เก็บลงประวัติ แล้วกระจายไปยัง subscriber ทุกรายที่เห็นข้อมูลใน event
+// subscriber ที่อ่านไม่ทันจนทำให้ buffer เต็มจะถูกถอดออก เพื่อไม่ให้ถ่วง client อื่น
+// เมื่อเชื่อมต่อใหม่ client จะได้ event ที่พลาดไปจากประวัติ
+func (b *Broker) Publish(evt Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	evt.Seq = b.seq
+	evt.ID = b.formatID(b.seq)
+	b.history.add(evt)
+
+	for sub := range b.subscribers {
+		if !evt.visibleTo(sub.tenant) {
+			continue
+		}
+		select {
+		case sub.events <- evt:
+		default:
+			b.remove(sub)
+			metrics.SSEEventsDroppedTotal.WithLabelValues(evt.Type).Inc()
+			b.logger.Warn("Dropping slow SSE subscriber", zap.String("event", evt.Type))
+		}
+	}
+}
+
+// SubscriberCount คืนค่าจำนวน subscriber ที่เชื่อมต่ออยู่
+func (b *Broker) SubscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// remove ถอด subscriber ออกจาก map (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (b *Broker) remove(sub *Subscriber) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+
+	delete(b.subscribers, sub)
+	close(sub.events)
+}
+
+// formatID สร้าง event ID ในรูปแบบ "<stream ID>-<sequence>"
+func (b *Broker) formatID(seq uint64) string {
+	return fmt.Sprintf("%s-%d", b.streamID, seq)
+}
+
+// parseID แยก sequence ออกจาก event ID โดยต้องเป็น ID ของ stream นี้เท่านั้น
+func (b *Broker) parseID(id string) (uint64, bool) {
+	streamID, seqStr, found := strings.Cut(id, "-")
+	if !found || streamID != b.streamID {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return seq, true
+}
@@ -0,0 +1,144 @@
+package stream_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
+)
+
+func TestBrokerFanOut(t *testing.T) {
+	broker := stream.NewBroker(4, 8, zaptest.NewLogger(t))
+
+	// สร้าง subscriber สองราย
+	sub1, _ := broker.Subscribe("")
+	sub2, _ := broker.Subscribe("")
+	assert.Equal(t, 2, broker.SubscriberCount())
+
+	broker.Publish(stream.Event{Type: "message", Data: []byte("hello")})
+
+	// ทุก subscriber ควรได้รับ event เดียวกัน
+	for _, sub := range []*stream.Subscriber{sub1, sub2} {
+		evt := <-sub.Events()
+		assert.Equal(t, "message", evt.Type)
+		assert.Equal(t, "hello", string(evt.Data))
+	}
+
+	// ยกเลิกการรับ event แล้ว channel ต้องถูกปิด
+	broker.Unsubscribe(sub1)
+	_, ok := <-sub1.Events()
+	assert.False(t, ok)
+	assert.Equal(t, 1, broker.SubscriberCount())
+
+	// เรียก Unsubscribe ซ้ำต้องไม่ panic
+	broker.Unsubscribe(sub1)
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := stream.NewBroker(1, 8, zaptest.NewLogger(t))
+	slow, _ := broker.Subscribe("")
+
+	// event แรกเข้า buffer ได้ event ที่สองทำให้ buffer เต็มและ subscriber ถูกถอดออก
+	broker.Publish(stream.Event{Type: "message", Data: []byte("1")})
+	broker.Publish(stream.Event{Type: "message", Data: []byte("2")})
+
+	assert.Equal(t, 0, broker.SubscriberCount())
+
+	evt, ok := <-slow.Events()
+	require.True(t, ok)
+	assert.Equal(t, "1", string(evt.Data))
+
+	_, ok = <-slow.Events()
+	assert.False(t, ok)
+}
+
+func TestBrokerReplay(t *testing.T) {
+	broker := stream.NewBroker(4, 3, zaptest.NewLogger(t))
+
+	// จำ ID ก่อนมี event ใดๆ
+	sub, initial := broker.Subscribe("")
+	broker.Unsubscribe(sub)
+
+	for _, data := range []string{"1", "2"} {
+		broker.Publish(stream.Event{Type: "message", Data: []byte(data)})
+	}
+
+	// replay ต้องได้ event ที่พลาดไปทั้งหมดตามลำดับ
+	sub, replay := broker.Subscribe(initial.LastID)
+	broker.Unsubscribe(sub)
+	require.False(t, replay.Reset)
+	require.Len(t, replay.Events, 2)
+	assert.Equal(t, "1", string(replay.Events[0].Data))
+	assert.Equal(t, "2", string(replay.Events[1].Data))
+	assert.Less(t, replay.Events[0].Seq, replay.Events[1].Seq)
+	assert.Equal(t, replay.Events[1].ID, replay.LastID)
+
+	// client ที่ทันแล้วไม่ต้องได้อะไรเพิ่ม
+	sub, replay = broker.Subscribe(replay.LastID)
+	broker.Unsubscribe(sub)
+	assert.False(t, replay.Reset)
+	assert.Empty(t, replay.Events)
+
+	// เมื่อประวัติถูกเขียนทับ ID เดิมต้องได้ Reset
+	for _, data := range []string{"3", "4", "5"} {
+		broker.Publish(stream.Event{Type: "message", Data: []byte(data)})
+	}
+	sub, replay = broker.Subscribe(initial.LastID)
+	broker.Unsubscribe(sub)
+	assert.True(t, replay.Reset)
+	assert.Empty(t, replay.Events)
+}
+
+func TestBrokerReplayUnknownID(t *testing.T) {
+	broker := stream.NewBroker(4, 8, zaptest.NewLogger(t))
+	broker.Publish(stream.Event{Type: "message", Data: []byte("1")})
+
+	// ID จาก stream อื่น, ID ที่อยู่ในอนาคต และรูปแบบผิด ต้องได้ Reset ทั้งหมด
+	_, current := broker.Subscribe("")
+	streamID := strings.TrimSuffix(current.LastID, "-1")
+	for _, id := range []string{"other-1", streamID + "-99", "garbage"} {
+		sub, replay := broker.Subscribe(id)
+		broker.Unsubscribe(sub)
+		assert.True(t, replay.Reset, id)
+	}
+}
+
+func TestBrokerTenantIsolation(t *testing.T) {
+	broker := stream.NewBroker(4, 8, zaptest.NewLogger(t))
+	_, initial := broker.Subscribe("")
+
+	acme, _ := broker.SubscribeTenant("", "acme")
+	globex, _ := broker.SubscribeTenant("", "globex")
+	all, _ := broker.Subscribe("")
+
+	onlyGlobex := stream.NewSensorEvent("sensor.updated", "srv", []stream.Item{
+		{SensorID: "g-1", Tenant: "globex", JSON: []byte(`{"id":"g-1"}`)},
+	})
+	mixed := stream.NewSensorEvent("sensor.updated", "srv", []stream.Item{
+		{SensorID: "a-1", Tenant: "acme", JSON: []byte(`{"id":"a-1"}`)},
+		{SensorID: "g-2", Tenant: "globex", JSON: []byte(`{"id":"g-2"}`)},
+	})
+	broker.Publish(onlyGlobex)
+	broker.Publish(mixed)
+
+	// acme ไม่ได้รับ event ที่มีเฉพาะข้อมูลของ globex และ payload ของ event ที่ปนกันมีเฉพาะเซนเซอร์ของ acme
+	evt := <-acme.Events()
+	data, ok := evt.Payload(stream.Filter{}.WithTenant(acme.Tenant()))
+	require.True(t, ok)
+	assert.JSONEq(t, `{"server_id":"srv","data":[{"id":"a-1"}]}`, string(data))
+	assert.Empty(t, acme.Events())
+
+	assert.Len(t, globex.Events(), 2)
+	assert.Len(t, all.Events(), 2)
+
+	// replay ของ tenant ข้าม event ที่ไม่มีข้อมูลของ tenant
+	sub, replay := broker.SubscribeTenant(initial.LastID, "acme")
+	defer broker.Unsubscribe(sub)
+	require.False(t, replay.Reset)
+	require.Len(t, replay.Events, 1)
+	assert.Equal(t, evt.ID, replay.Events[0].ID)
+}
@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Filter คือเงื่อนไขการ subscribe ของ client แต่ละราย
+// ภายในพารามิเตอร์เดียวกันเป็นแบบ "ตรงค่าใดค่าหนึ่ง" (เช่น ids=a,b)
+// ส่วนระหว่างพารามิเตอร์ต้องตรงทุกเงื่อนไข และ tag ทุกตัวที่ระบุต้องมีอยู่ในเซนเซอร์
+// tenant ไม่ได้มาจาก query แต่ถูกกำหนดโดย server ผ่าน WithTenant
+type Filter struct {
+	ids    map[string]struct{}
+	types  map[string]struct{}
+	tags   []string
+	tenant string
+}
+
+// ParseFilter สร้าง Filter จาก query parameter ids, type และ tag
+// ค่าแต่ละพารามิเตอร์คั่นด้วย comma หรือระบุซ้ำหลายครั้งก็ได้
+func ParseFilter(query url.Values) Filter {
+	return Filter{
+		ids:   toSet(splitValues(query["ids"])),
+		types: toSet(splitValues(query["type"])),
+		tags:  splitValues(query["tag"]),
+	}
+}
+
+// WithTenant คืนค่า Filter ที่รับเฉพาะเซนเซอร์ของ tenant (ค่าว่างคือทุก tenant)
+func (f Filter) WithTenant(tenant string) Filter {
+	f.tenant = tenant
+	return f
+}
+
+// IsEmpty คืนค่า true ถ้าไม่มีเงื่อนไขใดๆ (รับข้อมูลทุกเซนเซอร์)
+func (f Filter) IsEmpty() bool {
+	return len(f.ids) == 0 && len(f.types) == 0 && len(f.tags) == 0 && f.tenant == ""
+}
+
+// Match ตรวจสอบว่าเซนเซอร์ตรงกับเงื่อนไขหรือไม่
+func (f Filter) Match(id string, sensorType string, tags []string) bool {
+	if len(f.ids) > 0 {
+		if _, ok := f.ids[id]; !ok {
+			return false
+		}
+	}
+
+	if len(f.types) > 0 {
+		if _, ok := f.types[sensorType]; !ok {
+			return false
+		}
+	}
+
+	for _, want := range f.tags {
+		if !containsString(tags, want) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitValues แยกค่าที่คั่นด้วย comma และตัดค่าว่างทิ้ง
+func splitValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
+
+// toSet แปลง slice เป็น set (คืนค่า nil ถ้าไม่มีค่า)
+func toSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return set
+}
+
+// containsString ตรวจสอบว่ามีค่าใน slice หรือไม่
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package stream_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
+)
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		id       string
+		typ      string
+		tags     []string
+		expected bool
+	}{
+		{name: "empty_filter_matches_all", query: "", id: "temp-001", typ: "temperature", expected: true},
+		{name: "ids_match", query: "ids=temp-001,combined-001", id: "combined-001", typ: "combined", expected: true},
+		{name: "ids_no_match", query: "ids=temp-001", id: "humid-001", typ: "humidity", expected: false},
+		{name: "type_match", query: "type=humidity", id: "humid-001", typ: "humidity", expected: true},
+		{name: "type_no_match", query: "type=humidity", id: "temp-001", typ: "temperature", expected: false},
+		{name: "tag_match", query: "tag=building:A", id: "temp-001", typ: "temperature", tags: []string{"building:A", "room:101"}, expected: true},
+		{name: "all_tags_required", query: "tag=building:A&tag=room:102", id: "temp-001", typ: "temperature", tags: []string{"building:A", "room:101"}, expected: false},
+		{name: "combined_conditions", query: "type=temperature&tag=building:A", id: "temp-001", typ: "temperature", tags: []string{"building:A"}, expected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tc.query)
+			assert.NoError(t, err)
+
+			filter := stream.ParseFilter(query)
+			assert.Equal(t, tc.expected, filter.Match(tc.id, tc.typ, tc.tags))
+		})
+	}
+}
+
+func TestEventPayload(t *testing.T) {
+	evt := stream.NewSensorEvent("message", "srv", []stream.Item{
+		{SensorID: "a", SensorType: "temperature", JSON: []byte(`{"id":"a"}`)},
+		{SensorID: "b", SensorType: "humidity", JSON: []byte(`{"id":"b"}`)},
+	})
+
+	// ไม่มี filter ต้องได้ payload เต็ม
+	data, ok := evt.Payload(stream.Filter{})
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"server_id":"srv","data":[{"id":"a"},{"id":"b"}]}`, string(data))
+
+	// มี filter ต้องได้เฉพาะเซนเซอร์ที่ตรงเงื่อนไข
+	data, ok = evt.Payload(stream.ParseFilter(url.Values{"ids": {"b"}}))
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"server_id":"srv","data":[{"id":"b"}]}`, string(data))
+
+	// ไม่มีเซนเซอร์ตรงเงื่อนไข
+	data, ok = evt.Payload(stream.ParseFilter(url.Values{"type": {"co2"}}))
+	assert.False(t, ok)
+	assert.JSONEq(t, `{"server_id":"srv","data":[]}`, string(data))
+
+	// tenant อื่นต้องไม่เห็นเซนเซอร์ใดแม้ query จะตรง
+	data, ok = evt.Payload(stream.ParseFilter(url.Values{"ids": {"a"}}).WithTenant("acme"))
+	assert.False(t, ok)
+	assert.JSONEq(t, `{"server_id":"srv","data":[]}`, string(data))
+}
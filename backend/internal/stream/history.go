@@ -0,0 +1,56 @@
+package stream
+
+// DefaultHistorySize คือจำนวน event ล่าสุดที่เก็บไว้สำหรับ replay ให้ client ที่เชื่อมต่อใหม่
+const DefaultHistorySize = 512
+
+// history เป็น ring buffer ขนาดคงที่สำหรับเก็บ event ล่าสุดเรียงตาม sequence
+type history struct {
+	events []Event
+	start  int
+	size   int
+}
+
+// newHistory สร้าง ring buffer ตามความจุที่กำหนด
+func newHistory(capacity int) *history {
+	if capacity <= 0 {
+		capacity = DefaultHistorySize
+	}
+
+	return &history{
+		events: make([]Event, capacity),
+	}
+}
+
+// add เพิ่ม event ใหม่ ถ้า buffer เต็มจะเขียนทับ event ที่เก่าที่สุด
+func (h *history) add(evt Event) {
+	if h.size < len(h.events) {
+		h.events[(h.start+h.size)%len(h.events)] = evt
+		h.size++
+		return
+	}
+
+	h.events[h.start] = evt
+	h.start = (h.start + 1) % len(h.events)
+}
+
+// since คืนค่า event ทั้งหมดที่มี sequence มากกว่า seq
+// ok เป็น false เมื่อ event ถัดจาก seq ถูกเขียนทับไปแล้ว
+func (h *history) since(seq uint64) (events []Event, ok bool) {
+	if h.size == 0 {
+		return nil, true
+	}
+
+	oldest := h.events[h.start].Seq
+	if seq+1 < oldest {
+		return nil, false
+	}
+
+	for i := 0; i < h.size; i++ {
+		evt := h.events[(h.start+i)%len(h.events)]
+		if evt.Seq > seq {
+			events = append(events, evt)
+		}
+	}
+
+	return events, true
+}
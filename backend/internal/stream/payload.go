@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ชื่อ event ที่ใช้บน stream
+const (
+	// EventSnapshot คือข้อมูลเซนเซอร์ทั้งหมด ส่งเมื่อเริ่มเชื่อมต่อและเมื่อ resync เป็นระยะ
+	EventSnapshot = "snapshot"
+
+	// EventSensorUpdated คือข้อมูลเฉพาะเซนเซอร์ที่เปลี่ยนแปลง
+	EventSensorUpdated = "sensor.updated"
+
+	// EventSensorStatus คือการเปลี่ยน Status ของเซนเซอร์ (active, stale, offline)
+	EventSensorStatus = "sensor.status"
+
+	// EventSensorDeleted คือเซนเซอร์ที่ถูกลบออกจาก registry มีเพียง id และ type
+	EventSensorDeleted = "sensor.deleted"
+
+	// EventAlert คือการเปลี่ยนสถานะของ alert (pending, firing, resolved)
+	EventAlert = "alert"
+
+	// EventReset คือ snapshot ที่ส่งแทนการ replay เมื่อ Last-Event-ID เก่าเกินไป
+	EventReset = "reset"
+
+	// EventPing ใช้รักษาการเชื่อมต่อ
+	EventPing = "ping"
+
+	// EventTokenExpired ส่งก่อนปิด stream เมื่อ credential ของ client หมดอายุ client ต้องขอ ticket ใหม่ก่อนเชื่อมต่อ
+	EventTokenExpired = "token.expired"
+)
+
+// Item คือข้อมูลเซนเซอร์หนึ่งตัวที่ serialize เป็น JSON ไว้แล้ว
+// เก็บ metadata ไว้สำหรับกรองตาม Filter โดยไม่ต้อง serialize ซ้ำ
+type Item struct {
+	SensorID   string
+	SensorType string
+	Tags       []string
+	Tenant     string
+	JSON       []byte
+}
+
+// ServerID คืนค่า hostname ของ container/เครื่องที่รัน server
+func ServerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+// WrapSensors ห่อ JSON ของข้อมูลเซนเซอร์ให้อยู่ในรูปแบบ {"server_id":...,"data":...}
+func WrapSensors(serverID string, sensorsJSON string) []byte {
+	return []byte(fmt.Sprintf(`{"server_id":"%s","data":%s}`, serverID, sensorsJSON))
+}
+
+// PingPayload สร้าง payload สำหรับ event ping
+func PingPayload(serverID string) []byte {
+	return []byte(fmt.Sprintf(`{"ping": true, "server_id": "%s"}`, serverID))
+}
+
+// TokenExpiredPayload สร้าง payload สำหรับ event token.expired
+func TokenExpiredPayload(serverID string, expiredAt time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"server_id": "%s", "expired_at": "%s"}`, serverID, expiredAt.UTC().Format(time.RFC3339)))
+}
+
+// NewSensorEvent สร้าง event ข้อมูลเซนเซอร์จาก item ที่ serialize แล้ว
+// Data จะเป็น payload ของเซนเซอร์ทั้งหมด ส่วน client ที่มี Filter จะได้ payload ที่ประกอบใหม่จาก item
+func NewSensorEvent(eventType string, serverID string, items []Item) Event {
+	return Event{
+		Type:     eventType,
+		Data:     wrapItems(serverID, items),
+		items:    items,
+		serverID: serverID,
+	}
+}
+
+// Payload คืนค่า data ของ event ตาม Filter ของ client
+// ok เป็น false เมื่อเป็น event ข้อมูลเซนเซอร์แต่ไม่มีเซนเซอร์ใดตรงกับ Filter
+func (e Event) Payload(filter Filter) (data []byte, ok bool) {
+	if filter.IsEmpty() || e.items == nil {
+		return e.Data, true
+	}
+
+	matched := make([]Item, 0, len(e.items))
+	for _, item := range e.items {
+		if filter.tenant != "" && item.Tenant != filter.tenant {
+			continue
+		}
+		if filter.Match(item.SensorID, item.SensorType, item.Tags) {
+			matched = append(matched, item)
+		}
+	}
+
+	return wrapItems(e.serverID, matched), len(matched) > 0
+}
+
+// visibleTo ตรวจสอบว่า event มีข้อมูลของ tenant หรือไม่ (event ที่ไม่ใช่ข้อมูลเซนเซอร์ส่งให้ทุก tenant)
+func (e Event) visibleTo(tenant string) bool {
+	if tenant == "" || e.items == nil {
+		return true
+	}
+	for _, item := range e.items {
+		if item.Tenant == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapItems ประกอบ JSON array จาก item แล้วห่อด้วย server_id
+func wrapItems(serverID string, items []Item) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(item.JSON)
+	}
+	buf.WriteByte(']')
+
+	return WrapSensors(serverID, buf.String())
+}
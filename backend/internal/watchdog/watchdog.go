@@ -0,0 +1,189 @@
+package watchdog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// Policy กำหนดว่าเซนเซอร์ชนิดหนึ่งจะถูกมองว่า stale หรือ offline เมื่อใด
+type Policy struct {
+	// ExpectedInterval คือระยะเวลาที่อุปกรณ์ควรส่งข้อมูลหนึ่งครั้ง
+	ExpectedInterval time.Duration
+
+	// StaleAfterMissed คือจำนวนรอบที่ขาดหายไปก่อนเป็น stale
+	StaleAfterMissed int
+
+	// OfflineAfter คือระยะเวลาที่ไม่ได้รับข้อมูลก่อนเป็น offline
+	OfflineAfter time.Duration
+}
+
+// StatusStore คือ repository ที่ watchdog ใช้อ่านเซนเซอร์และเปลี่ยน Status
+type StatusStore interface {
+	GetAllSensors() ([]*model.SensorModel, error)
+	SetStatus(id string, status string, seenAt time.Time) (bool, error)
+}
+
+// Watchdog ตรวจสอบเวลาที่ได้รับข้อมูลล่าสุดของเซนเซอร์เป็นระยะและเปลี่ยน Status ตาม Policy ของชนิดเซนเซอร์
+type Watchdog struct {
+	store    StatusStore
+	fallback Policy
+	policies map[string]Policy
+	logger   *zap.Logger
+	done     chan struct{}
+	once     sync.Once
+}
+
+// New สร้าง watchdog โดยใช้ fallback กับชนิดเซนเซอร์ที่ไม่มีใน policies
+func New(store StatusStore, fallback Policy, policies map[string]Policy, logger *zap.Logger) *Watchdog {
+	if policies == nil {
+		policies = make(map[string]Policy)
+	}
+
+	return &Watchdog{
+		store:    store,
+		fallback: fallback,
+		policies: policies,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start เริ่ม goroutine ที่ตรวจสอบเซนเซอร์ทุก interval
+func (w *Watchdog) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.done:
+				return
+			case now := <-ticker.C:
+				w.Check(now)
+			}
+		}
+	}()
+}
+
+// Stop หยุดการตรวจสอบ
+func (w *Watchdog) Stop() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+// Check ประเมิน Status ของเซนเซอร์ทุกตัว ณ เวลา now และเปลี่ยนเฉพาะตัวที่สถานะไม่ตรง
+func (w *Watchdog) Check(now time.Time) {
+	sensors, err := w.store.GetAllSensors()
+	if err != nil {
+		w.logger.Error("Failed to read sensors for watchdog", zap.Error(err))
+		return
+	}
+
+	for _, sensor := range sensors {
+		status := w.statusFor(sensor, now)
+		if status == sensor.Status {
+			continue
+		}
+
+		// ถ้ามีข้อมูลใหม่เข้ามาระหว่างนี้ SetStatus จะไม่เปลี่ยนค่า และรอบถัดไปจะประเมินใหม่
+		if _, err := w.store.SetStatus(sensor.ID, status, sensor.LastSeen); err != nil {
+			w.logger.Error("Failed to set sensor status", zap.String("id", sensor.ID), zap.Error(err))
+		}
+	}
+}
+
+// PolicyFor คืนค่า Policy ของชนิดเซนเซอร์
+func (w *Watchdog) PolicyFor(sensorType string) Policy {
+	if policy, ok := w.policies[sensorType]; ok {
+		return policy
+	}
+	return w.fallback
+}
+
+// statusFor คำนวณ Status จากระยะเวลาตั้งแต่ได้รับข้อมูลล่าสุด
+func (w *Watchdog) statusFor(sensor *model.SensorModel, now time.Time) string {
+	if sensor.LastSeen.IsZero() {
+		return model.StatusOffline
+	}
+
+	policy := w.PolicyFor(sensor.Type)
+	gap := now.Sub(sensor.LastSeen)
+
+	switch {
+	case policy.OfflineAfter > 0 && gap >= policy.OfflineAfter:
+		return model.StatusOffline
+	case gap >= policy.ExpectedInterval*time.Duration(policy.StaleAfterMissed):
+		return model.StatusStale
+	default:
+		return model.StatusActive
+	}
+}
+
+// policyFile คือรูปแบบของ policy ในไฟล์ ระยะเวลาเป็น string แบบ Go duration เช่น "30s"
+type policyFile struct {
+	ExpectedInterval string `json:"expected_interval" yaml:"expected_interval"`
+	StaleAfterMissed int    `json:"stale_after_missed" yaml:"stale_after_missed"`
+	OfflineAfter     string `json:"offline_after" yaml:"offline_after"`
+}
+
+// LoadPolicies โหลด policy ต่อชนิดเซนเซอร์จากไฟล์ JSON หรือ YAML (ตามนามสกุลไฟล์)
+// ค่าที่ไม่ได้ระบุในไฟล์จะใช้ค่าจาก fallback
+func LoadPolicies(path string, fallback Policy) (map[string]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrConfigNotFound, fmt.Sprintf("watchdog policies %s: %v", path, err))
+	}
+
+	var raw map[string]policyFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("watchdog policies %s: %v", path, err))
+	}
+
+	policies := make(map[string]Policy, len(raw))
+	for sensorType, entry := range raw {
+		policy := fallback
+		if entry.ExpectedInterval != "" {
+			if policy.ExpectedInterval, err = time.ParseDuration(entry.ExpectedInterval); err != nil {
+				return nil, policyError(sensorType, "expected_interval", err)
+			}
+		}
+		// interval 0 ทำให้ทุกเซนเซอร์ขาดช่วงทันที จึงต้องเป็นค่าบวกเสมอ
+		if policy.ExpectedInterval <= 0 {
+			return nil, policyError(sensorType, "expected_interval", fmt.Errorf("%s must be positive", policy.ExpectedInterval))
+		}
+		if entry.StaleAfterMissed > 0 {
+			policy.StaleAfterMissed = entry.StaleAfterMissed
+		}
+		if entry.OfflineAfter != "" {
+			if policy.OfflineAfter, err = time.ParseDuration(entry.OfflineAfter); err != nil {
+				return nil, policyError(sensorType, "offline_after", err)
+			}
+		}
+		policies[sensorType] = policy
+	}
+
+	return policies, nil
+}
+
+// policyError สร้าง error สำหรับค่าใน policy ที่ไม่ถูกต้อง
+func policyError(sensorType string, field string, err error) error {
+	return apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("watchdog policy %q: invalid %s: %v", sensorType, field, err))
+}
@@ -0,0 +1,160 @@
+package watchdog_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/model"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/repository"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/watchdog"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+func TestWatchdogTransitions(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	// เก็บการเปลี่ยน Status ที่ repository แจ้งออกมา
+	var statuses []string
+	repo.AddChangeListener(func(changed []*model.SensorModel) {
+		for _, sensor := range changed {
+			if sensor.ID == "temp-001" {
+				statuses = append(statuses, sensor.Status)
+			}
+		}
+	})
+
+	fallback := watchdog.Policy{ExpectedInterval: 10 * time.Second, StaleAfterMissed: 3, OfflineAfter: 5 * time.Minute}
+	w := watchdog.New(repo, fallback, map[string]watchdog.Policy{
+		// เซนเซอร์ความชื้นส่งข้อมูลห่างกว่า จึงใช้ policy ที่ผ่อนกว่า
+		"humidity": {ExpectedInterval: time.Minute, StaleAfterMissed: 3, OfflineAfter: time.Hour},
+	}, zaptest.NewLogger(t))
+
+	sensor, err := repo.GetSensorByID("temp-001")
+	require.NoError(t, err)
+	seen := sensor.LastSeen
+
+	w.Check(seen.Add(20 * time.Second))
+	assert.Empty(t, statuses)
+
+	w.Check(seen.Add(30 * time.Second))
+	assert.Equal(t, []string{model.StatusStale}, statuses)
+
+	// ประเมินซ้ำด้วยสถานะเดิมต้องไม่แจ้งซ้ำ
+	w.Check(seen.Add(40 * time.Second))
+	assert.Equal(t, []string{model.StatusStale}, statuses)
+
+	humid, err := repo.GetSensorByID("humid-001")
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusActive, humid.Status)
+
+	w.Check(seen.Add(5 * time.Minute))
+	assert.Equal(t, []string{model.StatusStale, model.StatusOffline}, statuses)
+
+	humid, err = repo.GetSensorByID("humid-001")
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusStale, humid.Status)
+
+	// ได้รับข้อมูลใหม่ต้องกลับเป็น active ทันที
+	temp := 25.0
+	updated, err := repo.SaveReadings("temp-001", []model.ReadingModel{{Temperature: &temp}})
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusActive, updated.Status)
+	assert.True(t, updated.LastSeen.After(seen))
+}
+
+func TestSetStatusIgnoresOutdatedCheck(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	sensor, err := repo.GetSensorByID("temp-001")
+	require.NoError(t, err)
+
+	// มีข้อมูลเข้ามาหลังจาก watchdog อ่านค่า
+	temp := 25.0
+	_, err = repo.SaveReadings("temp-001", []model.ReadingModel{{Temperature: &temp}})
+	require.NoError(t, err)
+
+	applied, err := repo.SetStatus("temp-001", model.StatusOffline, sensor.LastSeen)
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	current, err := repo.GetSensorByID("temp-001")
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusActive, current.Status)
+}
+
+func TestWatchdogWithMockDataLoop(t *testing.T) {
+	repo := repository.NewSensorRepository()
+
+	_, err := repo.CreateSensor(&model.SensorModel{ID: "temp-100", Name: "Registered Sensor", Type: "temperature"})
+	require.NoError(t, err)
+	temp := 25.0
+	registered, err := repo.SaveReadings("temp-100", []model.ReadingModel{{Temperature: &temp}})
+	require.NoError(t, err)
+
+	// เซนเซอร์จำลองที่ได้รับข้อมูลจริงแล้วต้องไม่ถูกสุ่มค่าอีก
+	ingested, err := repo.SaveReadings("temp-002", []model.ReadingModel{{Temperature: &temp}})
+	require.NoError(t, err)
+
+	repo.StartMockDataLoop(repository.MockDataInterval)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	// รอให้ mock loop อัปเดตเซนเซอร์จำลองอย่างน้อยหนึ่งรอบ
+	waitForMockTick := func(after time.Time) {
+		require.Eventually(t, func() bool {
+			mock, err := repo.GetSensorByID("temp-001")
+			return err == nil && mock.LastSeen.After(after)
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	waitForMockTick(registered.LastSeen)
+
+	fallback := watchdog.Policy{ExpectedInterval: 10 * time.Second, StaleAfterMissed: 3, OfflineAfter: 5 * time.Minute}
+	w := watchdog.New(repo, fallback, nil, zaptest.NewLogger(t))
+	checkedAt := time.Now()
+	w.Check(ingested.LastSeen.Add(5 * time.Minute))
+
+	// mock loop รอบถัดไปต้องไม่ทำให้เซนเซอร์ที่ลงทะเบียนกลับเป็น active
+	waitForMockTick(checkedAt)
+
+	for _, id := range []string{"temp-100", "temp-002"} {
+		sensor, err := repo.GetSensorByID(id)
+		require.NoError(t, err)
+		assert.Equal(t, model.StatusOffline, sensor.Status, id)
+	}
+
+	current, err := repo.GetSensorByID("temp-100")
+	require.NoError(t, err)
+	assert.Equal(t, registered.LastSeen, current.LastSeen)
+	assert.Equal(t, registered.Values(), current.Values())
+
+	current, err = repo.GetSensorByID("temp-002")
+	require.NoError(t, err)
+	assert.Equal(t, ingested.LastSeen, current.LastSeen)
+}
+
+func TestLoadPolicies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchdog.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+humidity:
+  expected_interval: 1m
+  offline_after: 1h
+`), 0o644))
+
+	fallback := watchdog.Policy{ExpectedInterval: 10 * time.Second, StaleAfterMissed: 3, OfflineAfter: 5 * time.Minute}
+	policies, err := watchdog.LoadPolicies(path, fallback)
+	require.NoError(t, err)
+	assert.Equal(t, watchdog.Policy{ExpectedInterval: time.Minute, StaleAfterMissed: 3, OfflineAfter: time.Hour}, policies["humidity"])
+
+	require.NoError(t, os.WriteFile(path, []byte("humidity:\n  offline_after: soon\n"), 0o644))
+	_, err = watchdog.LoadPolicies(path, fallback)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("humidity:\n  expected_interval: 0s\n"), 0o644))
+	_, err = watchdog.LoadPolicies(path, fallback)
+	assert.ErrorIs(t, err, apierror.ErrInvalidConfig)
+}
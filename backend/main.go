@@ -13,7 +13,10 @@ import (
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/ingest"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/router"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/service"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/internal/stream"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/config"
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/logger"
@@ -56,6 +59,15 @@ func main() {
 	r := router.NewRouter()
 	e := r.Setup(cfg, log)
 
+	// เริ่ม MQTT bridge สำหรับรับข้อมูลจากอุปกรณ์ (ถ้าเปิดใช้งาน)
+	if cfg.MQTTEnabled {
+		bridge, err := startMQTTBridge(cfg, log)
+		if err != nil {
+			log.Fatal("Failed to start MQTT bridge", zap.Error(err))
+		}
+		defer bridge.Stop()
+	}
+
 	// สร้าง server ด้วยค่า config
 	server := &http.Server{
 		Addr:           fmt.Sprintf(":%d", cfg.Port),
@@ -83,6 +95,42 @@ func main() {
 
 	// ทำการ graceful shutdown
 	waitForShutdown(e, log, cancel)
+
+	// หยุดการส่ง webhook ซ้ำที่ค้างอยู่
+	service.GetNotifier(cfg, log).Close()
+
+	// บันทึกสถานะล่าสุดของเซนเซอร์ก่อนปิดโปรแกรม
+	if err := service.GetSensorService(cfg, log).Close(); err != nil {
+		log.Error("Failed to close sensor storage", zap.Error(err))
+	}
+}
+
+// startMQTTBridge สร้างและเชื่อมต่อ MQTT bridge ที่เขียนข้อมูลผ่าน SensorService
+func startMQTTBridge(cfg *config.Config, log *zap.Logger) (*ingest.MQTTBridge, error) {
+	clientID := cfg.MQTTClientID
+	if clientID == "" {
+		// ใช้ hostname เพื่อให้แต่ละ instance มี client ID ไม่ซ้ำกัน
+		clientID = "sensor-dashboard-" + stream.ServerID()
+	}
+
+	bridge, err := ingest.NewMQTTBridge(ingest.MQTTConfig{
+		BrokerURL:            cfg.MQTTBrokerURL,
+		ClientID:             clientID,
+		Username:             cfg.MQTTUsername,
+		Password:             cfg.MQTTPassword,
+		Topics:               config.SplitList(cfg.MQTTTopics),
+		QoS:                  byte(cfg.MQTTQoS),
+		MaxReconnectInterval: cfg.MQTTMaxReconnectInterval,
+	}, service.GetSensorService(cfg, log), log.Named("mqtt"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bridge.Start(); err != nil {
+		return nil, err
+	}
+
+	return bridge, nil
 }
 
 // waitForShutdown รอสัญญาณการปิดเซิร์ฟเวอร์และทำการปิดอย่างเรียบร้อย
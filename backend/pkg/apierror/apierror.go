@@ -14,14 +14,17 @@ var (
 	ErrEnvironmentInvalid = errors.New("invalid environment")
 
 	// Server errors
-	ErrServerStartFailed = errors.New("failed to start server")
-	ErrServerTimeout     = errors.New("server timeout")
+	ErrServerStartFailed  = errors.New("failed to start server")
+	ErrServerTimeout      = errors.New("server timeout")
+	ErrServiceUnavailable = errors.New("service unavailable")
 
 	// Request errors
 	ErrInvalidRequest   = errors.New("invalid request")
 	ErrResourceNotFound = errors.New("resource not found")
 	ErrUnauthorized     = errors.New("unauthorized access")
 	ErrForbidden        = errors.New("forbidden access")
+	ErrTooManyRequests  = errors.New("too many requests")
+	ErrRequestTooLarge  = errors.New("request entity too large")
 
 	// Data errors
 	ErrDataNotFound = errors.New("data not found")
@@ -64,6 +67,9 @@ func FromError(err error) *APIError {
 	case errors.Is(err, ErrServerStartFailed), errors.Is(err, ErrServerTimeout):
 		return NewAPIError("SERVER_ERROR", err.Error(), http.StatusInternalServerError)
 
+	case errors.Is(err, ErrServiceUnavailable):
+		return NewAPIError("SERVICE_UNAVAILABLE", err.Error(), http.StatusServiceUnavailable)
+
 	case errors.Is(err, ErrResourceNotFound), errors.Is(err, ErrDataNotFound):
 		return NewAPIError("NOT_FOUND", err.Error(), http.StatusNotFound)
 
@@ -76,6 +82,12 @@ func FromError(err error) *APIError {
 	case errors.Is(err, ErrInvalidRequest), errors.Is(err, ErrDataInvalid):
 		return NewAPIError("INVALID_INPUT", err.Error(), http.StatusBadRequest)
 
+	case errors.Is(err, ErrTooManyRequests):
+		return NewAPIError("TOO_MANY_REQUESTS", err.Error(), http.StatusTooManyRequests)
+
+	case errors.Is(err, ErrRequestTooLarge):
+		return NewAPIError("PAYLOAD_TOO_LARGE", err.Error(), http.StatusRequestEntityTooLarge)
+
 	case errors.Is(err, ErrDataConflict):
 		return NewAPIError("CONFLICT", err.Error(), http.StatusConflict)
 
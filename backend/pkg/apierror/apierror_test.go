@@ -56,6 +56,12 @@ func TestFromError(t *testing.T) {
 			expectedCode:   "SERVER_ERROR",
 			expectedStatus: http.StatusInternalServerError,
 		},
+		{
+			name:           "service_unavailable_error",
+			err:            apierror.ErrServiceUnavailable,
+			expectedCode:   "SERVICE_UNAVAILABLE",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
 		{
 			name:           "not_found_error",
 			err:            apierror.ErrDataNotFound,
@@ -80,6 +86,18 @@ func TestFromError(t *testing.T) {
 			expectedCode:   "INVALID_INPUT",
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:           "too_many_requests_error",
+			err:            apierror.ErrTooManyRequests,
+			expectedCode:   "TOO_MANY_REQUESTS",
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "request_too_large_error",
+			err:            apierror.ErrRequestTooLarge,
+			expectedCode:   "PAYLOAD_TOO_LARGE",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
 		{
 			name:           "data_conflict_error",
 			err:            apierror.ErrDataConflict,
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// HeaderAPIKey คือ header ที่ใช้ส่ง API key
+const HeaderAPIKey = "X-API-Key"
+
+// QueryAccessToken คือ query parameter สำหรับส่ง key ในกรณีที่ตั้ง header ไม่ได้ (เช่น EventSource ของ browser)
+const QueryAccessToken = "access_token"
+
+// MethodAPIKey คือค่า Principal.Method ของผู้เรียกที่ยืนยันตัวตนด้วย API key
+const MethodAPIKey = "api_key"
+
+var validate = validator.New()
+
+// APIKey คือ API key หนึ่งรายการในไฟล์ key ซึ่งเก็บเฉพาะ SHA-256 ของ key ไม่เก็บ key จริง
+type APIKey struct {
+	Name   string  `json:"name" yaml:"name" validate:"required"`
+	Hash   string  `json:"hash" yaml:"hash" validate:"required,len=64,hexadecimal"`
+	Scopes []Scope `json:"scopes" yaml:"scopes" validate:"min=1,dive,oneof=read:sensors write:readings admin"`
+	Tenant string  `json:"tenant,omitempty" yaml:"tenant,omitempty" validate:"omitempty,max=64"`
+}
+
+// HashKey คืนค่า SHA-256 ของ key ในรูปแบบ hex ตามที่ใช้ในไฟล์ key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyFromRequest คืนค่า API key จาก header X-API-Key หรือ query access_token (header มาก่อน)
+func APIKeyFromRequest(c echo.Context) string {
+	if key := c.Request().Header.Get(HeaderAPIKey); key != "" {
+		return key
+	}
+	return c.QueryParam(QueryAccessToken)
+}
+
+// LoadAPIKeys โหลด API key จากไฟล์ JSON หรือ YAML (ตามนามสกุลไฟล์) ที่เป็น list ของ key
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrConfigNotFound, fmt.Sprintf("api keys %s: %v", path, err))
+	}
+
+	var keys []APIKey
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &keys)
+	default:
+		err = json.Unmarshal(data, &keys)
+	}
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("api keys %s: %v", path, err))
+	}
+
+	names := make(map[string]bool, len(keys))
+	hashes := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		if err := validate.Struct(key); err != nil {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("api key #%d: %v", i+1, err))
+		}
+		if names[key.Name] {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("duplicate api key name %q", key.Name))
+		}
+		hash := strings.ToLower(key.Hash)
+		if hashes[hash] {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("api key %q reuses the hash of another key", key.Name))
+		}
+		names[key.Name] = true
+		hashes[hash] = true
+	}
+
+	return keys, nil
+}
+
+// KeyStore ยืนยันตัวตนด้วย API key โดยเทียบ SHA-256 ของ key ที่ได้รับกับ hash ที่เก็บไว้
+type KeyStore struct {
+	keys map[string]APIKey
+}
+
+// NewKeyStore สร้าง instance ใหม่ของ KeyStore
+func NewKeyStore(keys []APIKey) *KeyStore {
+	store := &KeyStore{keys: make(map[string]APIKey, len(keys))}
+	for _, key := range keys {
+		store.keys[strings.ToLower(key.Hash)] = key
+	}
+	return store
+}
+
+// Authenticate ยืนยันตัวตนจาก API key ใน request คืนค่า nil ถ้า request ไม่มี API key
+func (s *KeyStore) Authenticate(c echo.Context) (*Principal, error) {
+	raw := APIKeyFromRequest(c)
+	if raw == "" {
+		return nil, nil
+	}
+
+	key, ok := s.keys[HashKey(raw)]
+	if !ok {
+		return nil, apierror.Wrap(apierror.ErrUnauthorized, "invalid api key")
+	}
+
+	return &Principal{Subject: key.Name, Method: MethodAPIKey, Scopes: key.Scopes, Tenant: key.Tenant}, nil
+}
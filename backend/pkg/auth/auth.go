@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Scope คือสิทธิ์ที่ผู้เรียกได้รับ
+type Scope string
+
+const (
+	// ScopeReadSensors อ่านข้อมูลเซนเซอร์ ประวัติ alert และเปิด SSE stream
+	ScopeReadSensors Scope = "read:sensors"
+
+	// ScopeWriteReadings ส่งค่า reading ของเซนเซอร์
+	ScopeWriteReadings Scope = "write:readings"
+
+	// ScopeAdmin จัดการเซนเซอร์ webhook และ replay ได้ทุกอย่าง (ครอบคลุมทุก scope)
+	ScopeAdmin Scope = "admin"
+)
+
+// Valid ตรวจสอบว่าเป็น scope ที่รู้จักหรือไม่
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeReadSensors, ScopeWriteReadings, ScopeAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// AllTenants คือค่า Principal.Tenant ของ credential ผู้ดูแลที่เลือก tenant จาก host ของ request ได้ทุก tenant
+const AllTenants = "*"
+
+// principalKey คือ key ที่เก็บ Principal ไว้ใน echo.Context
+const principalKey = "auth.principal"
+
+// Principal คือผู้เรียกที่ผ่านการยืนยันตัวตนแล้ว
+type Principal struct {
+	// Subject คือชื่อของผู้เรียก (เช่นชื่อของ API key)
+	Subject string `json:"subject"`
+
+	// Method คือวิธีที่ใช้ยืนยันตัวตน (เช่น api_key, jwt)
+	Method string `json:"method"`
+
+	// Roles คือ role จาก claim ของ JWT
+	Roles []string `json:"roles,omitempty"`
+
+	// Scopes คือสิทธิ์ที่ได้รับ
+	Scopes []Scope `json:"scopes"`
+
+	// Tenant คือ tenant ที่ credential ผูกไว้ (ว่างคือ tenant เริ่มต้น, AllTenants คือระบุจาก host ของ request)
+	Tenant string `json:"tenant,omitempty"`
+
+	// ExpiresAt คือเวลาหมดอายุของ credential (ค่าศูนย์คือไม่หมดอายุ เช่น API key)
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HasScope ตรวจสอบว่ามีสิทธิ์ตาม scope หรือไม่ โดย ScopeAdmin ครอบคลุมทุก scope
+func (p *Principal) HasScope(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFrom คืนค่า Principal ของ request ที่ผ่าน Guard แล้ว
+func PrincipalFrom(c echo.Context) (*Principal, bool) {
+	p, ok := c.Get(principalKey).(*Principal)
+	return p, ok
+}
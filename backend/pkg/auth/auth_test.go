@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+func TestGuardRequire(t *testing.T) {
+	store := NewKeyStore([]APIKey{
+		{Name: "dashboard", Hash: HashKey("read-key"), Scopes: []Scope{ScopeReadSensors}},
+		{Name: "gateway", Hash: HashKey("write-key"), Scopes: []Scope{ScopeWriteReadings}},
+		{Name: "operator", Hash: HashKey("admin-key"), Scopes: []Scope{ScopeAdmin}},
+	})
+
+	tests := []struct {
+		name        string
+		scope       Scope
+		header      string
+		query       string
+		wantStatus  int
+		wantCode    string
+		wantSubject string
+	}{
+		{name: "Key in header", scope: ScopeReadSensors, header: "read-key", wantStatus: http.StatusOK, wantSubject: "dashboard"},
+		{name: "Key in query for EventSource", scope: ScopeReadSensors, query: "read-key", wantStatus: http.StatusOK, wantSubject: "dashboard"},
+		{name: "Header takes precedence over query", scope: ScopeReadSensors, header: "read-key", query: "unknown", wantStatus: http.StatusOK, wantSubject: "dashboard"},
+		{name: "Admin covers every scope", scope: ScopeWriteReadings, header: "admin-key", wantStatus: http.StatusOK, wantSubject: "operator"},
+		{name: "Missing key", scope: ScopeReadSensors, wantStatus: http.StatusUnauthorized, wantCode: "UNAUTHORIZED"},
+		{name: "Unknown key", scope: ScopeReadSensors, header: "guess", wantStatus: http.StatusUnauthorized, wantCode: "UNAUTHORIZED"},
+		{name: "Missing scope", scope: ScopeReadSensors, header: "write-key", wantStatus: http.StatusForbidden, wantCode: "FORBIDDEN"},
+		{name: "Non-admin key on admin route", scope: ScopeAdmin, header: "read-key", wantStatus: http.StatusForbidden, wantCode: "FORBIDDEN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/api/sensors", func(c echo.Context) error {
+				principal, ok := PrincipalFrom(c)
+				require.True(t, ok)
+				return c.String(http.StatusOK, principal.Subject)
+			}, NewGuard(store).Require(tt.scope))
+
+			target := "/api/sensors"
+			if tt.query != "" {
+				target += "?" + QueryAccessToken + "=" + tt.query
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderAPIKey, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantSubject, rec.Body.String())
+				return
+			}
+
+			var body apierror.APIError
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantCode, body.Code)
+		})
+	}
+}
+
+func TestGuardDisabled(t *testing.T) {
+	guard := NewGuard()
+	assert.False(t, guard.Enabled())
+
+	e := echo.New()
+	e.GET("/api/sensors", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, guard.Require(ScopeAdmin))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sensors", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	hash := HashKey("secret")
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr error
+		want    int
+	}{
+		{
+			name: "YAML keys",
+			file: "keys.yaml",
+			content: `
+- name: dashboard
+  hash: ` + hash + `
+  scopes: [read:sensors]
+`,
+			want: 1,
+		},
+		{
+			name:    "JSON keys",
+			file:    "keys.json",
+			content: `[{"name":"operator","hash":"` + hash + `","scopes":["admin","read:sensors"]}]`,
+			want:    1,
+		},
+		{
+			name:    "Plain key instead of hash",
+			file:    "keys.json",
+			content: `[{"name":"operator","hash":"secret","scopes":["admin"]}]`,
+			wantErr: apierror.ErrInvalidConfig,
+		},
+		{
+			name:    "Unknown scope",
+			file:    "keys.json",
+			content: `[{"name":"operator","hash":"` + hash + `","scopes":["delete:everything"]}]`,
+			wantErr: apierror.ErrInvalidConfig,
+		},
+		{
+			name:    "Duplicate hash",
+			file:    "keys.json",
+			content: `[{"name":"a","hash":"` + hash + `","scopes":["admin"]},{"name":"b","hash":"` + hash + `","scopes":["admin"]}]`,
+			wantErr: apierror.ErrInvalidConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			keys, err := LoadAPIKeys(path)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "expected %v, got %v", tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, keys, tt.want)
+		})
+	}
+
+	_, err := LoadAPIKeys(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.True(t, errors.Is(err, apierror.ErrConfigNotFound))
+
+	keys, err := LoadAPIKeys(filepath.Join("..", "..", "..", "configs", "api-keys.example.yaml"))
+	require.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// Authenticator คือวิธียืนยันตัวตนหนึ่งแบบ
+// คืนค่า nil, nil เมื่อ request ไม่มี credential ของวิธีนี้ เพื่อให้ Guard ลองวิธีถัดไป
+// และคืนค่า error เมื่อมี credential แต่ไม่ถูกต้อง
+type Authenticator interface {
+	Authenticate(c echo.Context) (*Principal, error)
+}
+
+// Guard ยืนยันตัวตนและตรวจสอบ scope ของแต่ละ route
+type Guard struct {
+	authenticators []Authenticator
+}
+
+// NewGuard สร้าง instance ใหม่ของ Guard ที่ลอง authenticator ตามลำดับ
+// Guard ที่ไม่มี authenticator คือปิดการยืนยันตัวตน ทุก request ผ่านได้
+func NewGuard(authenticators ...Authenticator) *Guard {
+	return &Guard{authenticators: authenticators}
+}
+
+// With คืนค่า Guard ใหม่ที่ลอง authenticator เพิ่มเติมต่อจากชุดเดิม (เช่น stream ticket เฉพาะ route ของ stream)
+func (g *Guard) With(authenticators ...Authenticator) *Guard {
+	combined := make([]Authenticator, 0, len(g.authenticators)+len(authenticators))
+	combined = append(combined, g.authenticators...)
+	return &Guard{authenticators: append(combined, authenticators...)}
+}
+
+// Enabled ตรวจสอบว่าเปิดการยืนยันตัวตนหรือไม่
+func (g *Guard) Enabled() bool {
+	return len(g.authenticators) > 0
+}
+
+// Require สร้าง echo middleware ที่ยอมให้ผ่านเฉพาะผู้เรียกที่มี scope ตามที่กำหนด
+// ไม่มี credential หรือ credential ไม่ถูกต้องตอบ 401 ส่วนมี credential แต่ไม่มีสิทธิ์ตอบ 403
+func (g *Guard) Require(scope Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if !g.Enabled() {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			principal, err := g.authenticate(c)
+			if err != nil {
+				return apierror.HandleAPIError(c, err)
+			}
+
+			if !principal.HasScope(scope) {
+				return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrForbidden,
+					fmt.Sprintf("scope %q required", scope)))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// authenticate คืนค่า Principal จาก authenticator แรกที่พบ credential และเก็บไว้ใน context
+func (g *Guard) authenticate(c echo.Context) (*Principal, error) {
+	if principal, ok := PrincipalFrom(c); ok {
+		return principal, nil
+	}
+
+	for _, authenticator := range g.authenticators {
+		principal, err := authenticator.Authenticate(c)
+		if err != nil {
+			return nil, err
+		}
+		if principal != nil {
+			c.Set(principalKey, principal)
+			return principal, nil
+		}
+	}
+
+	return nil, apierror.Wrap(apierror.ErrUnauthorized, "missing credentials")
+}
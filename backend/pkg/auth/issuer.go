@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// LocalIssuer คือ identity provider จำลองสำหรับทดสอบและพัฒนาในเครื่อง
+// ออก JWT ด้วย ECDSA P-256 key ที่สร้างขึ้นใหม่ทุกครั้งที่เริ่มต้น จึงไม่ควรใช้ใน production
+type LocalIssuer struct {
+	issuer   string
+	audience string
+	kid      string
+	key      *ecdsa.PrivateKey
+}
+
+// NewLocalIssuer สร้าง instance ใหม่ของ LocalIssuer ที่ออก token ด้วย iss และ aud ตามที่กำหนด
+func NewLocalIssuer(issuer string, audience string) (*LocalIssuer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrServerStartFailed, fmt.Sprintf("local issuer key: %v", err))
+	}
+
+	return &LocalIssuer{
+		issuer:   issuer,
+		audience: audience,
+		kid:      fmt.Sprintf("local-%d", time.Now().Unix()),
+		key:      key,
+	}, nil
+}
+
+// Issue ออก token ของ subject ที่หมดอายุใน ttl พร้อม claim เพิ่มเติม (เช่น roles)
+func (i *LocalIssuer) Issue(subject string, ttl time.Duration, extra map[string]interface{}) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims := jwt.MapClaims{}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	claims["sub"] = subject
+	claims["iat"] = now.Unix()
+	claims["exp"] = expiresAt.Unix()
+	if i.issuer != "" {
+		claims["iss"] = i.issuer
+	}
+	if i.audience != "" {
+		claims["aud"] = i.audience
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = i.kid
+
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Key คืนค่า public key ของ issuer ทำให้ใช้เป็น KeySet ของ JWTAuthenticator ได้โดยตรง
+func (i *LocalIssuer) Key(kid string) (crypto.PublicKey, error) {
+	if kid != i.kid {
+		return nil, apierror.Wrap(apierror.ErrUnauthorized, fmt.Sprintf("unknown signing key %q", kid))
+	}
+	return &i.key.PublicKey, nil
+}
+
+// JWKS คืนค่าเอกสาร JWKS ของ issuer สำหรับให้บริการที่ตรวจสอบ token ดึงไปใช้
+func (i *LocalIssuer) JWKS() ([]byte, error) {
+	size := (i.key.Curve.Params().BitSize + 7) / 8
+	return json.Marshal(jwkSet{Keys: []jwk{{
+		Kty: "EC",
+		Kid: i.kid,
+		Use: "sig",
+		Alg: jwt.SigningMethodES256.Alg(),
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(i.key.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(i.key.Y.FillBytes(make([]byte, size))),
+	}}})
+}
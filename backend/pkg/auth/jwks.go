@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// DefaultJWKSCacheTTL คือเวลาที่เก็บ key ที่โหลดจาก JWKS ไว้ก่อนโหลดใหม่
+const DefaultJWKSCacheTTL = 10 * time.Minute
+
+// jwksRefreshInterval คือระยะห่างขั้นต่ำของการโหลด JWKS ใหม่เมื่อพบ kid ที่ไม่รู้จัก
+// เพื่อไม่ให้ token ปลอมทำให้ต้องเรียก identity provider ทุก request
+const jwksRefreshInterval = 30 * time.Second
+
+// jwksFetchTimeout คือเวลาสูงสุดในการดึง JWKS จาก URL
+const jwksFetchTimeout = 10 * time.Second
+
+// KeySet คือแหล่งของ public key สำหรับตรวจสอบลายเซ็นของ JWT ตาม kid ใน header
+type KeySet interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// jwk คือ key หนึ่งตัวใน JWKS (รองรับ RSA และ EC)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jwkSet คือเอกสาร JWKS ตาม RFC 7517
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// ParseJWKS แปลงเอกสาร JWKS เป็น public key ตาม kid โดยข้าม key ที่ไม่ได้ใช้สำหรับลายเซ็นหรือไม่รองรับ
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set jwkSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("jwks: %v", err))
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("jwks key %q: %v", k.Kid, err))
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, "jwks has no usable signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey แปลง jwk เป็น public key คืนค่า nil สำหรับ kty ที่ไม่รองรับ
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// decodeBigInt แปลงค่า base64url (ไม่มี padding) ของ JWK เป็นตัวเลข
+func decodeBigInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing value")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// JWKS โหลด public key จากไฟล์หรือ URL ของ JWKS และเก็บไว้ตาม TTL
+// เมื่อพบ kid ที่ไม่รู้จัก (เช่น identity provider หมุน key) จะโหลดใหม่ก่อนหมด TTL ได้
+type JWKS struct {
+	source string
+	ttl    time.Duration
+	client *http.Client
+
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	mu          sync.Mutex
+}
+
+// NewJWKS สร้าง instance ใหม่ของ JWKS จาก path ของไฟล์หรือ URL (http/https)
+func NewJWKS(source string, ttl time.Duration) *JWKS {
+	if ttl <= 0 {
+		ttl = DefaultJWKSCacheTTL
+	}
+
+	return &JWKS{
+		source: source,
+		ttl:    ttl,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+	}
+}
+
+// Key คืนค่า public key ตาม kid
+// ถ้าโหลด JWKS ใหม่ไม่สำเร็จจะใช้ key ชุดเดิมต่อ และคืนค่า ErrServiceUnavailable เมื่อยังไม่เคยโหลดได้เลย
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	if j.keys == nil || now.Sub(j.fetchedAt) > j.ttl {
+		if err := j.refreshLocked(now); err != nil && j.keys == nil {
+			return nil, err
+		}
+	}
+
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+
+	if now.Sub(j.lastAttempt) >= jwksRefreshInterval {
+		if err := j.refreshLocked(now); err == nil {
+			if key, ok := j.keys[kid]; ok {
+				return key, nil
+			}
+		}
+	}
+
+	return nil, apierror.Wrap(apierror.ErrUnauthorized, fmt.Sprintf("unknown signing key %q", kid))
+}
+
+// Refresh โหลด JWKS ใหม่ทันที (ใช้ตรวจสอบการตั้งค่าตอนเริ่มต้น)
+func (j *JWKS) Refresh() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.refreshLocked(time.Now())
+}
+
+// refreshLocked โหลดและแทนที่ key ทั้งชุด (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (j *JWKS) refreshLocked(now time.Time) error {
+	j.lastAttempt = now
+
+	data, err := j.fetch()
+	if err != nil {
+		return apierror.Wrap(apierror.ErrServiceUnavailable, fmt.Sprintf("jwks %s: %v", j.source, err))
+	}
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return apierror.Wrap(apierror.ErrServiceUnavailable, err.Error())
+	}
+
+	j.keys = keys
+	j.fetchedAt = now
+	return nil
+}
+
+// fetch อ่าน JWKS จาก URL หรือไฟล์
+func (j *JWKS) fetch() ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(j.source)
+	}
+
+	resp, err := j.client.Get(j.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
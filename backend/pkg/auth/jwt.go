@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// MethodJWT คือค่า Principal.Method ของผู้เรียกที่ยืนยันตัวตนด้วย JWT
+const MethodJWT = "jwt"
+
+// DefaultRolesClaim คือ claim ที่เก็บ role ของผู้ใช้เมื่อไม่ได้กำหนด
+const DefaultRolesClaim = "roles"
+
+// DefaultTenantClaim คือ claim ที่เก็บ tenant ของผู้ใช้เมื่อไม่ได้กำหนด
+const DefaultTenantClaim = "tenant"
+
+// DefaultJWTLeeway คือความคลาดเคลื่อนของนาฬิการะหว่าง server กับ identity provider ที่ยอมรับเมื่อไม่ได้กำหนด
+const DefaultJWTLeeway = 30 * time.Second
+
+// signingMethods คือ algorithm ที่ยอมรับสำหรับ JWT จาก identity provider (ไม่ยอมรับ HMAC และ none)
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// JWTConfig กำหนดการตรวจสอบ JWT และการแปลง role เป็น scope
+type JWTConfig struct {
+	// Issuer คือค่า iss ที่ต้องตรงกัน (ว่างคือไม่ตรวจสอบ)
+	Issuer string
+
+	// Audience คือค่าที่ต้องอยู่ใน aud (ว่างคือไม่ตรวจสอบ)
+	Audience string
+
+	// RolesClaim คือ claim ที่เก็บ role รองรับ path แบบมีจุด เช่น realm_access.roles (ว่างใช้ DefaultRolesClaim)
+	RolesClaim string
+
+	// RoleScopes แปลง role เป็น scope
+	RoleScopes map[string][]Scope
+
+	// TenantClaim คือ claim ที่เก็บ tenant รองรับ path แบบมีจุดเหมือน RolesClaim (ว่างใช้ DefaultTenantClaim)
+	TenantClaim string
+
+	// Leeway คือความคลาดเคลื่อนของนาฬิกาที่ยอมรับได้สำหรับ exp, nbf และ iat (0 ใช้ DefaultJWTLeeway)
+	Leeway time.Duration
+}
+
+// ParseRoleScopes แปลงข้อความรูปแบบ "role=scope|scope,role=scope" เป็น map ของ role กับ scope
+func ParseRoleScopes(value string) (map[string][]Scope, error) {
+	roleScopes := make(map[string][]Scope)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		role, scopes, ok := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("role scopes %q: expected role=scope", entry))
+		}
+
+		for _, s := range strings.Split(scopes, "|") {
+			scope := Scope(strings.TrimSpace(s))
+			if !scope.Valid() {
+				return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("role %q: unknown scope %q", role, s))
+			}
+			roleScopes[role] = append(roleScopes[role], scope)
+		}
+	}
+	return roleScopes, nil
+}
+
+// JWTAuthenticator ยืนยันตัวตนด้วย bearer token ที่ลงนามโดย identity provider
+// scope ของผู้เรียกได้จาก role ใน RolesClaim ตาม RoleScopes รวมกับ scope ที่รู้จักใน claim scope
+type JWTAuthenticator struct {
+	keys   KeySet
+	config JWTConfig
+	parser *jwt.Parser
+}
+
+// NewJWTAuthenticator สร้าง instance ใหม่ของ JWTAuthenticator
+func NewJWTAuthenticator(keys KeySet, config JWTConfig) *JWTAuthenticator {
+	if config.RolesClaim == "" {
+		config.RolesClaim = DefaultRolesClaim
+	}
+	if config.TenantClaim == "" {
+		config.TenantClaim = DefaultTenantClaim
+	}
+	if config.Leeway <= 0 {
+		config.Leeway = DefaultJWTLeeway
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	return &JWTAuthenticator{
+		keys:   keys,
+		config: config,
+		parser: jwt.NewParser(options...),
+	}
+}
+
+// Authenticate ยืนยันตัวตนจาก header Authorization: Bearer คืนค่า nil ถ้า request ไม่มี bearer token
+func (a *JWTAuthenticator) Authenticate(c echo.Context) (*Principal, error) {
+	scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+	return a.Validate(strings.TrimSpace(token))
+}
+
+// Validate ตรวจสอบลายเซ็นและ claim ของ token แล้วคืนค่า Principal
+// tenant AllTenants ใน token ถูกปฏิเสธ เพราะสิทธิ์เลือก tenant จาก host ให้ได้เฉพาะ API key ที่ผู้ดูแลกำหนดในไฟล์ key
+func (a *JWTAuthenticator) Validate(raw string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, claims, a.keyFunc)
+	if err != nil {
+		if errors.Is(err, apierror.ErrServiceUnavailable) {
+			return nil, err
+		}
+		return nil, apierror.Wrap(apierror.ErrUnauthorized, fmt.Sprintf("invalid bearer token: %v", err))
+	}
+
+	subject, _ := claims.GetSubject()
+	expiresAt, _ := claims.GetExpirationTime()
+	tenant, _ := lookupClaim(claims, a.config.TenantClaim).(string)
+	if tenant == AllTenants {
+		return nil, apierror.Wrap(apierror.ErrUnauthorized, fmt.Sprintf("invalid bearer token: tenant %q is not allowed", tenant))
+	}
+
+	roles := stringList(lookupClaim(claims, a.config.RolesClaim))
+	scopes := make([]Scope, 0, len(roles))
+	seen := make(map[Scope]bool)
+	add := func(scope Scope) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, role := range roles {
+		for _, scope := range a.config.RoleScopes[role] {
+			add(scope)
+		}
+	}
+	for _, s := range stringList(claims["scope"]) {
+		if scope := Scope(s); scope.Valid() {
+			add(scope)
+		}
+	}
+
+	return &Principal{
+		Subject:   subject,
+		Method:    MethodJWT,
+		Roles:     roles,
+		Scopes:    scopes,
+		Tenant:    tenant,
+		ExpiresAt: expiresAt.Time,
+	}, nil
+}
+
+// keyFunc เลือก public key ตาม kid ใน header ของ token
+func (a *JWTAuthenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	return a.keys.Key(kid)
+}
+
+// lookupClaim อ่านค่า claim ตาม path ที่คั่นด้วยจุด
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+	return value
+}
+
+// stringList แปลงค่า claim ที่เป็น array ของ string หรือ string คั่นด้วยช่องว่างเป็น slice
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+func TestJWTAuthenticatorValidate(t *testing.T) {
+	issuer, err := NewLocalIssuer("https://sso.example.com", "sensor-dashboard")
+	require.NoError(t, err)
+	other, err := NewLocalIssuer("https://sso.example.com", "another-app")
+	require.NoError(t, err)
+
+	authenticator := NewJWTAuthenticator(issuer, JWTConfig{
+		Issuer:   "https://sso.example.com",
+		Audience: "sensor-dashboard",
+		RoleScopes: map[string][]Scope{
+			"viewer": {ScopeReadSensors},
+			"ingest": {ScopeWriteReadings},
+		},
+	})
+
+	issue := func(i *LocalIssuer, ttl time.Duration, claims map[string]interface{}) string {
+		token, _, err := i.Issue("alice", ttl, claims)
+		require.NoError(t, err)
+		return token
+	}
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "mallory",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("guess"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		token      string
+		wantScopes []Scope
+		wantErr    error
+	}{
+		{
+			name:       "Roles map to scopes",
+			token:      issue(issuer, time.Hour, map[string]interface{}{"roles": []string{"viewer", "ingest", "unmapped"}}),
+			wantScopes: []Scope{ScopeReadSensors, ScopeWriteReadings},
+		},
+		{
+			name:       "Known values in the scope claim are kept",
+			token:      issue(issuer, time.Hour, map[string]interface{}{"scope": "openid read:sensors"}),
+			wantScopes: []Scope{ScopeReadSensors},
+		},
+		{
+			name:       "No roles means no scopes",
+			token:      issue(issuer, time.Hour, nil),
+			wantScopes: []Scope{},
+		},
+		{
+			name:    "Expired token",
+			token:   issue(issuer, -time.Hour, nil),
+			wantErr: apierror.ErrUnauthorized,
+		},
+		{
+			name:    "Signed by another key",
+			token:   issue(other, time.Hour, nil),
+			wantErr: apierror.ErrUnauthorized,
+		},
+		{
+			name:    "HMAC token is rejected",
+			token:   hmacToken,
+			wantErr: apierror.ErrUnauthorized,
+		},
+		{
+			name:    "Malformed token",
+			token:   "not-a-jwt",
+			wantErr: apierror.ErrUnauthorized,
+		},
+		{
+			name:    "All-tenant claim is rejected",
+			token:   issue(issuer, time.Hour, map[string]interface{}{"roles": []string{"viewer"}, "tenant": AllTenants}),
+			wantErr: apierror.ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Validate(tt.token)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "expected %v, got %v", tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "alice", principal.Subject)
+			assert.Equal(t, MethodJWT, principal.Method)
+			assert.Equal(t, tt.wantScopes, principal.Scopes)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), principal.ExpiresAt, 2*time.Second)
+		})
+	}
+
+	t.Run("Audience mismatch", func(t *testing.T) {
+		wrongAudience, err := NewLocalIssuer("https://sso.example.com", "another-app")
+		require.NoError(t, err)
+		_, err = NewJWTAuthenticator(wrongAudience, JWTConfig{Audience: "sensor-dashboard"}).
+			Validate(issue(wrongAudience, time.Hour, nil))
+		assert.True(t, errors.Is(err, apierror.ErrUnauthorized))
+	})
+}
+
+func TestJWTAuthenticatorNestedRolesClaim(t *testing.T) {
+	issuer, err := NewLocalIssuer("", "")
+	require.NoError(t, err)
+
+	token, _, err := issuer.Issue("bob", time.Minute, map[string]interface{}{
+		"realm_access": map[string]interface{}{"roles": []string{"operator"}},
+		"org":          map[string]interface{}{"tenant": "acme"},
+	})
+	require.NoError(t, err)
+
+	principal, err := NewJWTAuthenticator(issuer, JWTConfig{
+		RolesClaim:  "realm_access.roles",
+		RoleScopes:  map[string][]Scope{"operator": {ScopeAdmin}},
+		TenantClaim: "org.tenant",
+	}).Validate(token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"operator"}, principal.Roles)
+	assert.True(t, principal.HasScope(ScopeWriteReadings))
+	assert.Equal(t, "acme", principal.Tenant)
+}
+
+func TestParseRoleScopes(t *testing.T) {
+	roleScopes, err := ParseRoleScopes("viewer=read:sensors, operator=read:sensors|write:readings,,admin=admin")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]Scope{
+		"viewer":   {ScopeReadSensors},
+		"operator": {ScopeReadSensors, ScopeWriteReadings},
+		"admin":    {ScopeAdmin},
+	}, roleScopes)
+
+	for _, invalid := range []string{"viewer", "=admin", "viewer=read:everything"} {
+		_, err := ParseRoleScopes(invalid)
+		assert.True(t, errors.Is(err, apierror.ErrInvalidConfig), invalid)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	issuer, err := NewLocalIssuer("", "")
+	require.NoError(t, err)
+	jwks, err := issuer.JWKS()
+	require.NoError(t, err)
+
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(jwks)
+	}))
+	defer server.Close()
+
+	token, _, err := issuer.Issue("alice", time.Minute, nil)
+	require.NoError(t, err)
+
+	t.Run("URL source is cached", func(t *testing.T) {
+		authenticator := NewJWTAuthenticator(NewJWKS(server.URL, time.Hour), JWTConfig{})
+		for i := 0; i < 3; i++ {
+			_, err := authenticator.Validate(token)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("Unknown kid refreshes at most once per interval", func(t *testing.T) {
+		fetches.Store(0)
+		keys := NewJWKS(server.URL, time.Hour)
+		require.NoError(t, keys.Refresh())
+
+		for i := 0; i < 3; i++ {
+			_, err := keys.Key("rotated-away")
+			assert.True(t, errors.Is(err, apierror.ErrUnauthorized))
+		}
+		assert.Equal(t, int32(1), fetches.Load())
+	})
+
+	t.Run("File source", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		require.NoError(t, os.WriteFile(path, jwks, 0o644))
+
+		_, err := NewJWTAuthenticator(NewJWKS(path, 0), JWTConfig{}).Validate(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unreachable JWKS is a server error", func(t *testing.T) {
+		_, err := NewJWTAuthenticator(NewJWKS(filepath.Join(t.TempDir(), "missing.json"), 0), JWTConfig{}).Validate(token)
+		assert.True(t, errors.Is(err, apierror.ErrServiceUnavailable), "got %v", err)
+	})
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	data, err := json.Marshal(jwkSet{Keys: []jwk{
+		{Kty: "RSA", Kid: "rsa-1", Use: "sig", N: encode(rsaKey.N.Bytes()), E: encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{Kty: "RSA", Kid: "enc-1", Use: "enc", N: encode(rsaKey.N.Bytes()), E: "AQAB"},
+		{Kty: "oct", Kid: "hmac-1"},
+	}})
+	require.NoError(t, err)
+
+	keys, err := ParseJWKS(data)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, rsaKey.PublicKey.Equal(keys["rsa-1"]))
+
+	_, err = ParseJWKS([]byte(`{"keys":[{"kty":"EC","kid":"bad","crv":"P-256","x":"AQ","y":"AQ"}]}`))
+	assert.True(t, errors.Is(err, apierror.ErrInvalidConfig))
+}
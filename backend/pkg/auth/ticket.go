@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// QueryStreamTicket คือ query parameter ที่ใช้ส่ง stream ticket ตอนเปิด SSE stream
+const QueryStreamTicket = "ticket"
+
+// MethodStreamTicket คือค่า Principal.Method ของผู้เรียกที่เปิด stream ด้วย ticket
+const MethodStreamTicket = "stream_ticket"
+
+// DefaultStreamTicketTTL คืออายุของ stream ticket เมื่อไม่ได้กำหนด
+const DefaultStreamTicketTTL = 30 * time.Second
+
+// ticketAudience คือ aud ของ ticket เพื่อไม่ให้ใช้ ticket แทน token ของ endpoint อื่น
+const ticketAudience = "sensor-stream"
+
+// ticketClaims คือ claim ของ stream ticket
+type ticketClaims struct {
+	Method string  `json:"method"`
+	Scopes []Scope `json:"scopes"`
+	Tenant string  `json:"tenant,omitempty"`
+
+	// TokenExpiresAt คือเวลาหมดอายุของ credential ที่ใช้ขอ ticket (stream จะปิดเมื่อถึงเวลานี้)
+	TokenExpiresAt *jwt.NumericDate `json:"token_exp,omitempty"`
+
+	jwt.RegisteredClaims
+}
+
+// TicketIssuer ออกและตรวจสอบ stream ticket ซึ่งเป็น token อายุสั้นที่ลงนามด้วย HMAC ของ server เอง
+// ใช้แทนการส่ง bearer token ใน URL ของ EventSource ที่ตั้ง header เองไม่ได้
+type TicketIssuer struct {
+	secret []byte
+	ttl    time.Duration
+	parser *jwt.Parser
+}
+
+// NewTicketIssuer สร้าง instance ใหม่ของ TicketIssuer
+// secret ว่างจะสุ่มขึ้นใหม่ ซึ่ง ticket จะใช้ได้เฉพาะกับ instance ที่ออกเท่านั้น
+func NewTicketIssuer(secret []byte, ttl time.Duration) (*TicketIssuer, error) {
+	if ttl <= 0 {
+		ttl = DefaultStreamTicketTTL
+	}
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, apierror.Wrap(apierror.ErrServerStartFailed, fmt.Sprintf("stream ticket secret: %v", err))
+		}
+	}
+
+	return &TicketIssuer{
+		secret: secret,
+		ttl:    ttl,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithAudience(ticketAudience),
+			jwt.WithExpirationRequired(),
+		),
+	}, nil
+}
+
+// Issue ออก ticket ให้ principal ที่ยืนยันตัวตนแล้ว ticket หมดอายุภายใน TTL หรือพร้อมกับ credential เดิมถ้าเร็วกว่า
+func (t *TicketIssuer) Issue(principal *Principal) (string, time.Time, error) {
+	if !allTenantsAllowed(principal.Method, principal.Tenant) {
+		return "", time.Time{}, apierror.Wrap(apierror.ErrForbidden, fmt.Sprintf("tenant %q is not allowed", principal.Tenant))
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+	if !principal.ExpiresAt.IsZero() && principal.ExpiresAt.Before(expiresAt) {
+		expiresAt = principal.ExpiresAt
+	}
+
+	claims := ticketClaims{
+		Method: principal.Method,
+		Scopes: principal.Scopes,
+		Tenant: principal.Tenant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   principal.Subject,
+			Audience:  jwt.ClaimStrings{ticketAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	if !principal.ExpiresAt.IsZero() {
+		claims.TokenExpiresAt = jwt.NewNumericDate(principal.ExpiresAt)
+	}
+
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return ticket, expiresAt, nil
+}
+
+// Authenticate ยืนยันตัวตนจาก query ticket คืนค่า nil ถ้า request ไม่มี ticket
+// Principal ที่ได้มี ExpiresAt เป็นเวลาหมดอายุของ credential เดิม ไม่ใช่ของ ticket
+func (t *TicketIssuer) Authenticate(c echo.Context) (*Principal, error) {
+	raw := c.QueryParam(QueryStreamTicket)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var claims ticketClaims
+	_, err := t.parser.ParseWithClaims(raw, &claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	})
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrUnauthorized, fmt.Sprintf("invalid stream ticket: %v", err))
+	}
+	if !allTenantsAllowed(claims.Method, claims.Tenant) {
+		return nil, apierror.Wrap(apierror.ErrUnauthorized, fmt.Sprintf("invalid stream ticket: tenant %q is not allowed", claims.Tenant))
+	}
+
+	principal := &Principal{
+		Subject: claims.Subject,
+		Method:  MethodStreamTicket,
+		Scopes:  claims.Scopes,
+		Tenant:  claims.Tenant,
+	}
+	if claims.TokenExpiresAt != nil {
+		principal.ExpiresAt = claims.TokenExpiresAt.Time
+	}
+	return principal, nil
+}
+
+// allTenantsAllowed ตรวจสอบว่า credential ที่ได้ด้วย method ใช้ tenant นี้ใน ticket ได้
+// AllTenants ให้ได้เฉพาะ API key ที่ผู้ดูแลกำหนดในไฟล์ key ไม่ใช่ claim ของ JWT
+func allTenantsAllowed(method string, tenant string) bool {
+	return tenant != AllTenants || method == MethodAPIKey
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+func TestTicketIssuer(t *testing.T) {
+	tickets, err := NewTicketIssuer([]byte("shared-secret"), time.Minute)
+	require.NoError(t, err)
+
+	authenticate := func(ticket string) (*Principal, error) {
+		req := httptest.NewRequest(http.MethodGet, "/api/sensors/stream?"+QueryStreamTicket+"="+url.QueryEscape(ticket), nil)
+		return tickets.Authenticate(echo.New().NewContext(req, httptest.NewRecorder()))
+	}
+
+	t.Run("Ticket carries the principal and its token expiry", func(t *testing.T) {
+		tokenExpiry := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+		ticket, expiresAt, err := tickets.Issue(&Principal{
+			Subject:   "alice",
+			Method:    MethodJWT,
+			Scopes:    []Scope{ScopeReadSensors},
+			Tenant:    "acme",
+			ExpiresAt: tokenExpiry,
+		})
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, 2*time.Second)
+
+		principal, err := authenticate(ticket)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", principal.Subject)
+		assert.Equal(t, MethodStreamTicket, principal.Method)
+		assert.Equal(t, []Scope{ScopeReadSensors}, principal.Scopes)
+		assert.Equal(t, "acme", principal.Tenant)
+		assert.True(t, tokenExpiry.Equal(principal.ExpiresAt))
+	})
+
+	t.Run("Ticket never outlives the token", func(t *testing.T) {
+		tokenExpiry := time.Now().Add(10 * time.Second)
+		_, expiresAt, err := tickets.Issue(&Principal{Subject: "alice", ExpiresAt: tokenExpiry})
+		require.NoError(t, err)
+		assert.Equal(t, tokenExpiry, expiresAt)
+	})
+
+	t.Run("API key tickets have no stream expiry", func(t *testing.T) {
+		ticket, _, err := tickets.Issue(&Principal{Subject: "dashboard", Method: MethodAPIKey, Scopes: []Scope{ScopeReadSensors}})
+		require.NoError(t, err)
+
+		principal, err := authenticate(ticket)
+		require.NoError(t, err)
+		assert.True(t, principal.ExpiresAt.IsZero())
+	})
+
+	t.Run("Only API key tickets carry all tenants", func(t *testing.T) {
+		ticket, _, err := tickets.Issue(&Principal{Subject: "operator", Method: MethodAPIKey, Scopes: []Scope{ScopeReadSensors}, Tenant: AllTenants})
+		require.NoError(t, err)
+		principal, err := authenticate(ticket)
+		require.NoError(t, err)
+		assert.Equal(t, AllTenants, principal.Tenant)
+
+		_, _, err = tickets.Issue(&Principal{Subject: "alice", Method: MethodJWT, Tenant: AllTenants})
+		assert.True(t, errors.Is(err, apierror.ErrForbidden))
+
+		// ticket ที่ลงนามด้วย secret เดียวกันแต่อ้างว่าเป็นของ JWT ที่มี tenant "*" ต้องถูกปฏิเสธ
+		forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, ticketClaims{
+			Method: MethodJWT,
+			Tenant: AllTenants,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "alice",
+				Audience:  jwt.ClaimStrings{ticketAudience},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+		}).SignedString([]byte("shared-secret"))
+		require.NoError(t, err)
+		_, err = authenticate(forged)
+		assert.True(t, errors.Is(err, apierror.ErrUnauthorized))
+	})
+
+	t.Run("Ticket from another secret is rejected", func(t *testing.T) {
+		other, err := NewTicketIssuer(nil, time.Minute)
+		require.NoError(t, err)
+		ticket, _, err := other.Issue(&Principal{Subject: "alice"})
+		require.NoError(t, err)
+
+		_, err = authenticate(ticket)
+		assert.True(t, errors.Is(err, apierror.ErrUnauthorized))
+	})
+
+	t.Run("Bearer token is not accepted as a ticket", func(t *testing.T) {
+		issuer, err := NewLocalIssuer("", ticketAudience)
+		require.NoError(t, err)
+		token, _, err := issuer.Issue("alice", time.Minute, nil)
+		require.NoError(t, err)
+
+		_, err = authenticate(token)
+		assert.True(t, errors.Is(err, apierror.ErrUnauthorized))
+	})
+}
+
+func TestGuardWithTicket(t *testing.T) {
+	tickets, err := NewTicketIssuer(nil, time.Minute)
+	require.NoError(t, err)
+	ticket, _, err := tickets.Issue(&Principal{Subject: "alice", Scopes: []Scope{ScopeReadSensors}})
+	require.NoError(t, err)
+
+	guard := NewGuard(NewKeyStore(nil))
+	e := echo.New()
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/api/sensors/stream", ok, guard.With(tickets).Require(ScopeReadSensors))
+	e.GET("/api/sensors", ok, guard.Require(ScopeReadSensors))
+
+	for path, want := range map[string]int{
+		"/api/sensors/stream": http.StatusOK,
+		"/api/sensors":        http.StatusUnauthorized,
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"?"+QueryStreamTicket+"="+ticket, nil))
+		assert.Equal(t, want, rec.Code, path)
+	}
+}
@@ -1,16 +1,65 @@
 package cache
 
 import (
+	"container/list"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/logger"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
 )
 
+// ค่าเริ่มต้นของ cache ที่สร้างด้วย NewCache
+const (
+	DefaultMaxEntries      = 10000
+	DefaultMaxBytes        = 64 << 20
+	DefaultCleanupInterval = time.Minute
+)
+
+// EvictionReason คือสาเหตุที่ข้อมูลถูกนำออกจาก cache
+type EvictionReason string
+
+const (
+	// EvictionCapacity ข้อมูลที่ใช้งานน้อยที่สุดถูกนำออกเพราะ cache เต็ม
+	EvictionCapacity EvictionReason = "capacity"
+
+	// EvictionExpired ข้อมูลหมดอายุ
+	EvictionExpired EvictionReason = "expired"
+)
+
+// EvictionFunc คือ callback ที่ถูกเรียกทุกครั้งที่ข้อมูลถูกนำออกจาก cache โดยอัตโนมัติ
+// (ไม่ถูกเรียกเมื่อผู้ใช้ Delete หรือ Clear เอง) และถูกเรียกหลังจากปล่อย lock แล้ว
+type EvictionFunc func(key string, value []byte, reason EvictionReason)
+
+// Config กำหนดขนาดสูงสุดและพฤติกรรมของ cache ค่าที่เป็น 0 หมายถึงไม่จำกัด
+type Config struct {
+	// MaxEntries คือจำนวนข้อมูลสูงสุดใน cache
+	MaxEntries int
+
+	// MaxBytes คือขนาดรวมสูงสุดของ key และ value ใน cache
+	MaxBytes int64
+
+	// CleanupInterval คือระยะเวลาระหว่างการล้างข้อมูลที่หมดอายุ (0 ใช้ DefaultCleanupInterval)
+	CleanupInterval time.Duration
+
+	// OnEvict ถูกเรียกเมื่อข้อมูลถูกนำออกเพราะ cache เต็มหรือหมดอายุ
+	OnEvict EvictionFunc
+}
+
+// Stats คือสถิติการทำงานของ cache
+type Stats struct {
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
 // CacheItem เป็นโครงสร้างที่เก็บข้อมูลและเวลาหมดอายุของ cache
 type CacheItem struct {
+	key        string
 	value      []byte
 	expiration time.Time
 }
@@ -20,16 +69,50 @@ func (item *CacheItem) IsExpired() bool {
 	return time.Now().After(item.expiration)
 }
 
-// Cache เป็นโครงสร้างที่ใช้จัดการข้อมูล cache
+// size คือจำนวน byte ที่ item ใช้นับรวมใน MaxBytes
+func (item *CacheItem) size() int64 {
+	return int64(len(item.key) + len(item.value))
+}
+
+// evicted คือข้อมูลที่ถูกนำออกและรอเรียก OnEvict หลังปล่อย lock
+type evicted struct {
+	item   *CacheItem
+	reason EvictionReason
+}
+
+// Cache เป็นโครงสร้างที่ใช้จัดการข้อมูล cache แบบจำกัดขนาด
+// เมื่อเกินขนาดที่กำหนดจะนำข้อมูลที่ถูกใช้งานล่าสุดนานที่สุดออกก่อน (LRU)
 type Cache struct {
-	items map[string]CacheItem
-	mu    sync.RWMutex
+	config Config
+	items  map[string]*list.Element
+	order  *list.List // ด้านหน้าคือข้อมูลที่ถูกใช้งานล่าสุด
+	bytes  int64
+	stats  Stats
+	mu     sync.Mutex
+
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-// NewCache สร้าง instance ใหม่ของ Cache
+// NewCache สร้าง instance ใหม่ของ Cache ด้วยขนาดเริ่มต้น
 func NewCache() *Cache {
+	return NewCacheWithConfig(Config{
+		MaxEntries: DefaultMaxEntries,
+		MaxBytes:   DefaultMaxBytes,
+	})
+}
+
+// NewCacheWithConfig สร้าง instance ใหม่ของ Cache ตาม Config
+func NewCacheWithConfig(config Config) *Cache {
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = DefaultCleanupInterval
+	}
+
 	cache := &Cache{
-		items: make(map[string]CacheItem),
+		config: config,
+		items:  make(map[string]*list.Element),
+		order:  list.New(),
+		done:   make(chan struct{}),
 	}
 
 	// เริ่ม goroutine สำหรับการล้าง cache ที่หมดอายุ
@@ -39,32 +122,58 @@ func NewCache() *Cache {
 }
 
 // Set บันทึกข้อมูลลงใน cache พร้อมกำหนดเวลาหมดอายุ
+// ข้อมูลที่ใหญ่กว่า MaxBytes จะไม่ถูกเก็บ
 func (c *Cache) Set(key string, value []byte, ttl time.Duration) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.items[key] = CacheItem{
+	item := &CacheItem{
+		key:        key,
 		value:      value,
 		expiration: time.Now().Add(ttl),
 	}
+
+	c.mu.Lock()
+	if elem, found := c.items[key]; found {
+		c.removeElement(elem)
+	}
+
+	var removed []evicted
+	if c.config.MaxBytes <= 0 || item.size() <= c.config.MaxBytes {
+		c.items[key] = c.order.PushFront(item)
+		c.bytes += item.size()
+		removed = c.evictOverCapacity()
+	}
+	c.mu.Unlock()
+
+	c.notifyEvicted(removed)
 }
 
-// Get ดึงข้อมูลจาก cache และตรวจสอบอายุ
+// Get ดึงข้อมูลจาก cache และตรวจสอบอายุ ข้อมูลที่พบจะถูกย้ายเป็นข้อมูลที่ใช้งานล่าสุด
 func (c *Cache) Get(key string) ([]byte, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
 
-	item, found := c.items[key]
+	elem, found := c.items[key]
 	if !found {
+		c.stats.Misses++
+		c.mu.Unlock()
+		metrics.CacheMissesTotal.Inc()
 		return nil, false
 	}
 
-	// ตรวจสอบว่า cache หมดอายุหรือยัง
+	// ตรวจสอบว่า cache หมดอายุหรือยัง ถ้าหมดอายุแล้วนำออกทันทีไม่ต้องรอ cleaner
+	item := elem.Value.(*CacheItem)
 	if item.IsExpired() {
-		// ไม่ต้องลบออกตรงนี้ เพราะจะถูกล้างโดย cleaner goroutine
+		c.stats.Misses++
+		removed := c.evict(elem, EvictionExpired)
+		c.mu.Unlock()
+		metrics.CacheMissesTotal.Inc()
+		c.notifyEvicted([]evicted{removed})
 		return nil, false
 	}
 
+	c.order.MoveToFront(elem)
+	c.stats.Hits++
+	c.mu.Unlock()
+
+	metrics.CacheHitsTotal.Inc()
 	return item.value, true
 }
 
@@ -73,7 +182,9 @@ func (c *Cache) Delete(key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	delete(c.items, key)
+	if elem, found := c.items[key]; found {
+		c.removeElement(elem)
+	}
 }
 
 // Clear ล้างข้อมูลทั้งหมดใน cache
@@ -81,28 +192,102 @@ func (c *Cache) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.items = make(map[string]CacheItem)
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+}
+
+// Stats คืนค่าสถิติการทำงานของ cache
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.items)
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// Close หยุด goroutine ที่ล้าง cache ที่หมดอายุ
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
 }
 
 // startCleaner รันในรูปแบบของ goroutine เพื่อล้าง cache ที่หมดอายุเป็นระยะๆ
 func (c *Cache) startCleaner() {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(c.config.CleanupInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		c.cleanExpired()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.cleanExpired()
+		}
 	}
 }
 
 // cleanExpired ล้าง cache ที่หมดอายุออกจากระบบ
 func (c *Cache) cleanExpired() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
-	for key, item := range c.items {
-		if item.IsExpired() {
-			delete(c.items, key)
-			logger.Debug("Cache entry expired and removed", zap.String("key", key))
+	var removed []evicted
+	for _, elem := range c.items {
+		if elem.Value.(*CacheItem).IsExpired() {
+			removed = append(removed, c.evict(elem, EvictionExpired))
 		}
 	}
+	c.mu.Unlock()
+
+	for _, r := range removed {
+		logger.Debug("Cache entry expired and removed", zap.String("key", r.item.key))
+	}
+	c.notifyEvicted(removed)
+}
+
+// evictOverCapacity นำข้อมูลที่ใช้งานล่าสุดนานที่สุดออกจนกว่าจะไม่เกินขนาดที่กำหนด (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (c *Cache) evictOverCapacity() []evicted {
+	var removed []evicted
+	for c.overCapacity() {
+		removed = append(removed, c.evict(c.order.Back(), EvictionCapacity))
+	}
+	return removed
+}
+
+// overCapacity ตรวจสอบว่า cache เกินขนาดที่กำหนดหรือไม่ (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (c *Cache) overCapacity() bool {
+	if c.order.Len() == 0 {
+		return false
+	}
+	return (c.config.MaxEntries > 0 && c.order.Len() > c.config.MaxEntries) ||
+		(c.config.MaxBytes > 0 && c.bytes > c.config.MaxBytes)
+}
+
+// evict นำข้อมูลออกและนับเป็น eviction (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (c *Cache) evict(elem *list.Element, reason EvictionReason) evicted {
+	item := c.removeElement(elem)
+	c.stats.Evictions++
+	metrics.CacheEvictionsTotal.WithLabelValues(string(reason)).Inc()
+	return evicted{item: item, reason: reason}
+}
+
+// removeElement นำข้อมูลออกจาก map และ list (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (c *Cache) removeElement(elem *list.Element) *CacheItem {
+	item := c.order.Remove(elem).(*CacheItem)
+	delete(c.items, item.key)
+	c.bytes -= item.size()
+	return item
+}
+
+// notifyEvicted เรียก OnEvict สำหรับข้อมูลที่ถูกนำออก (ต้องเรียกหลังปล่อย lock)
+func (c *Cache) notifyEvicted(removed []evicted) {
+	if c.config.OnEvict == nil {
+		return
+	}
+	for _, r := range removed {
+		c.config.OnEvict(r.item.key, r.item.value, r.reason)
+	}
 }
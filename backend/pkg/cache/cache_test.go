@@ -0,0 +1,153 @@
+package cache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/cache"
+)
+
+// evictionLog เก็บ key ที่ถูกนำออกผ่าน OnEvict
+type evictionLog struct {
+	mu      sync.Mutex
+	reasons map[string]cache.EvictionReason
+}
+
+func newEvictionLog() *evictionLog {
+	return &evictionLog{reasons: make(map[string]cache.EvictionReason)}
+}
+
+func (l *evictionLog) record(key string, _ []byte, reason cache.EvictionReason) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reasons[key] = reason
+}
+
+// TestCacheLRU ทดสอบการนำข้อมูลออกเมื่อเกินขนาดที่กำหนด
+func TestCacheLRU(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  cache.Config
+		set     []string
+		get     []string // key ที่ถูกอ่านหลังจาก set ครบ (ทำให้เป็นข้อมูลที่ใช้งานล่าสุด)
+		then    []string // key ที่ถูก set ต่อหลังจากอ่าน
+		kept    []string
+		evicted []string
+	}{
+		{
+			name:    "max entries evicts least recently set",
+			config:  cache.Config{MaxEntries: 2},
+			set:     []string{"a", "b", "c"},
+			kept:    []string{"b", "c"},
+			evicted: []string{"a"},
+		},
+		{
+			name:    "get refreshes recency",
+			config:  cache.Config{MaxEntries: 2},
+			set:     []string{"a", "b"},
+			get:     []string{"a"},
+			then:    []string{"c"},
+			kept:    []string{"a", "c"},
+			evicted: []string{"b"},
+		},
+		{
+			// แต่ละ item ใช้ 1 byte ของ key + 4 byte ของ value
+			name:    "max bytes",
+			config:  cache.Config{MaxBytes: 12},
+			set:     []string{"a", "b", "c"},
+			kept:    []string{"b", "c"},
+			evicted: []string{"a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			log := newEvictionLog()
+			tc.config.OnEvict = log.record
+			c := cache.NewCacheWithConfig(tc.config)
+			defer c.Close()
+
+			for _, key := range tc.set {
+				c.Set(key, []byte("data"), time.Minute)
+			}
+			for _, key := range tc.get {
+				_, found := c.Get(key)
+				assert.True(t, found)
+			}
+			for _, key := range tc.then {
+				c.Set(key, []byte("data"), time.Minute)
+			}
+
+			for _, key := range tc.kept {
+				_, found := c.Get(key)
+				assert.True(t, found, "expected %s to be kept", key)
+			}
+			for _, key := range tc.evicted {
+				_, found := c.Get(key)
+				assert.False(t, found, "expected %s to be evicted", key)
+				assert.Equal(t, cache.EvictionCapacity, log.reasons[key])
+			}
+			assert.Equal(t, uint64(len(tc.evicted)), c.Stats().Evictions)
+		})
+	}
+}
+
+// TestCacheExpiration ทดสอบว่าข้อมูลที่หมดอายุถูกนำออกทันทีเมื่ออ่าน
+func TestCacheExpiration(t *testing.T) {
+	log := newEvictionLog()
+	c := cache.NewCacheWithConfig(cache.Config{OnEvict: log.record})
+	defer c.Close()
+
+	c.Set("short", []byte("data"), time.Millisecond)
+	c.Set("long", []byte("data"), time.Minute)
+	time.Sleep(5 * time.Millisecond)
+
+	_, found := c.Get("short")
+	assert.False(t, found)
+	assert.Equal(t, cache.EvictionExpired, log.reasons["short"])
+
+	stats := c.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Evictions)
+}
+
+// TestCacheAPI ทดสอบ Get/Set/Delete/Clear และสถิติ
+func TestCacheAPI(t *testing.T) {
+	log := newEvictionLog()
+	c := cache.NewCacheWithConfig(cache.Config{MaxBytes: 100, OnEvict: log.record})
+	defer c.Close()
+
+	c.Set("a", []byte("one"), time.Minute)
+	c.Set("a", []byte("two"), time.Minute)
+	value, found := c.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, []byte("two"), value)
+
+	// ข้อมูลที่ใหญ่กว่า MaxBytes ไม่ถูกเก็บ
+	c.Set("big", make([]byte, 200), time.Minute)
+	_, found = c.Get("big")
+	assert.False(t, found)
+
+	c.Set("b", []byte("three"), time.Minute)
+	c.Delete("a")
+	_, found = c.Get("a")
+	assert.False(t, found)
+
+	stats := c.Stats()
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, int64(len("b")+len("three")), stats.Bytes)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+
+	c.Clear()
+	stats = c.Stats()
+	assert.Equal(t, 0, stats.Entries)
+	assert.Equal(t, int64(0), stats.Bytes)
+
+	// Delete และ Clear ไม่ใช่ eviction
+	assert.Empty(t, log.reasons)
+}
@@ -16,10 +16,43 @@ import (
 const (
 	DefaultPort           = 8080
 	DefaultMaxConnections = 10000
+	DefaultMaxConnsPerIP  = 20
 	DefaultReadTimeout    = 5 * time.Minute
 	DefaultWriteTimeout   = 10 * time.Minute
 	DefaultIdleTimeout    = 2 * time.Minute
 	DefaultMaxHeaderBytes = 1 << 20 // 1MB
+
+	DefaultSSEResyncInterval = 1 * time.Minute
+	DefaultCacheTTL          = 30 * time.Second
+
+	DefaultAuthJWKSCacheTTL    = 10 * time.Minute
+	DefaultAuthJWTRolesClaim   = "roles"
+	DefaultAuthJWTTenantClaim  = "tenant"
+	DefaultAuthJWTRoleScopes   = "viewer=read:sensors,ingest=write:readings,admin=admin"
+	DefaultAuthStreamTicketTTL = 30 * time.Second
+
+	DefaultTenant = "default"
+
+	DefaultMQTTBrokerURL            = "tcp://localhost:1883"
+	DefaultMQTTTopics               = "sensors/+/telemetry"
+	DefaultMQTTQoS                  = 1
+	DefaultMQTTMaxReconnectInterval = 1 * time.Minute
+
+	DefaultStorageDriver             = "memory"
+	DefaultStoragePath               = "./data"
+	DefaultStorageRawRetention       = 7 * 24 * time.Hour
+	DefaultStorageMinuteRetention    = 90 * 24 * time.Hour
+	DefaultStorageHourRetention      = 2 * 365 * 24 * time.Hour
+	DefaultStorageCompactionInterval = 10 * time.Minute
+
+	DefaultWebhookMaxAttempts    = 5
+	DefaultWebhookInitialBackoff = 1 * time.Second
+	DefaultWebhookTimeout        = 5 * time.Second
+
+	DefaultWatchdogCheckInterval    = 5 * time.Second
+	DefaultWatchdogExpectedInterval = 10 * time.Second
+	DefaultWatchdogStaleAfterMissed = 3
+	DefaultWatchdogOfflineAfter     = 5 * time.Minute
 )
 
 type Environment string
@@ -52,12 +85,119 @@ type Config struct {
 	StaticPath     string      `mapstructure:"APP_STATIC_PATH" validate:"required,direxists"`
 	Env            Environment `mapstructure:"APP_ENV" validate:"required,oneof=dev uat prod"`
 	MaxConnections int         `mapstructure:"APP_MAX_CONNECTIONS" validate:"required,min=10,max=100000"`
+	MaxConnsPerIP  int         `mapstructure:"APP_MAX_CONNECTIONS_PER_IP" validate:"min=0"` // 0 disables the per-IP SSE stream cap
 
 	ReadTimeout    time.Duration `mapstructure:"APP_READ_TIMEOUT" validate:"required,min=1s"`
 	WriteTimeout   time.Duration `mapstructure:"APP_WRITE_TIMEOUT" validate:"required,min=1s"`
 	IdleTimeout    time.Duration `mapstructure:"APP_IDLE_TIMEOUT" validate:"required,min=1s"`
 	MaxHeaderBytes int           `mapstructure:"APP_MAX_HEADER_BYTES" validate:"required,min=1024"`
 
+	// Generate random readings for the built-in mock sensors
+	MockData bool `mapstructure:"APP_MOCK_DATA"`
+
+	// Scenario-driven simulator, replaces the mock data loop when enabled
+	SimulatorEnabled      bool   `mapstructure:"APP_SIMULATOR_ENABLED"`
+	SimulatorScenarioFile string `mapstructure:"APP_SIMULATOR_SCENARIO_FILE" validate:"required_if=SimulatorEnabled true"`
+
+	// Record readings or SSE events to a line-delimited file, empty disables recording
+	RecordFile string `mapstructure:"APP_RECORD_FILE"`
+	RecordMode string `mapstructure:"APP_RECORD_MODE" validate:"oneof=readings events"`
+
+	// Replay a recording through the repository instead of mock/simulated data, empty disables replay
+	ReplayFile  string  `mapstructure:"APP_REPLAY_FILE"`
+	ReplaySpeed float64 `mapstructure:"APP_REPLAY_SPEED" validate:"gt=0"`
+	ReplayStep  bool    `mapstructure:"APP_REPLAY_STEP"`
+	ReplayLoop  bool    `mapstructure:"APP_REPLAY_LOOP"`
+
+	// Request rate limiting per route group, policies default to built-in API/stream limits when no file is set
+	RateLimitEnabled bool   `mapstructure:"APP_RATE_LIMIT_ENABLED"`
+	RateLimitFile    string `mapstructure:"APP_RATE_LIMIT_FILE"`
+	RateLimitExempt  string `mapstructure:"APP_RATE_LIMIT_EXEMPT"`
+
+	// Reverse proxies (comma-separated IPs or CIDRs) whose X-Forwarded-For is trusted for the client IP used by the
+	// rate and connection limiters; empty uses the connection's address so clients cannot spoof their IP with headers
+	TrustedProxies string `mapstructure:"APP_TRUSTED_PROXIES"`
+
+	// Require credentials with the route's scope on every /api endpoint, API keys and JWTs can be enabled together;
+	// keys are read from a JSON or YAML file of SHA-256 hashes
+	AuthEnabled  bool   `mapstructure:"APP_AUTH_ENABLED"`
+	AuthKeysFile string `mapstructure:"APP_AUTH_KEYS_FILE"`
+
+	// Bearer JWT validation against a JWKS file or URL, role claims map to scopes with "role=scope|scope,..."
+	// The local issuer (dev only) signs test tokens and serves its own JWKS instead of AuthJWTJWKS
+	AuthJWTEnabled      bool          `mapstructure:"APP_AUTH_JWT_ENABLED"`
+	AuthJWTJWKS         string        `mapstructure:"APP_AUTH_JWT_JWKS"`
+	AuthJWTJWKSCacheTTL time.Duration `mapstructure:"APP_AUTH_JWT_JWKS_CACHE_TTL" validate:"min=0"`
+	AuthJWTIssuer       string        `mapstructure:"APP_AUTH_JWT_ISSUER"`
+	AuthJWTAudience     string        `mapstructure:"APP_AUTH_JWT_AUDIENCE"`
+	AuthJWTRolesClaim   string        `mapstructure:"APP_AUTH_JWT_ROLES_CLAIM"`
+	AuthJWTTenantClaim  string        `mapstructure:"APP_AUTH_JWT_TENANT_CLAIM"`
+	AuthJWTRoleScopes   string        `mapstructure:"APP_AUTH_JWT_ROLE_SCOPES"`
+	AuthJWTLocalIssuer  bool          `mapstructure:"APP_AUTH_JWT_LOCAL_ISSUER"`
+
+	// Short-lived signed tickets for opening the SSE stream, share the secret between instances behind a load balancer
+	AuthStreamTicketTTL    time.Duration `mapstructure:"APP_AUTH_STREAM_TICKET_TTL" validate:"min=0"`
+	AuthStreamTicketSecret string        `mapstructure:"APP_AUTH_STREAM_TICKET_SECRET"`
+
+	// Tenant of each request: the credential's tenant (API key or JWT claim) first, then the Host header
+	// mapped with "host=tenant,...", then TenantDefault; an empty TenantDefault rejects unresolved requests
+	TenantHosts   string `mapstructure:"APP_TENANT_HOSTS"`
+	TenantDefault string `mapstructure:"APP_TENANT_DEFAULT"`
+
+	// Expose Prometheus metrics on /metrics
+	MetricsEnabled bool `mapstructure:"APP_METRICS_ENABLED"`
+
+	// Lifetime of cached sensor data, writes invalidate the affected entries immediately
+	CacheTTL time.Duration `mapstructure:"APP_CACHE_TTL" validate:"gt=0"`
+
+	// Serve cached sensor data up to this long past its TTL while a single background refresh runs, 0 disables
+	CacheStaleWhileRevalidate time.Duration `mapstructure:"APP_CACHE_STALE_WHILE_REVALIDATE" validate:"min=0"`
+
+	// Interval for broadcasting a full snapshot on the SSE stream, 0 disables periodic resync
+	SSEResyncInterval time.Duration `mapstructure:"APP_SSE_RESYNC_INTERVAL" validate:"min=0"`
+
+	// MQTT ingestion bridge, topics are comma-separated patterns where the first '+' is the sensor ID
+	MQTTEnabled              bool          `mapstructure:"APP_MQTT_ENABLED"`
+	MQTTBrokerURL            string        `mapstructure:"APP_MQTT_BROKER_URL" validate:"required_if=MQTTEnabled true"`
+	MQTTClientID             string        `mapstructure:"APP_MQTT_CLIENT_ID"`
+	MQTTUsername             string        `mapstructure:"APP_MQTT_USERNAME"`
+	MQTTPassword             string        `mapstructure:"APP_MQTT_PASSWORD"`
+	MQTTTopics               string        `mapstructure:"APP_MQTT_TOPICS" validate:"required_if=MQTTEnabled true"`
+	MQTTQoS                  int           `mapstructure:"APP_MQTT_QOS" validate:"min=0,max=2"`
+	MQTTMaxReconnectInterval time.Duration `mapstructure:"APP_MQTT_MAX_RECONNECT_INTERVAL" validate:"min=0"`
+
+	// Reading storage: "memory" keeps only the latest value, "file" appends every reading to daily segment files
+	StorageDriver string `mapstructure:"APP_STORAGE_DRIVER" validate:"required,oneof=memory file"`
+	StoragePath   string `mapstructure:"APP_STORAGE_PATH" validate:"required_if=StorageDriver file"`
+
+	// Retention per storage tier (raw readings, 1-minute and hourly rollups), 0 keeps data forever
+	StorageRawRetention       time.Duration `mapstructure:"APP_STORAGE_RAW_RETENTION" validate:"min=0"`
+	StorageMinuteRetention    time.Duration `mapstructure:"APP_STORAGE_MINUTE_RETENTION" validate:"min=0"`
+	StorageHourRetention      time.Duration `mapstructure:"APP_STORAGE_HOUR_RETENTION" validate:"min=0"`
+	StorageCompactionInterval time.Duration `mapstructure:"APP_STORAGE_COMPACTION_INTERVAL" validate:"min=0"`
+
+	// JSON or YAML file with extra sensor types (metrics, units, valid ranges) added to the built-in registry
+	SensorTypesFile string `mapstructure:"APP_SENSOR_TYPES_FILE"`
+
+	// JSON or YAML file with threshold alert rules, empty disables alerting
+	AlertRulesFile string `mapstructure:"APP_ALERT_RULES_FILE"`
+
+	// Webhook notifications for alerts and sensor status changes, URLs are comma-separated
+	WebhookURLs           string        `mapstructure:"APP_WEBHOOK_URLS"`
+	WebhookSecret         string        `mapstructure:"APP_WEBHOOK_SECRET"`
+	WebhookMaxAttempts    int           `mapstructure:"APP_WEBHOOK_MAX_ATTEMPTS" validate:"min=0"`
+	WebhookInitialBackoff time.Duration `mapstructure:"APP_WEBHOOK_INITIAL_BACKOFF" validate:"min=0"`
+	WebhookTimeout        time.Duration `mapstructure:"APP_WEBHOOK_TIMEOUT" validate:"min=0"`
+
+	// Stale/offline detection: a sensor is stale after missing N expected intervals and offline after OfflineAfter,
+	// the policy file overrides these per sensor type
+	WatchdogEnabled          bool          `mapstructure:"APP_WATCHDOG_ENABLED"`
+	WatchdogCheckInterval    time.Duration `mapstructure:"APP_WATCHDOG_CHECK_INTERVAL" validate:"required_if=WatchdogEnabled true,min=0"`
+	WatchdogExpectedInterval time.Duration `mapstructure:"APP_WATCHDOG_EXPECTED_INTERVAL" validate:"gt=0"`
+	WatchdogStaleAfterMissed int           `mapstructure:"APP_WATCHDOG_STALE_AFTER_MISSED" validate:"min=1"`
+	WatchdogOfflineAfter     time.Duration `mapstructure:"APP_WATCHDOG_OFFLINE_AFTER" validate:"min=0"`
+	WatchdogPolicyFile       string        `mapstructure:"APP_WATCHDOG_POLICY_FILE"`
+
 	LogLevel  string         `mapstructure:"APP_LOG_LEVEL"`
 	CORSHosts string         `mapstructure:"APP_CORS_HOSTS"`
 	Security  SecurityConfig `validate:"required"`
@@ -118,12 +258,72 @@ func loadEnvFile(env string) error {
 
 	v.SetDefault("APP_PORT", DefaultPort)
 	v.SetDefault("APP_MAX_CONNECTIONS", DefaultMaxConnections)
+	v.SetDefault("APP_MAX_CONNECTIONS_PER_IP", DefaultMaxConnsPerIP)
 	v.SetDefault("APP_READ_TIMEOUT", DefaultReadTimeout.String())
 	v.SetDefault("APP_WRITE_TIMEOUT", DefaultWriteTimeout.String())
 	v.SetDefault("APP_IDLE_TIMEOUT", DefaultIdleTimeout.String())
 	v.SetDefault("APP_MAX_HEADER_BYTES", DefaultMaxHeaderBytes)
 	v.SetDefault("APP_LOG_LEVEL", "info")
 	v.SetDefault("APP_CORS_HOSTS", "*")
+	v.SetDefault("APP_SSE_RESYNC_INTERVAL", DefaultSSEResyncInterval.String())
+	v.SetDefault("APP_CACHE_TTL", DefaultCacheTTL.String())
+	v.SetDefault("APP_CACHE_STALE_WHILE_REVALIDATE", "0s")
+	v.SetDefault("APP_MOCK_DATA", true)
+	v.SetDefault("APP_SIMULATOR_ENABLED", false)
+	v.SetDefault("APP_SIMULATOR_SCENARIO_FILE", "")
+	v.SetDefault("APP_RECORD_FILE", "")
+	v.SetDefault("APP_RECORD_MODE", "readings")
+	v.SetDefault("APP_REPLAY_FILE", "")
+	v.SetDefault("APP_REPLAY_SPEED", 1.0)
+	v.SetDefault("APP_REPLAY_STEP", false)
+	v.SetDefault("APP_REPLAY_LOOP", false)
+	v.SetDefault("APP_RATE_LIMIT_ENABLED", true)
+	v.SetDefault("APP_RATE_LIMIT_FILE", "")
+	v.SetDefault("APP_RATE_LIMIT_EXEMPT", "/health,/metrics")
+	v.SetDefault("APP_TRUSTED_PROXIES", "")
+	v.SetDefault("APP_AUTH_ENABLED", false)
+	v.SetDefault("APP_AUTH_KEYS_FILE", "")
+	v.SetDefault("APP_AUTH_JWT_ENABLED", false)
+	v.SetDefault("APP_AUTH_JWT_JWKS", "")
+	v.SetDefault("APP_AUTH_JWT_JWKS_CACHE_TTL", DefaultAuthJWKSCacheTTL.String())
+	v.SetDefault("APP_AUTH_JWT_ISSUER", "")
+	v.SetDefault("APP_AUTH_JWT_AUDIENCE", "")
+	v.SetDefault("APP_AUTH_JWT_ROLES_CLAIM", DefaultAuthJWTRolesClaim)
+	v.SetDefault("APP_AUTH_JWT_TENANT_CLAIM", DefaultAuthJWTTenantClaim)
+	v.SetDefault("APP_AUTH_JWT_ROLE_SCOPES", DefaultAuthJWTRoleScopes)
+	v.SetDefault("APP_AUTH_JWT_LOCAL_ISSUER", false)
+	v.SetDefault("APP_AUTH_STREAM_TICKET_TTL", DefaultAuthStreamTicketTTL.String())
+	v.SetDefault("APP_AUTH_STREAM_TICKET_SECRET", "")
+	v.SetDefault("APP_TENANT_HOSTS", "")
+	v.SetDefault("APP_TENANT_DEFAULT", DefaultTenant)
+	v.SetDefault("APP_METRICS_ENABLED", true)
+	v.SetDefault("APP_MQTT_ENABLED", false)
+	v.SetDefault("APP_MQTT_BROKER_URL", DefaultMQTTBrokerURL)
+	v.SetDefault("APP_MQTT_CLIENT_ID", "")
+	v.SetDefault("APP_MQTT_USERNAME", "")
+	v.SetDefault("APP_MQTT_PASSWORD", "")
+	v.SetDefault("APP_MQTT_TOPICS", DefaultMQTTTopics)
+	v.SetDefault("APP_MQTT_QOS", DefaultMQTTQoS)
+	v.SetDefault("APP_MQTT_MAX_RECONNECT_INTERVAL", DefaultMQTTMaxReconnectInterval.String())
+	v.SetDefault("APP_STORAGE_DRIVER", DefaultStorageDriver)
+	v.SetDefault("APP_STORAGE_PATH", DefaultStoragePath)
+	v.SetDefault("APP_STORAGE_RAW_RETENTION", DefaultStorageRawRetention.String())
+	v.SetDefault("APP_STORAGE_MINUTE_RETENTION", DefaultStorageMinuteRetention.String())
+	v.SetDefault("APP_STORAGE_HOUR_RETENTION", DefaultStorageHourRetention.String())
+	v.SetDefault("APP_STORAGE_COMPACTION_INTERVAL", DefaultStorageCompactionInterval.String())
+	v.SetDefault("APP_SENSOR_TYPES_FILE", "")
+	v.SetDefault("APP_ALERT_RULES_FILE", "")
+	v.SetDefault("APP_WEBHOOK_URLS", "")
+	v.SetDefault("APP_WEBHOOK_SECRET", "")
+	v.SetDefault("APP_WEBHOOK_MAX_ATTEMPTS", DefaultWebhookMaxAttempts)
+	v.SetDefault("APP_WEBHOOK_INITIAL_BACKOFF", DefaultWebhookInitialBackoff.String())
+	v.SetDefault("APP_WEBHOOK_TIMEOUT", DefaultWebhookTimeout.String())
+	v.SetDefault("APP_WATCHDOG_ENABLED", true)
+	v.SetDefault("APP_WATCHDOG_CHECK_INTERVAL", DefaultWatchdogCheckInterval.String())
+	v.SetDefault("APP_WATCHDOG_EXPECTED_INTERVAL", DefaultWatchdogExpectedInterval.String())
+	v.SetDefault("APP_WATCHDOG_STALE_AFTER_MISSED", DefaultWatchdogStaleAfterMissed)
+	v.SetDefault("APP_WATCHDOG_OFFLINE_AFTER", DefaultWatchdogOfflineAfter.String())
+	v.SetDefault("APP_WATCHDOG_POLICY_FILE", "")
 
 	viper.MergeConfigMap(v.AllSettings())
 
@@ -166,12 +366,72 @@ func LoadConfig(envOpt ...string) (*Config, error) {
 
 	viper.SetDefault("APP_PORT", DefaultPort)
 	viper.SetDefault("APP_MAX_CONNECTIONS", DefaultMaxConnections)
+	viper.SetDefault("APP_MAX_CONNECTIONS_PER_IP", DefaultMaxConnsPerIP)
 	viper.SetDefault("APP_READ_TIMEOUT", DefaultReadTimeout.String())
 	viper.SetDefault("APP_WRITE_TIMEOUT", DefaultWriteTimeout.String())
 	viper.SetDefault("APP_IDLE_TIMEOUT", DefaultIdleTimeout.String())
 	viper.SetDefault("APP_MAX_HEADER_BYTES", DefaultMaxHeaderBytes)
 	viper.SetDefault("APP_LOG_LEVEL", "info")
 	viper.SetDefault("APP_CORS_HOSTS", "*")
+	viper.SetDefault("APP_SSE_RESYNC_INTERVAL", DefaultSSEResyncInterval.String())
+	viper.SetDefault("APP_CACHE_TTL", DefaultCacheTTL.String())
+	viper.SetDefault("APP_CACHE_STALE_WHILE_REVALIDATE", "0s")
+	viper.SetDefault("APP_MOCK_DATA", true)
+	viper.SetDefault("APP_SIMULATOR_ENABLED", false)
+	viper.SetDefault("APP_SIMULATOR_SCENARIO_FILE", "")
+	viper.SetDefault("APP_RECORD_FILE", "")
+	viper.SetDefault("APP_RECORD_MODE", "readings")
+	viper.SetDefault("APP_REPLAY_FILE", "")
+	viper.SetDefault("APP_REPLAY_SPEED", 1.0)
+	viper.SetDefault("APP_REPLAY_STEP", false)
+	viper.SetDefault("APP_REPLAY_LOOP", false)
+	viper.SetDefault("APP_RATE_LIMIT_ENABLED", true)
+	viper.SetDefault("APP_RATE_LIMIT_FILE", "")
+	viper.SetDefault("APP_RATE_LIMIT_EXEMPT", "/health,/metrics")
+	viper.SetDefault("APP_TRUSTED_PROXIES", "")
+	viper.SetDefault("APP_AUTH_ENABLED", false)
+	viper.SetDefault("APP_AUTH_KEYS_FILE", "")
+	viper.SetDefault("APP_AUTH_JWT_ENABLED", false)
+	viper.SetDefault("APP_AUTH_JWT_JWKS", "")
+	viper.SetDefault("APP_AUTH_JWT_JWKS_CACHE_TTL", DefaultAuthJWKSCacheTTL.String())
+	viper.SetDefault("APP_AUTH_JWT_ISSUER", "")
+	viper.SetDefault("APP_AUTH_JWT_AUDIENCE", "")
+	viper.SetDefault("APP_AUTH_JWT_ROLES_CLAIM", DefaultAuthJWTRolesClaim)
+	viper.SetDefault("APP_AUTH_JWT_TENANT_CLAIM", DefaultAuthJWTTenantClaim)
+	viper.SetDefault("APP_AUTH_JWT_ROLE_SCOPES", DefaultAuthJWTRoleScopes)
+	viper.SetDefault("APP_AUTH_JWT_LOCAL_ISSUER", false)
+	viper.SetDefault("APP_AUTH_STREAM_TICKET_TTL", DefaultAuthStreamTicketTTL.String())
+	viper.SetDefault("APP_AUTH_STREAM_TICKET_SECRET", "")
+	viper.SetDefault("APP_TENANT_HOSTS", "")
+	viper.SetDefault("APP_TENANT_DEFAULT", DefaultTenant)
+	viper.SetDefault("APP_METRICS_ENABLED", true)
+	viper.SetDefault("APP_MQTT_ENABLED", false)
+	viper.SetDefault("APP_MQTT_BROKER_URL", DefaultMQTTBrokerURL)
+	viper.SetDefault("APP_MQTT_CLIENT_ID", "")
+	viper.SetDefault("APP_MQTT_USERNAME", "")
+	viper.SetDefault("APP_MQTT_PASSWORD", "")
+	viper.SetDefault("APP_MQTT_TOPICS", DefaultMQTTTopics)
+	viper.SetDefault("APP_MQTT_QOS", DefaultMQTTQoS)
+	viper.SetDefault("APP_MQTT_MAX_RECONNECT_INTERVAL", DefaultMQTTMaxReconnectInterval.String())
+	viper.SetDefault("APP_STORAGE_DRIVER", DefaultStorageDriver)
+	viper.SetDefault("APP_STORAGE_PATH", DefaultStoragePath)
+	viper.SetDefault("APP_STORAGE_RAW_RETENTION", DefaultStorageRawRetention.String())
+	viper.SetDefault("APP_STORAGE_MINUTE_RETENTION", DefaultStorageMinuteRetention.String())
+	viper.SetDefault("APP_STORAGE_HOUR_RETENTION", DefaultStorageHourRetention.String())
+	viper.SetDefault("APP_STORAGE_COMPACTION_INTERVAL", DefaultStorageCompactionInterval.String())
+	viper.SetDefault("APP_SENSOR_TYPES_FILE", "")
+	viper.SetDefault("APP_ALERT_RULES_FILE", "")
+	viper.SetDefault("APP_WEBHOOK_URLS", "")
+	viper.SetDefault("APP_WEBHOOK_SECRET", "")
+	viper.SetDefault("APP_WEBHOOK_MAX_ATTEMPTS", DefaultWebhookMaxAttempts)
+	viper.SetDefault("APP_WEBHOOK_INITIAL_BACKOFF", DefaultWebhookInitialBackoff.String())
+	viper.SetDefault("APP_WEBHOOK_TIMEOUT", DefaultWebhookTimeout.String())
+	viper.SetDefault("APP_WATCHDOG_ENABLED", true)
+	viper.SetDefault("APP_WATCHDOG_CHECK_INTERVAL", DefaultWatchdogCheckInterval.String())
+	viper.SetDefault("APP_WATCHDOG_EXPECTED_INTERVAL", DefaultWatchdogExpectedInterval.String())
+	viper.SetDefault("APP_WATCHDOG_STALE_AFTER_MISSED", DefaultWatchdogStaleAfterMissed)
+	viper.SetDefault("APP_WATCHDOG_OFFLINE_AFTER", DefaultWatchdogOfflineAfter.String())
+	viper.SetDefault("APP_WATCHDOG_POLICY_FILE", "")
 	viper.SetDefault("APP_ENV", env)
 
 	var config Config
@@ -264,6 +524,17 @@ func (c *Config) String() string {
 		c.Port, c.Env, c.StaticPath, c.MaxConnections)
 }
 
+// SplitList splits a comma-separated setting, trimming each entry and dropping empty ones.
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func GetEnvFilePath(env Environment) string {
 	basePath := "./configs/backend"
 	switch env {
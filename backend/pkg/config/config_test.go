@@ -47,6 +47,15 @@ func TestLoadConfig(t *testing.T) {
 			},
 			expectedError: true,
 		},
+		{
+			name: "zero_watchdog_expected_interval",
+			envVars: map[string]string{
+				"APP_ENV":                        "dev",
+				"APP_STATIC_PATH":                "./testdata",
+				"APP_WATCHDOG_EXPECTED_INTERVAL": "0s",
+			},
+			expectedError: true,
+		},
 		{
 			name: "invalid_duration_format",
 			envVars: map[string]string{
@@ -159,6 +168,12 @@ func TestString(t *testing.T) {
 	assert.Contains(t, str, "MaxConnections: 1000")
 }
 
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"sensors/+/telemetry", "devices/#"}, config.SplitList(" sensors/+/telemetry, ,devices/# ,"))
+	assert.Empty(t, config.SplitList(""))
+	assert.Empty(t, config.SplitList(" , "))
+}
+
 func TestConfigDefaults(t *testing.T) {
 	// Save original environment value and restore it after test completion
 	originalEnv := os.Getenv("APP_ENV")
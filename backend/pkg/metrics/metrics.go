@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace คือ prefix ของชื่อ metric ทั้งหมดของแอปพลิเคชัน
+const namespace = "sensor_dashboard"
+
+// Registry คือ registry ของ metric ทั้งหมดที่ถูกเปิดเผยผ่าน /metrics
+// ใช้ registry ของตัวเองแทน default เพื่อไม่ให้ metric จาก library อื่นปนเข้ามา
+var Registry = prometheus.NewRegistry()
+
+// SSE metrics
+var (
+	// SSEConnectionsActive คือจำนวน SSE client ที่เชื่อมต่ออยู่
+	SSEConnectionsActive = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "connections_active",
+		Help:      "Number of SSE clients currently connected.",
+	})
+
+	// SSEConnectsTotal คือจำนวนครั้งที่ client เชื่อมต่อ SSE
+	SSEConnectsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "connects_total",
+		Help:      "Total number of SSE client connections.",
+	})
+
+	// SSEDisconnectsTotal คือจำนวนครั้งที่ client ตัดการเชื่อมต่อ แยกตามสาเหตุ (client, dropped, token_expired)
+	SSEDisconnectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "disconnects_total",
+		Help:      "Total number of SSE client disconnections by reason.",
+	}, []string{"reason"})
+
+	// SSEEventsSentTotal คือจำนวน event ที่เขียนไปยัง client แยกตามชนิด event
+	SSEEventsSentTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "events_sent_total",
+		Help:      "Total number of SSE events written to clients by event type.",
+	}, []string{"event"})
+
+	// SSEEventsDroppedTotal คือจำนวน event ที่ส่งไม่ได้เพราะ buffer ของ subscriber เต็ม แยกตามชนิด event
+	SSEEventsDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "events_dropped_total",
+		Help:      "Total number of SSE events dropped for slow subscribers by event type.",
+	}, []string{"event"})
+
+	// SSEBytesWrittenTotal คือจำนวน byte ที่เขียนไปยัง SSE client ทั้งหมด
+	SSEBytesWrittenTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sse",
+		Name:      "bytes_written_total",
+		Help:      "Total number of bytes written to SSE clients.",
+	})
+)
+
+// Cache metrics
+var (
+	// CacheHitsTotal คือจำนวนครั้งที่พบข้อมูลใน cache
+	CacheHitsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "hits_total",
+		Help:      "Total number of cache hits.",
+	})
+
+	// CacheMissesTotal คือจำนวนครั้งที่ไม่พบข้อมูลใน cache (รวมข้อมูลที่หมดอายุแล้ว)
+	CacheMissesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "misses_total",
+		Help:      "Total number of cache misses, including expired entries.",
+	})
+
+	// CacheEvictionsTotal คือจำนวนข้อมูลที่ถูกนำออกจาก cache แยกตามสาเหตุ
+	CacheEvictionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "evictions_total",
+		Help:      "Total number of cache entries evicted by reason.",
+	}, []string{"reason"})
+)
+
+// HTTP metrics
+var (
+	// HTTPRequestDuration คือเวลาที่ใช้ตอบ request แยกตาม method, route และ status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// RateLimitRejectionsTotal คือจำนวน request ที่ถูกปฏิเสธโดย limiter แยกตามชนิดของ limiter
+	RateLimitRejectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limit_rejections_total",
+		Help:      "Total number of requests rejected by a limiter.",
+	}, []string{"limiter"})
+)
+
+// MQTT metrics
+var (
+	// MQTTMessagesTotal คือจำนวนข้อความ MQTT ที่ได้รับ แยกตาม subscription (topic filter ที่กำหนด) และผลลัพธ์ (ingested, error)
+	// ใช้ topic filter แทน topic จริงเพื่อไม่ให้จำนวน label เพิ่มตามจำนวนเซนเซอร์
+	MQTTMessagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "mqtt",
+		Name:      "messages_total",
+		Help:      "Total number of MQTT messages received by subscription and result.",
+	}, []string{"subscription", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		SSEConnectionsActive,
+		SSEConnectsTotal,
+		SSEDisconnectsTotal,
+		SSEEventsSentTotal,
+		SSEEventsDroppedTotal,
+		SSEBytesWrittenTotal,
+		CacheHitsTotal,
+		CacheMissesTotal,
+		CacheEvictionsTotal,
+		HTTPRequestDuration,
+		RateLimitRejectionsTotal,
+		MQTTMessagesTotal,
+	)
+}
+
+// Handler คืนค่า echo handler สำหรับ endpoint /metrics ในรูปแบบ Prometheus text format
+func Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
+
+// Middleware วัดเวลาที่ใช้ตอบ request ทุกตัว โดยใช้ route pattern (เช่น /api/sensors/:id) เป็น label
+// เพื่อไม่ให้จำนวน label เพิ่มตาม ID ที่ถูกเรียก
+// SSE stream ไม่ถูกนับเพราะเวลาคืออายุของการเชื่อมต่อ ซึ่งวัดแยกด้วย metric ของ SSE
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			res := c.Response()
+			if res.Header().Get(echo.HeaderContentType) == "text/event-stream" {
+				return err
+			}
+
+			status := res.Status
+			if err != nil {
+				if httpErr, ok := err.(*echo.HTTPError); ok {
+					status = httpErr.Code
+				} else if !res.Committed {
+					status = http.StatusInternalServerError
+				}
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			HTTPRequestDuration.
+				WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).
+				Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
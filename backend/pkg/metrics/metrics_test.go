@@ -0,0 +1,104 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
+)
+
+// scrape อ่านผลลัพธ์ของ /metrics
+func scrape(t *testing.T, e *echo.Echo) string {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+// TestMiddleware ทดสอบว่า latency ถูกบันทึกตาม route pattern และ status และ SSE stream ไม่ถูกนับ
+func TestMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(metrics.Middleware())
+	e.GET("/metrics", metrics.Handler())
+	e.GET("/api/sensors/:id", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/api/limited", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusTooManyRequests, "Too many requests")
+	})
+	e.GET("/api/stream", func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/api/sensors/temp-001", "/api/sensors/temp-002", "/api/limited", "/api/stream"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, e)
+
+	testCases := []struct {
+		name     string
+		series   string
+		expected bool
+	}{
+		{
+			name:     "route pattern used as label",
+			series:   `sensor_dashboard_http_request_duration_seconds_count{method="GET",route="/api/sensors/:id",status="200"} 2`,
+			expected: true,
+		},
+		{
+			name:     "HTTP error status",
+			series:   `sensor_dashboard_http_request_duration_seconds_count{method="GET",route="/api/limited",status="429"} 1`,
+			expected: true,
+		},
+		{
+			name:     "SSE stream not observed",
+			series:   `route="/api/stream"`,
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expected {
+				assert.Contains(t, body, tc.series)
+			} else {
+				assert.NotContains(t, body, tc.series)
+			}
+		})
+	}
+}
+
+// TestHandler ทดสอบว่า metric ของ SSE และ cache ถูกเปิดเผยผ่าน /metrics
+func TestHandler(t *testing.T) {
+	e := echo.New()
+	e.GET("/metrics", metrics.Handler())
+
+	metrics.SSEEventsSentTotal.WithLabelValues("sensor.updated").Inc()
+	metrics.SSEEventsDroppedTotal.WithLabelValues("sensor.updated").Inc()
+	metrics.CacheEvictionsTotal.WithLabelValues("expired").Inc()
+	metrics.RateLimitRejectionsTotal.WithLabelValues("rate").Inc()
+
+	body := scrape(t, e)
+	for _, name := range []string{
+		"sensor_dashboard_sse_connections_active",
+		"sensor_dashboard_sse_connects_total",
+		`sensor_dashboard_sse_events_sent_total{event="sensor.updated"}`,
+		`sensor_dashboard_sse_events_dropped_total{event="sensor.updated"}`,
+		"sensor_dashboard_sse_bytes_written_total",
+		"sensor_dashboard_cache_hits_total",
+		"sensor_dashboard_cache_misses_total",
+		`sensor_dashboard_cache_evictions_total{reason="expired"}`,
+		`sensor_dashboard_http_rate_limit_rejections_total{limiter="rate"}`,
+		"go_goroutines",
+	} {
+		assert.Contains(t, body, name)
+	}
+}
@@ -1,22 +1,61 @@
 package middleware
 
 import (
-	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"golang.org/x/sync/semaphore"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
 )
 
+// DefaultRetryAfter คือเวลาที่แนะนำให้ client รอก่อนเชื่อมต่อใหม่เมื่อถูกปฏิเสธ
+const DefaultRetryAfter = 5 * time.Second
+
+// ConnectionLimiterConfig กำหนดขีดจำกัดของ ConnectionLimiter
+type ConnectionLimiterConfig struct {
+	// MaxConnections คือจำนวน connection สูงสุดพร้อมกันทั้ง server
+	MaxConnections int64
+
+	// MaxPerIP คือจำนวน connection สูงสุดพร้อมกันต่อ client IP (0 คือไม่จำกัด)
+	MaxPerIP int
+
+	// RetryAfter คือค่าของ header Retry-After เมื่อปฏิเสธ (0 ใช้ DefaultRetryAfter)
+	RetryAfter time.Duration
+}
+
 // ConnectionLimiter คือ middleware สำหรับจำกัดจำนวน connection ที่เข้ามาพร้อมกัน
+// slot ถูกถือไว้ตลอดอายุของ request จนกว่า handler จะคืนค่า จึงใช้กับ stream ที่เปิดค้างไว้ได้
 type ConnectionLimiter struct {
 	// limiter ใช้สำหรับจำกัดจำนวน connection ด้วย semaphore
 	limiter *semaphore.Weighted
+
+	// perIP นับจำนวน connection ที่เปิดอยู่ของแต่ละ client IP
+	perIP      map[string]int
+	maxPerIP   int
+	retryAfter time.Duration
+	mu         sync.Mutex
 }
 
-// NewConnectionLimiter สร้าง instance ใหม่ของ ConnectionLimiter
+// NewConnectionLimiter สร้าง instance ใหม่ของ ConnectionLimiter ที่จำกัดเฉพาะจำนวนรวม
 func NewConnectionLimiter(maxConnections int64) *ConnectionLimiter {
+	return NewConnectionLimiterWithConfig(ConnectionLimiterConfig{MaxConnections: maxConnections})
+}
+
+// NewConnectionLimiterWithConfig สร้าง instance ใหม่ของ ConnectionLimiter ตาม config
+func NewConnectionLimiterWithConfig(config ConnectionLimiterConfig) *ConnectionLimiter {
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = DefaultRetryAfter
+	}
+
 	return &ConnectionLimiter{
-		limiter: semaphore.NewWeighted(maxConnections),
+		limiter:    semaphore.NewWeighted(config.MaxConnections),
+		perIP:      make(map[string]int),
+		maxPerIP:   config.MaxPerIP,
+		retryAfter: config.RetryAfter,
 	}
 }
 
@@ -24,18 +63,60 @@ func NewConnectionLimiter(maxConnections int64) *ConnectionLimiter {
 func (cl *ConnectionLimiter) Middleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			ip := c.RealIP()
+
+			// ตรวจสอบขีดจำกัดต่อ IP ก่อน เพื่อไม่ให้ client เดียวใช้ slot ของทั้ง server
+			if !cl.acquireIP(ip) {
+				metrics.RateLimitRejectionsTotal.WithLabelValues("connections_per_ip").Inc()
+				return cl.reject(c, "too many concurrent connections from this client")
+			}
+			defer cl.releaseIP(ip)
+
 			// พยายามขอ token จาก semaphore
 			if !cl.limiter.TryAcquire(1) {
-				return c.String(http.StatusServiceUnavailable, "Server is at maximum capacity. Please try again later.")
+				metrics.RateLimitRejectionsTotal.WithLabelValues("connections").Inc()
+				return cl.reject(c, "server is at maximum capacity")
 			}
-
-			// ตั้งค่า callback ที่จะทำงานหลังจาก request เสร็จสิ้น
-			c.Response().Before(func() {
-				cl.limiter.Release(1)
-			})
+			// ปล่อย token เมื่อ handler คืนค่า (สำหรับ SSE คือเมื่อ stream ปิด)
+			defer cl.limiter.Release(1)
 
 			// ดำเนินการต่อไปยัง handler ถัดไป
 			return next(c)
 		}
 	}
 }
+
+// acquireIP จอง slot ของ client IP คืนค่า false ถ้าเกินขีดจำกัด
+func (cl *ConnectionLimiter) acquireIP(ip string) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.maxPerIP > 0 && cl.perIP[ip] >= cl.maxPerIP {
+		return false
+	}
+	cl.perIP[ip]++
+	return true
+}
+
+// releaseIP คืน slot ของ client IP และลบ IP ที่ไม่มี connection เหลือออกจาก map
+func (cl *ConnectionLimiter) releaseIP(ip string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.perIP[ip] <= 1 {
+		delete(cl.perIP, ip)
+		return
+	}
+	cl.perIP[ip]--
+}
+
+// reject ตอบ 503 ในรูปแบบ APIError พร้อม header Retry-After
+func (cl *ConnectionLimiter) reject(c echo.Context, message string) error {
+	seconds := int(cl.retryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(seconds))
+
+	return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrServiceUnavailable, message))
+}
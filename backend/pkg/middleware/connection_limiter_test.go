@@ -3,6 +3,8 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -13,72 +15,98 @@ func TestConnectionLimiter(t *testing.T) {
 	e := echo.New()
 
 	tests := []struct {
-		name           string
-		maxConnections int64
-		concurrency    int
-		wantStatusCode int
+		name            string
+		maxConnections  int64
+		maxPerIP        int
+		clients         []string // client IP ของแต่ละ connection ที่เปิดค้างไว้พร้อมกัน
+		wantStatusCodes []int
 	}{
 		{
-			name:           "Allow connection under limit",
-			maxConnections: 5,
-			concurrency:    1,
-			wantStatusCode: http.StatusOK,
+			name:            "Allow connection under limit",
+			maxConnections:  5,
+			clients:         []string{"10.0.0.1"},
+			wantStatusCodes: []int{http.StatusOK},
 		},
 		{
-			name:           "Reject connection over limit",
-			maxConnections: 1,
-			concurrency:    2,
-			wantStatusCode: http.StatusServiceUnavailable,
+			name:            "Reject connection over limit",
+			maxConnections:  1,
+			clients:         []string{"10.0.0.1", "10.0.0.2"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusServiceUnavailable},
+		},
+		{
+			name:            "Reject connection over per-IP limit",
+			maxConnections:  10,
+			maxPerIP:        2,
+			clients:         []string{"10.0.0.1", "10.0.0.1", "10.0.0.1", "10.0.0.2"},
+			wantStatusCodes: []int{http.StatusOK, http.StatusOK, http.StatusServiceUnavailable, http.StatusOK},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// สร้าง connection limiter
-			limiter := NewConnectionLimiter(tt.maxConnections)
-
-			// สร้าง handler ที่จะถูกเรียกหลังจากผ่าน middleware
+			limiter := NewConnectionLimiterWithConfig(ConnectionLimiterConfig{
+				MaxConnections: tt.maxConnections,
+				MaxPerIP:       tt.maxPerIP,
+			})
+
+			// handler จำลอง stream ที่เปิดค้างไว้จนกว่าจะปิด release
+			entered := make(chan struct{})
+			release := make(chan struct{})
 			handler := func(c echo.Context) error {
+				entered <- struct{}{}
+				<-release
 				return c.String(http.StatusOK, "OK")
 			}
-
-			// ส่งคำขอตามจำนวน concurrency
-			statusCodes := make([]int, tt.concurrency)
-
-			for i := 0; i < tt.concurrency; i++ {
-				// สร้าง request และ response recorder
-				req := httptest.NewRequest(http.MethodGet, "/", nil)
-				rec := httptest.NewRecorder()
-				c := e.NewContext(req, rec)
-
-				// ดำเนินการด้วย middleware และ handler
-				mw := limiter.Middleware()(handler)
-				_ = mw(c)
-
-				statusCodes[i] = rec.Code
+			mw := limiter.Middleware()(handler)
+
+			// เปิด connection ทีละตัว โดยรอจนกว่าตัวก่อนหน้าจะเข้า handler หรือถูกปฏิเสธ
+			recorders := make([]*httptest.ResponseRecorder, len(tt.clients))
+			var wg sync.WaitGroup
+			for i, ip := range tt.clients {
+				req := httptest.NewRequest(http.MethodGet, "/api/sensors/stream", nil)
+				req.Header.Set(echo.HeaderXRealIP, ip)
+				recorders[i] = httptest.NewRecorder()
+				c := e.NewContext(req, recorders[i])
+
+				done := make(chan struct{})
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer close(done)
+					_ = mw(c)
+				}()
+
+				select {
+				case <-entered:
+				case <-done:
+				}
 			}
 
+			close(release)
+			wg.Wait()
+
 			// ตรวจสอบผลลัพธ์
-			if tt.concurrency == 1 || tt.maxConnections >= int64(tt.concurrency) {
-				// ทุกการเชื่อมต่อควรสำเร็จ
-				for i, code := range statusCodes {
-					if code != http.StatusOK {
-						t.Errorf("Request %d expected status code %d, got %d", i, http.StatusOK, code)
-					}
+			for i, rec := range recorders {
+				if rec.Code != tt.wantStatusCodes[i] {
+					t.Errorf("Request %d expected status code %d, got %d", i, tt.wantStatusCodes[i], rec.Code)
 				}
-			} else {
-				// อย่างน้อยหนึ่งการเชื่อมต่อควรถูกปฏิเสธ
-				rejected := false
-				for _, code := range statusCodes {
-					if code == http.StatusServiceUnavailable {
-						rejected = true
-						break
+				if rec.Code == http.StatusServiceUnavailable {
+					if rec.Header().Get(echo.HeaderRetryAfter) == "" {
+						t.Errorf("Request %d expected Retry-After header", i)
+					}
+					if !strings.Contains(rec.Body.String(), `"code":"SERVICE_UNAVAILABLE"`) {
+						t.Errorf("Request %d expected APIError body, got %s", i, rec.Body.String())
 					}
 				}
+			}
 
-				if !rejected {
-					t.Errorf("Expected at least one request to be rejected, but all succeeded")
-				}
+			// slot ทั้งหมดต้องถูกคืนหลังจาก stream ปิด
+			if len(limiter.perIP) != 0 {
+				t.Errorf("Expected all per-IP slots to be released, got %v", limiter.perIP)
+			}
+			if !limiter.limiter.TryAcquire(tt.maxConnections) {
+				t.Errorf("Expected all global slots to be released")
 			}
 		})
 	}
@@ -115,3 +143,56 @@ func TestConnectionLimiterReleaseAndAcquire(t *testing.T) {
 	// ต้องปล่อย token สุดท้ายเพื่อทำความสะอาด
 	limiter.limiter.Release(1)
 }
+
+// TestConnectionLimiterIgnoresSpoofedHeaders ทดสอบว่า client เปลี่ยน X-Forwarded-For ทุก connection แล้วยังถูกจำกัดต่อ IP
+func TestConnectionLimiterIgnoresSpoofedHeaders(t *testing.T) {
+	extract, err := NewIPExtractor(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.IPExtractor = extract
+
+	limiter := NewConnectionLimiterWithConfig(ConnectionLimiterConfig{MaxConnections: 10, MaxPerIP: 2})
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	mw := limiter.Middleware()(func(c echo.Context) error {
+		entered <- struct{}{}
+		<-release
+		return c.String(http.StatusOK, "OK")
+	})
+
+	var wg sync.WaitGroup
+	defer func() {
+		close(release)
+		wg.Wait()
+	}()
+
+	for i, spoofed := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sensors/stream", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		req.Header.Set(echo.HeaderXForwardedFor, spoofed)
+		req.Header.Set(echo.HeaderXRealIP, spoofed)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		done := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done)
+			_ = mw(c)
+		}()
+
+		select {
+		case <-entered:
+			if i >= 2 {
+				t.Errorf("Request %d expected to be rejected", i)
+			}
+		case <-done:
+			if i < 2 || rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("Request %d got unexpected status code %d", i, rec.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+)
+
+// NewIPExtractor สร้าง echo.IPExtractor สำหรับ c.RealIP() ที่ limiter ใช้แยก client
+// proxies คือ IP หรือ CIDR ของ reverse proxy ที่เชื่อถือ ถ้าว่างจะใช้ IP ของ connection โดยตรง
+// X-Forwarded-For ถูกอ่านเฉพาะเมื่อ request มาจาก proxy ที่เชื่อถือ เพื่อไม่ให้ client ปลอม header เพื่อหลบการจำกัดต่อ IP
+func NewIPExtractor(proxies []string) (echo.IPExtractor, error) {
+	var trusted []echo.TrustOption
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		network, err := parseProxy(proxy)
+		if err != nil {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("trusted proxy %q: expected IP or CIDR", proxy))
+		}
+		trusted = append(trusted, echo.TrustIPRange(network))
+	}
+	if len(trusted) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	// เชื่อถือเฉพาะช่วงที่กำหนด ไม่รวม loopback และ private network ที่ echo เชื่อถือเป็นค่าเริ่มต้น
+	options := append([]echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}, trusted...)
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// parseProxy แปลง IP หรือ CIDR เป็นช่วง IP (IP เดียวคือ /32 หรือ /128)
+func parseProxy(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		return network, err
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP %q", value)
+	}
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
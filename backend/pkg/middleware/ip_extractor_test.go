@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPExtractor(t *testing.T) {
+	tests := []struct {
+		name       string
+		proxies    []string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "No trusted proxy ignores headers", remoteAddr: "203.0.113.7:5000", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "Trusted proxy forwards client IP", proxies: []string{" 10.0.0.0/8 ", ""}, remoteAddr: "10.1.2.3:5000", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{name: "Spoofed entry before trusted proxy is ignored", proxies: []string{"10.0.0.5"}, remoteAddr: "10.0.0.5:5000", forwarded: "1.2.3.4, 198.51.100.1", want: "198.51.100.1"},
+		{name: "Untrusted sender cannot forward", proxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.7:5000", forwarded: "198.51.100.1", want: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extract, err := NewIPExtractor(tt.proxies)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, tt.forwarded)
+			req.Header.Set(echo.HeaderXRealIP, "192.0.2.1")
+			assert.Equal(t, tt.want, extract(req))
+		})
+	}
+
+	for _, proxies := range [][]string{{"proxy.local"}, {"10.0.0.0/33"}} {
+		_, err := NewIPExtractor(proxies)
+		assert.Error(t, err, proxies)
+	}
+}
@@ -0,0 +1,256 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/metrics"
+)
+
+// ชนิดของตัวระบุ client ที่ policy ใช้แยก bucket
+const (
+	// KeyIP แยก bucket ตาม client IP
+	KeyIP = "ip"
+
+	// KeyAPIKey แยก bucket ตาม API key (header X-API-Key หรือ query access_token) ที่ผ่านการตรวจสอบกับ Keys
+	// ถ้าไม่มี key หรือ key ไม่ถูกต้องจะใช้ client IP เพื่อไม่ให้ key สุ่มได้ bucket ใหม่ทุกครั้ง
+	KeyAPIKey = "api_key"
+
+	// KeyGlobal ใช้ bucket เดียวร่วมกันทุก client ของ route group
+	KeyGlobal = "global"
+)
+
+// DefaultVisitorExpiry คือเวลาที่ bucket ของ client ที่ไม่ได้ใช้งานจะถูกลบ
+const DefaultVisitorExpiry = 3 * time.Minute
+
+// RateLimitPolicy คือ policy จำกัดอัตรา request ของ route group หนึ่ง
+// request ใช้ policy แรกที่ Routes มี prefix ตรงกับ path, request ที่ไม่ตรงกับ policy ใดไม่ถูกจำกัด
+type RateLimitPolicy struct {
+	Name   string   `json:"name" yaml:"name" validate:"required"`
+	Routes []string `json:"routes" yaml:"routes" validate:"min=1,dive,startswith=/"`
+	Key    string   `json:"key" yaml:"key" validate:"oneof=ip api_key global"`
+	Rate   float64  `json:"rate" yaml:"rate" validate:"gt=0"`
+	Burst  int      `json:"burst" yaml:"burst" validate:"min=1"`
+}
+
+// RateLimiterConfig กำหนด policy และ path ที่ไม่ถูกจำกัด
+type RateLimiterConfig struct {
+	// Policies คือ policy ตามลำดับการจับคู่
+	Policies []RateLimitPolicy
+
+	// Exempt คือ path ที่ไม่ถูกจำกัดเลย (ตรงกันทั้ง path)
+	Exempt []string
+
+	// VisitorExpiry คือเวลาที่ bucket ที่ไม่ได้ใช้งานจะถูกลบ (0 ใช้ DefaultVisitorExpiry)
+	VisitorExpiry time.Duration
+
+	// Keys ใช้ตรวจสอบ API key ของ policy ที่แยกตาม api_key (nil คือทุก request ใช้ client IP)
+	Keys *auth.KeyStore
+}
+
+var validate = validator.New()
+
+// DefaultRateLimitPolicies คืนค่า policy เริ่มต้นเมื่อไม่ได้กำหนดไฟล์
+// จำกัดเฉพาะ API ส่วน static file ไม่ถูกจำกัด และการเปิด stream แยก bucket จาก REST API
+func DefaultRateLimitPolicies() []RateLimitPolicy {
+	return []RateLimitPolicy{
+		{Name: "stream", Routes: []string{"/api/sensors/stream"}, Key: KeyIP, Rate: 1, Burst: 10},
+		{Name: "api", Routes: []string{"/api/"}, Key: KeyIP, Rate: 20, Burst: 40},
+	}
+}
+
+// LoadRateLimitPolicies โหลด policy จากไฟล์ JSON หรือ YAML (ตามนามสกุลไฟล์) ที่เป็น list ของ policy
+func LoadRateLimitPolicies(path string) ([]RateLimitPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrConfigNotFound, fmt.Sprintf("rate limit policies %s: %v", path, err))
+	}
+
+	var policies []RateLimitPolicy
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &policies)
+	default:
+		err = json.Unmarshal(data, &policies)
+	}
+	if err != nil {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("rate limit policies %s: %v", path, err))
+	}
+
+	seen := make(map[string]bool, len(policies))
+	for i, policy := range policies {
+		if err := validate.Struct(policy); err != nil {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("rate limit policy #%d: %v", i+1, err))
+		}
+		if seen[policy.Name] {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("duplicate rate limit policy %q", policy.Name))
+		}
+		seen[policy.Name] = true
+	}
+
+	return policies, nil
+}
+
+// visitor คือ token bucket ของ client หนึ่งรายภายใต้ policy หนึ่ง
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// policyStore เก็บ bucket ของทุก client ภายใต้ policy หนึ่ง
+type policyStore struct {
+	policy   RateLimitPolicy
+	visitors map[string]*visitor
+}
+
+// RateLimiter คือ middleware จำกัดอัตรา request ตาม policy ของแต่ละ route group
+// ทุก response ที่อยู่ภายใต้ policy จะมี header X-RateLimit-Limit, X-RateLimit-Remaining และ X-RateLimit-Reset
+type RateLimiter struct {
+	stores      []*policyStore
+	exempt      map[string]bool
+	expiry      time.Duration
+	keys        *auth.KeyStore
+	lastCleanup time.Time
+	mu          sync.Mutex
+}
+
+// NewRateLimiter สร้าง instance ใหม่ของ RateLimiter
+func NewRateLimiter(config RateLimiterConfig) *RateLimiter {
+	if config.VisitorExpiry <= 0 {
+		config.VisitorExpiry = DefaultVisitorExpiry
+	}
+
+	rl := &RateLimiter{
+		exempt:      make(map[string]bool, len(config.Exempt)),
+		expiry:      config.VisitorExpiry,
+		keys:        config.Keys,
+		lastCleanup: time.Now(),
+	}
+	for _, policy := range config.Policies {
+		rl.stores = append(rl.stores, &policyStore{policy: policy, visitors: make(map[string]*visitor)})
+	}
+	for _, path := range config.Exempt {
+		if path = strings.TrimSpace(path); path != "" {
+			rl.exempt[path] = true
+		}
+	}
+	return rl
+}
+
+// Middleware สร้าง echo middleware function สำหรับจำกัดอัตรา request
+func (rl *RateLimiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			if rl.exempt[path] {
+				return next(c)
+			}
+
+			store := rl.match(path)
+			if store == nil {
+				return next(c)
+			}
+
+			policy := store.policy
+			allowed, tokens := rl.take(store, rl.identifier(c, policy.Key))
+
+			header := c.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(policy.Burst))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+			header.Set("X-RateLimit-Reset", strconv.Itoa(secondsUntil(float64(policy.Burst)-tokens, policy.Rate)))
+
+			if !allowed {
+				metrics.RateLimitRejectionsTotal.WithLabelValues(policy.Name).Inc()
+				header.Set(echo.HeaderRetryAfter, strconv.Itoa(secondsUntil(1-tokens, policy.Rate)))
+				return apierror.HandleAPIError(c, apierror.Wrap(apierror.ErrTooManyRequests,
+					fmt.Sprintf("rate limit %q exceeded", policy.Name)))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// match คืนค่า policy แรกที่มี route prefix ตรงกับ path
+func (rl *RateLimiter) match(path string) *policyStore {
+	for _, store := range rl.stores {
+		for _, route := range store.policy.Routes {
+			if strings.HasPrefix(path, route) {
+				return store
+			}
+		}
+	}
+	return nil
+}
+
+// take ขอ token จาก bucket ของ client คืนค่าว่าอนุญาตหรือไม่และจำนวน token ที่เหลือ
+func (rl *RateLimiter) take(store *policyStore, key string) (bool, float64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(rl.lastCleanup) > rl.expiry {
+		rl.cleanupLocked(now)
+	}
+
+	v, ok := store.visitors[key]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(rate.Limit(store.policy.Rate), store.policy.Burst)}
+		store.visitors[key] = v
+	}
+	v.lastSeen = now
+
+	allowed := v.limiter.AllowN(now, 1)
+	return allowed, v.limiter.TokensAt(now)
+}
+
+// cleanupLocked ลบ bucket ที่ไม่ได้ใช้งานนานกว่า expiry (ผู้เรียกต้องถือ lock อยู่แล้ว)
+func (rl *RateLimiter) cleanupLocked(now time.Time) {
+	for _, store := range rl.stores {
+		for key, v := range store.visitors {
+			if now.Sub(v.lastSeen) > rl.expiry {
+				delete(store.visitors, key)
+			}
+		}
+	}
+	rl.lastCleanup = now
+}
+
+// identifier คืนค่าตัวระบุ client ตามชนิด key ของ policy
+// API key ใช้ชื่อของ key ที่ตรวจสอบแล้วเป็นชื่อ bucket เพื่อไม่ให้ key จริงค้างอยู่ในหน่วยความจำ
+// client IP มาจาก echo.IPExtractor ของ server (ดู NewIPExtractor) ไม่ใช่ header ที่ client ส่งมาเอง
+func (rl *RateLimiter) identifier(c echo.Context, key string) string {
+	switch key {
+	case KeyGlobal:
+		return KeyGlobal
+	case KeyAPIKey:
+		if rl.keys != nil {
+			if principal, err := rl.keys.Authenticate(c); err == nil && principal != nil {
+				return "key:" + principal.Subject
+			}
+		}
+	}
+	return "ip:" + c.RealIP()
+}
+
+// secondsUntil คืนค่าจำนวนวินาที (ปัดขึ้น) ที่ bucket ต้องใช้เติม token ตามจำนวนที่ขาด
+func secondsUntil(missing float64, perSecond float64) int {
+	if missing <= 0 {
+		return 0
+	}
+	return int(math.Ceil(missing / perSecond))
+}
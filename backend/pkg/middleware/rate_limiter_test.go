@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
+)
+
+func TestRateLimiter(t *testing.T) {
+	policies := []RateLimitPolicy{
+		{Name: "stream", Routes: []string{"/api/sensors/stream"}, Key: KeyIP, Rate: 0.001, Burst: 1},
+		{Name: "api", Routes: []string{"/api/"}, Key: KeyAPIKey, Rate: 0.001, Burst: 2},
+	}
+	keys := auth.NewKeyStore([]auth.APIKey{
+		{Name: "key-a", Hash: auth.HashKey("key-a"), Scopes: []auth.Scope{auth.ScopeReadSensors}},
+		{Name: "key-b", Hash: auth.HashKey("key-b"), Scopes: []auth.Scope{auth.ScopeReadSensors}},
+	})
+
+	type request struct {
+		path   string
+		ip     string
+		apiKey string
+		status int
+	}
+
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "Route groups use separate buckets",
+			requests: []request{
+				{path: "/api/sensors", ip: "10.0.0.1", status: http.StatusOK},
+				{path: "/api/sensors/stream", ip: "10.0.0.1", status: http.StatusOK},
+				{path: "/api/sensors", ip: "10.0.0.1", status: http.StatusOK},
+				{path: "/api/sensors/stream", ip: "10.0.0.1", status: http.StatusTooManyRequests},
+				{path: "/api/alerts", ip: "10.0.0.1", status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "Per-IP buckets",
+			requests: []request{
+				{path: "/api/sensors/stream", ip: "10.0.0.1", status: http.StatusOK},
+				{path: "/api/sensors/stream", ip: "10.0.0.2", status: http.StatusOK},
+				{path: "/api/sensors/stream", ip: "10.0.0.1", status: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "Per-API-key buckets share across IPs",
+			requests: []request{
+				{path: "/api/sensors", ip: "10.0.0.1", apiKey: "key-a", status: http.StatusOK},
+				{path: "/api/sensors", ip: "10.0.0.2", apiKey: "key-a", status: http.StatusOK},
+				{path: "/api/sensors", ip: "10.0.0.3", apiKey: "key-a", status: http.StatusTooManyRequests},
+				{path: "/api/sensors", ip: "10.0.0.3", apiKey: "key-b", status: http.StatusOK},
+			},
+		},
+		{
+			name: "Unknown API keys share the client IP bucket",
+			requests: []request{
+				{path: "/api/sensors", ip: "10.0.0.1", apiKey: "random-1", status: http.StatusOK},
+				{path: "/api/sensors", ip: "10.0.0.1", apiKey: "random-2", status: http.StatusOK},
+				{path: "/api/sensors", ip: "10.0.0.1", apiKey: "random-3", status: http.StatusTooManyRequests},
+				{path: "/api/sensors", ip: "10.0.0.1", status: http.StatusTooManyRequests},
+				{path: "/api/sensors", ip: "10.0.0.1", apiKey: "key-a", status: http.StatusOK},
+			},
+		},
+		{
+			name: "Exempt and unmatched paths are not limited",
+			requests: []request{
+				{path: "/health", ip: "10.0.0.1", status: http.StatusOK},
+				{path: "/health", ip: "10.0.0.1", status: http.StatusOK},
+				{path: "/app.js", ip: "10.0.0.1", status: http.StatusOK},
+				{path: "/app.js", ip: "10.0.0.1", status: http.StatusOK},
+				{path: "/app.js", ip: "10.0.0.1", status: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(NewRateLimiter(RateLimiterConfig{Policies: policies, Exempt: []string{"/health"}, Keys: keys}).Middleware())
+			e.Any("/*", func(c echo.Context) error {
+				return c.String(http.StatusOK, "OK")
+			})
+
+			for i, r := range tt.requests {
+				req := httptest.NewRequest(http.MethodGet, r.path, nil)
+				req.Header.Set(echo.HeaderXRealIP, r.ip)
+				if r.apiKey != "" {
+					req.Header.Set(auth.HeaderAPIKey, r.apiKey)
+				}
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+
+				assert.Equal(t, r.status, rec.Code, "request %d to %s", i, r.path)
+				if rec.Code == http.StatusTooManyRequests {
+					assert.NotEmpty(t, rec.Header().Get(echo.HeaderRetryAfter))
+					assert.Contains(t, rec.Body.String(), `"code":"TOO_MANY_REQUESTS"`)
+				}
+			}
+		})
+	}
+}
+
+// TestRateLimiterIgnoresSpoofedHeaders ทดสอบว่า client ที่ปลอม X-Forwarded-For ทุก request ยังใช้ bucket เดียวกันตาม IP ของ connection
+func TestRateLimiterIgnoresSpoofedHeaders(t *testing.T) {
+	extract, err := NewIPExtractor(nil)
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.IPExtractor = extract
+	limiter := NewRateLimiter(RateLimiterConfig{Policies: []RateLimitPolicy{
+		{Name: "api", Routes: []string{"/api/"}, Key: KeyAPIKey, Rate: 0.001, Burst: 2},
+	}})
+	e.Use(limiter.Middleware())
+	e.GET("/api/sensors", func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	for i, spoofed := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3", "198.51.100.4"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sensors", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		req.Header.Set(echo.HeaderXForwardedFor, spoofed)
+		req.Header.Set(echo.HeaderXRealIP, spoofed)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		want := http.StatusOK
+		if i >= 2 {
+			want = http.StatusTooManyRequests
+		}
+		assert.Equal(t, want, rec.Code, "request %d", i)
+	}
+	assert.Len(t, limiter.stores[0].visitors, 1)
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	e := echo.New()
+	e.Use(NewRateLimiter(RateLimiterConfig{Policies: []RateLimitPolicy{
+		{Name: "api", Routes: []string{"/api/"}, Key: KeyGlobal, Rate: 1, Burst: 5},
+	}}).Middleware())
+	e.GET("/api/sensors", func(c echo.Context) error {
+		return c.String(http.StatusOK, "OK")
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sensors", nil))
+
+	assert.Equal(t, "5", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "4", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Reset"))
+}
+
+func TestLoadRateLimitPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr error
+		want    int
+	}{
+		{
+			name: "YAML policies",
+			file: "policies.yaml",
+			content: `
+- name: api
+  routes: [/api/]
+  key: api_key
+  rate: 10
+  burst: 20
+`,
+			want: 1,
+		},
+		{
+			name:    "JSON policies",
+			file:    "policies.json",
+			content: `[{"name":"stream","routes":["/api/sensors/stream"],"key":"ip","rate":1,"burst":5}]`,
+			want:    1,
+		},
+		{
+			name:    "Unknown key type",
+			file:    "policies.json",
+			content: `[{"name":"api","routes":["/api/"],"key":"user","rate":1,"burst":5}]`,
+			wantErr: apierror.ErrInvalidConfig,
+		},
+		{
+			name:    "Duplicate name",
+			file:    "policies.json",
+			content: `[{"name":"api","routes":["/api/"],"key":"ip","rate":1,"burst":5},{"name":"api","routes":["/x/"],"key":"ip","rate":1,"burst":5}]`,
+			wantErr: apierror.ErrInvalidConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o644))
+
+			policies, err := LoadRateLimitPolicies(path)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), "expected %v, got %v", tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, policies, tt.want)
+		})
+	}
+
+	_, err := LoadRateLimitPolicies(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.True(t, errors.Is(err, apierror.ErrConfigNotFound))
+}
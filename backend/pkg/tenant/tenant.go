@@ -0,0 +1,128 @@
+package tenant
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/apierror"
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
+)
+
+// contextKey คือ key ที่เก็บ tenant ไว้ใน echo.Context
+const contextKey = "tenant.id"
+
+// namePattern คือรูปแบบชื่อ tenant ที่ยอมรับ ใช้เป็นส่วนหนึ่งของ cache key จึงจำกัดตัวอักษร
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Valid ตรวจสอบว่าชื่อ tenant ใช้ได้หรือไม่ (ตัวอักษร ตัวเลข . _ - ยาวไม่เกิน 64 ตัว)
+func Valid(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// ParseHosts แปลงข้อความรูปแบบ "host=tenant,host=tenant" เป็น map ของ host กับ tenant
+// host ถูกแปลงเป็นตัวพิมพ์เล็กและตัด port ออก
+func ParseHosts(value string) (map[string]string, error) {
+	hosts := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		host, name, ok := strings.Cut(entry, "=")
+		host = normalizeHost(host)
+		name = strings.TrimSpace(name)
+		if !ok || host == "" {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("tenant hosts %q: expected host=tenant", entry))
+		}
+		if !Valid(name) {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("host %q: invalid tenant %q", host, name))
+		}
+		if _, exists := hosts[host]; exists {
+			return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("duplicate tenant host %q", host))
+		}
+		hosts[host] = name
+	}
+	return hosts, nil
+}
+
+// Resolver ระบุ tenant ของ request จาก credential, host header หรือ tenant เริ่มต้นตามลำดับ
+type Resolver struct {
+	hosts    map[string]string
+	fallback string
+}
+
+// NewResolver สร้าง instance ใหม่ของ Resolver
+// fallback คือ tenant ของ request ที่ระบุ tenant ไม่ได้ ค่าว่างคือปฏิเสธ request เหล่านั้น
+func NewResolver(hosts map[string]string, fallback string) (*Resolver, error) {
+	if fallback != "" && !Valid(fallback) {
+		return nil, apierror.Wrap(apierror.ErrInvalidConfig, fmt.Sprintf("invalid default tenant %q", fallback))
+	}
+	return &Resolver{hosts: hosts, fallback: fallback}, nil
+}
+
+// Resolve คืนค่า tenant ของ request
+// credential ต้องตรงกับ tenant ของ host (ถ้า host ถูกกำหนดไว้) เพื่อไม่ให้ใช้ credential ของลูกค้ารายหนึ่งผ่าน domain ของอีกราย
+// credential ที่ไม่ผูกกับ tenant ใช้ได้เฉพาะ tenant เริ่มต้น มีเพียง auth.AllTenants ที่เลือก tenant จาก host ได้
+func (r *Resolver) Resolve(c echo.Context) (string, error) {
+	hostTenant, hostMapped := r.hosts[normalizeHost(c.Request().Host)]
+
+	if principal, ok := auth.PrincipalFrom(c); ok && principal.Tenant != auth.AllTenants {
+		name := principal.Tenant
+		if name == "" {
+			if r.fallback == "" {
+				return "", apierror.Wrap(apierror.ErrForbidden, "credentials are not bound to a tenant")
+			}
+			name = r.fallback
+		}
+		if !Valid(name) {
+			return "", apierror.Wrap(apierror.ErrForbidden, fmt.Sprintf("invalid tenant %q", name))
+		}
+		if hostMapped && hostTenant != name {
+			return "", apierror.Wrap(apierror.ErrForbidden, "credentials belong to another tenant")
+		}
+		return name, nil
+	}
+
+	// ไม่มี credential (ปิดการยืนยันตัวตน) หรือเป็น credential ของทุก tenant
+	if hostMapped {
+		return hostTenant, nil
+	}
+	if r.fallback == "" {
+		return "", apierror.Wrap(apierror.ErrForbidden, "tenant could not be resolved")
+	}
+	return r.fallback, nil
+}
+
+// Middleware ระบุ tenant แล้วเก็บไว้ใน echo.Context ต้องทำงานหลัง auth.Guard เพื่อให้อ่าน tenant จาก credential ได้
+func (r *Resolver) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			name, err := r.Resolve(c)
+			if err != nil {
+				return apierror.HandleAPIError(c, err)
+			}
+			c.Set(contextKey, name)
+			return next(c)
+		}
+	}
+}
+
+// From คืนค่า tenant ของ request ที่ผ่าน Middleware แล้ว
+func From(c echo.Context) (string, bool) {
+	name, ok := c.Get(contextKey).(string)
+	return name, ok
+}
+
+// normalizeHost แปลง host เป็นตัวพิมพ์เล็กและตัด port ออก
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,91 @@
+package tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Napat/go-sse-sensor-dashboard-demo/backend/pkg/auth"
+)
+
+func TestResolverMiddleware(t *testing.T) {
+	hosts, err := ParseHosts("acme.example.com=acme, Globex.Example.com:8443=globex")
+	require.NoError(t, err)
+
+	guard := auth.NewGuard(auth.NewKeyStore([]auth.APIKey{
+		{Name: "acme-dashboard", Hash: auth.HashKey("acme-key"), Scopes: []auth.Scope{auth.ScopeReadSensors}, Tenant: "acme"},
+		{Name: "shared", Hash: auth.HashKey("shared-key"), Scopes: []auth.Scope{auth.ScopeReadSensors}},
+		{Name: "operator", Hash: auth.HashKey("operator-key"), Scopes: []auth.Scope{auth.ScopeAdmin}, Tenant: auth.AllTenants},
+	}))
+
+	newServer := func(fallback string) *echo.Echo {
+		resolver, err := NewResolver(hosts, fallback)
+		require.NoError(t, err)
+
+		e := echo.New()
+		e.GET("/api/sensors", func(c echo.Context) error {
+			name, _ := From(c)
+			return c.String(http.StatusOK, name)
+		}, guard.Require(auth.ScopeReadSensors), resolver.Middleware())
+		return e
+	}
+
+	tests := []struct {
+		name       string
+		host       string
+		key        string
+		fallback   string
+		wantStatus int
+		wantTenant string
+	}{
+		{name: "Credential tenant without mapped host", host: "dashboard.local", key: "acme-key", fallback: "default", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "Credential tenant matches host", host: "acme.example.com", key: "acme-key", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "Credential of another tenant", host: "globex.example.com", key: "acme-key", fallback: "default", wantStatus: http.StatusForbidden},
+		{name: "Host without port and case", host: "GLOBEX.example.com:443", key: "operator-key", wantStatus: http.StatusOK, wantTenant: "globex"},
+		{name: "Tenantless credential cannot pick tenant by host", host: "globex.example.com", key: "shared-key", fallback: "default", wantStatus: http.StatusForbidden},
+		{name: "Tenantless credential uses default tenant", host: "dashboard.local", key: "shared-key", fallback: "default", wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "Tenantless credential without default tenant", host: "dashboard.local", key: "shared-key", wantStatus: http.StatusForbidden},
+		{name: "All-tenant credential on unmapped host uses default tenant", host: "dashboard.local", key: "operator-key", fallback: "default", wantStatus: http.StatusOK, wantTenant: "default"},
+		{name: "All-tenant credential without default tenant", host: "dashboard.local", key: "operator-key", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/sensors", nil)
+			req.Host = tt.host
+			req.Header.Set(auth.HeaderAPIKey, tt.key)
+			rec := httptest.NewRecorder()
+
+			newServer(tt.fallback).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantTenant, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	hosts, err := ParseHosts(" acme.example.com=acme ,, ")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"acme.example.com": "acme"}, hosts)
+
+	for _, value := range []string{
+		"acme.example.com",
+		"=acme",
+		"acme.example.com=",
+		"acme.example.com=acme|other",
+		"acme.example.com=acme,ACME.example.com=globex",
+	} {
+		_, err := ParseHosts(value)
+		assert.Error(t, err, value)
+	}
+
+	_, err = NewResolver(nil, "bad tenant")
+	assert.Error(t, err)
+}
@@ -41,8 +41,20 @@ let temperatureChart, humidityChart;
 // เก็บข้อมูลเซนเซอร์ล่าสุด
 let latestSensors = [];
 
+// API key จาก URL ของหน้า dashboard (?access_token=...) ใช้เมื่อ server เปิดการยืนยันตัวตน
+const accessToken = new URLSearchParams(window.location.search).get('access_token');
+
+// เพิ่ม access_token ใน URL ของ API (EventSource ตั้ง header เองไม่ได้จึงส่งผ่าน query)
+function apiURL(path) {
+    if (!accessToken) {
+        return path;
+    }
+    const separator = path.includes('?') ? '&' : '?';
+    return `${path}${separator}access_token=${encodeURIComponent(accessToken)}`;
+}
+
 // ดึงข้อมูล environment จาก API
-fetch('/api/environment')
+fetch(apiURL('/api/environment'))
     .then(response => {
         if (!response.ok) {
             throw new Error(`Network response error: ${response.status}`);
@@ -70,7 +82,7 @@ fetch('/api/environment')
 
 // ดึงข้อมูลเซนเซอร์ทั้งหมด
 function fetchAllSensors() {
-    fetch('/api/sensors')
+    fetch(apiURL('/api/sensors'))
         .then(response => {
             if (!response.ok) {
                 throw new Error(`Network response error: ${response.status}`);
@@ -144,6 +156,19 @@ function displaySensorsList(sensors) {
                 valuesElement.textContent = `${sensor.humidity.toFixed(2)} %`;
             } else if (sensor.type === 'combined') {
                 valuesElement.textContent = `${sensor.temperature.toFixed(2)} °C / ${sensor.humidity.toFixed(2)} %`;
+            } else if (sensor.metrics) {
+                // ชนิดอื่นแสดงทุก metric พร้อมหน่วย เช่น CO2 ppm, battery %
+                valuesElement.textContent = Object.entries(sensor.metrics)
+                    .sort(([a], [b]) => a.localeCompare(b))
+                    .map(([name, m]) => `${name} ${m.value.toFixed(2)} ${m.unit || ''}`.trim())
+                    .join(' / ');
+            }
+            
+            // แสดงสถานะเมื่อเซนเซอร์ไม่ได้ส่งข้อมูลตามกำหนด (stale/offline)
+            if (sensor.status && sensor.status !== 'active') {
+                const lastSeen = sensor.last_seen ? new Date(sensor.last_seen).toLocaleTimeString() : '-';
+                valuesElement.textContent += ` [${sensor.status}, ล่าสุด ${lastSeen}]`;
+                sensorItem.classList.add(`sensor-${sensor.status}`);
             }
             
             sensorItem.appendChild(nameElement);
@@ -536,6 +561,135 @@ function showError(message) {
     }, 5000);
 }
 
+// เก็บสถานะล่าสุดของเซนเซอร์แต่ละตัวตาม id เพื่อรวม delta เข้ากับ snapshot
+const sensorsById = new Map();
+
+// แปลง event.data จาก SSE เป็น object
+function parseEventData(event) {
+    // Handle double-encoded JSON string
+    let data = JSON.parse(event.data);
+    if (typeof data === 'string') {
+        data = JSON.parse(data);
+    }
+    
+    // แสดง server ID
+    if (data.server_id) {
+        document.getElementById("server-id").textContent = data.server_id;
+    }
+    
+    return data;
+}
+
+// แสดงผลจากสถานะเซนเซอร์ที่รวมไว้ทั้งหมด
+function renderSensors() {
+    const sensors = Array.from(sensorsById.values())
+        .sort((a, b) => a.id.localeCompare(b.id));
+    if (sensors.length === 0) {
+        return;
+    }
+    
+    // เก็บข้อมูลล่าสุด
+    latestSensors = sensors;
+    
+    // หาเวลาล่าสุดจากเซนเซอร์
+    const timestamp = sensors
+        .map(sensor => sensor.timestamp)
+        .reduce((latest, current) => (current > latest ? current : latest));
+    
+    // อัปเดตค่าที่แสดงบนการ์ด
+    updateDashboardCards(sensors);
+    
+    // อัปเดตกราฟ
+    updateCharts(sensors, timestamp);
+    
+    // อัปเดตรายการเซนเซอร์
+    displaySensorsList(sensors);
+}
+
+// จัดการ event "snapshot" และ "reset" ซึ่งมีข้อมูลเซนเซอร์ครบทุกตัว
+function handleSnapshotEvent(event) {
+    try {
+        const data = parseEventData(event);
+        if (data.data && Array.isArray(data.data)) {
+            sensorsById.clear();
+            data.data.forEach(sensor => sensorsById.set(sensor.id, sensor));
+            renderSensors();
+            return;
+        }
+    } catch (error) {
+        console.warn("Error processing sensor snapshot:", error);
+    }
+    
+    // Default values if data is invalid
+    document.getElementById("temperature").textContent = "N/A";
+    document.getElementById("humidity").textContent = "N/A";
+    document.getElementById("timestamp").textContent = "Error loading data";
+}
+
+// จัดการ event "sensor.updated" ซึ่งมีเฉพาะเซนเซอร์ที่เปลี่ยนแปลง
+function handleSensorUpdatedEvent(event) {
+    try {
+        const data = parseEventData(event);
+        if (data.data && Array.isArray(data.data) && data.data.length > 0) {
+            data.data.forEach(sensor => sensorsById.set(sensor.id, sensor));
+            renderSensors();
+        }
+    } catch (error) {
+        console.warn("Error processing sensor update:", error);
+    }
+}
+
+// จัดการ event "sensor.deleted" ซึ่งส่งมาเมื่อเซนเซอร์ถูกลบออกจาก registry
+function handleSensorDeletedEvent(event) {
+    try {
+        const data = parseEventData(event);
+        if (data.data && Array.isArray(data.data)) {
+            data.data.forEach(sensor => sensorsById.delete(sensor.id));
+            renderSensors();
+        }
+    } catch (error) {
+        console.warn("Error processing sensor deletion:", error);
+    }
+}
+
+// จัดการ event "sensor.status" ซึ่งส่งมาเมื่อเซนเซอร์เปลี่ยนสถานะ (active, stale, offline)
+function handleSensorStatusEvent(event) {
+    try {
+        const data = parseEventData(event);
+        if (!data.data || !Array.isArray(data.data)) {
+            return;
+        }
+        data.data.forEach(change => {
+            const sensor = sensorsById.get(change.sensor_id);
+            if (sensor) {
+                sensor.status = change.status;
+                sensor.last_seen = change.last_seen;
+            }
+        });
+        displaySensorsList(latestSensors);
+    } catch (error) {
+        console.warn("Error processing sensor status:", error);
+    }
+}
+
+// จัดการ event "alert" ซึ่งส่งมาทุกครั้งที่สถานะของ alert เปลี่ยน (pending, firing, resolved, cancelled)
+function handleAlertEvent(event) {
+    try {
+        const data = parseEventData(event);
+        if (!data.data || !Array.isArray(data.data)) {
+            return;
+        }
+        data.data.forEach(alert => {
+            console.log(`Alert ${alert.id} is ${alert.state} (value ${alert.value})`);
+            if (alert.state === 'firing') {
+                showError(`แจ้งเตือน ${alert.sensor_id}: ${alert.expr} (ค่าปัจจุบัน ${alert.value.toFixed(1)})`);
+            }
+        });
+    } catch (error) {
+        console.warn("Error processing alert:", error);
+    }
+}
+
 // สร้าง function สำหรับ SSE connection พร้อม retry
 function connectSSE() {
     console.log('Connecting to SSE...');
@@ -546,52 +700,20 @@ function connectSSE() {
     document.getElementById("timestamp").textContent = "กำลังเชื่อมต่อ...";
     document.getElementById("server-id").textContent = "กำลังรอข้อมูล...";
     
-    const eventSource = new EventSource('/api/sensors/stream');
+    const eventSource = new EventSource(apiURL('/api/sensors/stream'));
     
     eventSource.onopen = function() {
         console.log('SSE connection established');
     };
 
-    eventSource.onmessage = function(event) {
-        try {
-            // Handle double-encoded JSON string
-            let data = JSON.parse(event.data);
-            if (typeof data === 'string') {
-                data = JSON.parse(data);
-            }
-            
-            // แสดง server ID
-            if (data.server_id) {
-                document.getElementById("server-id").textContent = data.server_id;
-            }
-            
-            if (data.data && Array.isArray(data.data) && data.data.length > 0) {
-                // เก็บข้อมูลล่าสุด
-                latestSensors = data.data;
-                
-                // หาเวลาล่าสุดจากเซนเซอร์
-                const timestamp = data.data[0].timestamp;
-                
-                // อัปเดตค่าที่แสดงบนการ์ด
-                updateDashboardCards(data.data);
-                
-                // อัปเดตกราฟ
-                updateCharts(data.data, timestamp);
-                
-                // อัปเดตรายการเซนเซอร์
-                displaySensorsList(data.data);
-                
-                return;
-            }
-        } catch (error) {
-            console.warn("Error processing sensor data:", error);
-        }
-        
-        // Default values if data is invalid
-        document.getElementById("temperature").textContent = "N/A";
-        document.getElementById("humidity").textContent = "N/A";
-        document.getElementById("timestamp").textContent = "Error loading data";
-    };
+    // "snapshot" คือข้อมูลทั้งหมด (ตอนเริ่มและ resync เป็นระยะ), "sensor.updated" คือเฉพาะเซนเซอร์ที่เปลี่ยน
+    // ส่วน "reset" คือ snapshot ที่ส่งมาเมื่อ server ไม่สามารถ replay event ที่พลาดไปได้
+    eventSource.addEventListener('snapshot', handleSnapshotEvent);
+    eventSource.addEventListener('reset', handleSnapshotEvent);
+    eventSource.addEventListener('sensor.updated', handleSensorUpdatedEvent);
+    eventSource.addEventListener('sensor.status', handleSensorStatusEvent);
+    eventSource.addEventListener('sensor.deleted', handleSensorDeletedEvent);
+    eventSource.addEventListener('alert', handleAlertEvent);
 
     eventSource.onerror = function(error) {
         console.error('SSE connection error:', error);